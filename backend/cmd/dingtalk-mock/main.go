@@ -0,0 +1,24 @@
+// dingtalk-mock 启动本地钉钉开放平台模拟服务，用于联调企业内部应用工作通知
+//
+//	go run ./cmd/dingtalk-mock -addr :18080 -appkey test -appsecret test
+//
+// 钉钉通知配置中 base_url 设为 http://localhost:18080，收到的消息可通过 GET /_mock/messages、GET /_mock/cards 查看。
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"backend/pkg/notify/dingtalkmock"
+)
+
+func main() {
+	addr := flag.String("addr", ":18080", "监听地址")
+	appKey := flag.String("appkey", "test", "模拟的 AppKey")
+	appSecret := flag.String("appsecret", "test", "模拟的 AppSecret")
+	flag.Parse()
+
+	log.Printf("DingTalk mock listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, dingtalkmock.New(*appKey, *appSecret)))
+}
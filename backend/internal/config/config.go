@@ -15,6 +15,7 @@ type Config struct {
 	JWT      JWTConfig      `yaml:"jwt"`
 	Log      LogConfig      `yaml:"log"`
 	SSO      SSOConfig      `yaml:"sso"`
+	Search   SearchConfig   `yaml:"search"`
 }
 
 // ServerConfig 服务器配置
@@ -23,7 +24,8 @@ type ServerConfig struct {
 	Mode         string        `yaml:"mode"` // debug, release, test
 	ReadTimeout  time.Duration `yaml:"read_timeout"`
 	WriteTimeout time.Duration `yaml:"write_timeout"`
-	Name         string        `yaml:"name"` // 服务名称
+	Name         string        `yaml:"name"`     // 服务名称
+	BaseURL      string        `yaml:"base_url"` // 前端访问地址，用于生成通知中的工单链接
 }
 
 // SSOConfig SSO 配置
@@ -59,6 +61,11 @@ type CASConfig struct {
 	SingleLogout bool `yaml:"single_logout"` // 是否启用单点登出
 }
 
+// SearchConfig 全文检索配置
+type SearchConfig struct {
+	Engine string `yaml:"engine"` // 索引后端：memory（内嵌索引，无需外部服务）
+}
+
 // DatabaseConfig 数据库配置
 type DatabaseConfig struct {
 	Host         string `yaml:"host"`
@@ -265,6 +272,10 @@ func setDefaults(config *Config) {
 	if config.SSO.CAS.TGTTTL == 0 {
 		config.SSO.CAS.TGTTTL = 28800 // 8小时
 	}
+	// 全文检索默认配置
+	if config.Search.Engine == "" {
+		config.Search.Engine = "memory"
+	}
 }
 
 // DSN 返回数据库连接字符串
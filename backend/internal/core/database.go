@@ -1,15 +1,30 @@
 package core
 
 import (
+	"fmt"
+
 	"backend/internal/global"
 	"backend/internal/model"
 	"backend/internal/model/sso"
+	"backend/pkg/logger"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 // Migrate 执行数据库迁移
 func Migrate() error {
 	db := global.GetDB()
 
+	// 工单编号唯一索引创建前处理历史重复编号
+	if err := dedupeTicketNumbers(db); err != nil {
+		return err
+	}
+	// 钉钉用户ID唯一索引创建前清理历史重复ID
+	if err := dedupeUserDingTalkIDs(db); err != nil {
+		return err
+	}
+
 	// 迁移现有模型
 	if err := db.AutoMigrate(
 		&model.User{},
@@ -22,17 +37,36 @@ func Migrate() error {
 		// 表单模板相关
 		&model.FormTemplate{},
 		&model.FormField{},
+		&model.FormTemplateVersion{},
 		// 审批流程相关
 		&model.ApprovalFlow{},
 		&model.FlowNode{},
 		// 工单相关模型
 		&model.TicketType{},
+		&model.TicketNumberSequence{},
 		&model.Ticket{},
 		&model.TicketData{},
 		&model.ApprovalRecord{},
+		&model.TicketEvent{},
+		&model.TicketDataChange{},
 		&model.TicketComment{},
 		&model.TicketAttachment{},
 		&model.TicketTemplate{},
+		&model.TicketWatcher{},
+		&model.TicketView{},
+		&model.TicketExportJob{},
+		&model.ReportSubscription{},
+		&model.TicketSchedule{},
+		&model.TicketScheduleRun{},
+		&model.Webhook{},
+		&model.WebhookDelivery{},
+		&model.ApprovalActionToken{},
+		// 通知相关
+		&model.NotificationOutbox{},
+		&model.NotificationPreference{},
+		&model.NotificationTemplate{},
+		&model.UserNotification{},
+		&model.NotificationStreamTicket{},
 	); err != nil {
 		return err
 	}
@@ -50,3 +84,57 @@ func Migrate() error {
 		&sso.UserConsent{},
 	)
 }
+
+// dedupeTicketNumbers 为历史数据中重复的工单编号追加工单ID后缀（保留最早的工单），以便创建编号唯一索引。
+// 尚无编号列（由早期版本升级）时无需处理，由 AutoMigrate 添加列和索引
+func dedupeTicketNumbers(db *gorm.DB) error {
+	m := db.Migrator()
+	if !m.HasTable(&model.Ticket{}) || !m.HasColumn(&model.Ticket{}, "Number") || m.HasIndex(&model.Ticket{}, "idx_ticket_number") {
+		return nil
+	}
+	var numbers []string
+	if err := db.Unscoped().Model(&model.Ticket{}).Where("number <> ''").
+		Group("number").Having("COUNT(*) > 1").Pluck("number", &numbers).Error; err != nil {
+		return err
+	}
+	for _, number := range numbers {
+		var ids []uint
+		if err := db.Unscoped().Model(&model.Ticket{}).Where("number = ?", number).
+			Order("id ASC").Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		for _, id := range ids[1:] {
+			if err := db.Unscoped().Model(&model.Ticket{}).Where("id = ?", id).
+				UpdateColumn("number", fmt.Sprintf("%s-%d", number, id)).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// dedupeUserDingTalkIDs 历史数据中多个用户使用同一钉钉用户ID时，保留最早的用户，清空其余用户的钉钉用户ID，
+// 以便创建唯一索引（重复的ID无法确定钉钉回调的操作人），被清空的用户需由管理员重新维护
+func dedupeUserDingTalkIDs(db *gorm.DB) error {
+	m := db.Migrator()
+	if !m.HasTable(&model.User{}) || !m.HasColumn(&model.User{}, "DingTalkUserID") || m.HasIndex(&model.User{}, "idx_user_dingtalk_user_id") {
+		return nil
+	}
+	var ids []string
+	if err := db.Model(&model.User{}).Where("ding_talk_user_id <> ''").
+		Group("ding_talk_user_id").Having("COUNT(*) > 1").Pluck("ding_talk_user_id", &ids).Error; err != nil {
+		return err
+	}
+	for _, id := range ids {
+		var first model.User
+		if err := db.Select("id").Where("ding_talk_user_id = ?", id).Order("id ASC").First(&first).Error; err != nil {
+			return err
+		}
+		if err := db.Model(&model.User{}).Where("ding_talk_user_id = ? AND id <> ?", id, first.ID).
+			UpdateColumn("ding_talk_user_id", "").Error; err != nil {
+			return err
+		}
+		logger.Warn("Cleared duplicate DingTalk user ID", zap.String("dingtalk_user_id", id), zap.Uint("kept_user_id", first.ID))
+	}
+	return nil
+}
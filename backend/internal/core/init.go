@@ -14,6 +14,7 @@ import (
 	"backend/internal/global"
 	"backend/internal/ldap"
 	"backend/internal/router"
+	"backend/internal/service"
 	ssoService "backend/internal/service/sso"
 	"backend/pkg/jwt"
 	"backend/pkg/logger"
@@ -52,6 +53,11 @@ func Bootstrap(configPath string) error {
 		return fmt.Errorf("failed to init database: %w", err)
 	}
 
+	// 初始化全文索引（内容由定时任务在启动时构建）
+	if _, err := global.InitSearch(cfg); err != nil {
+		return err
+	}
+
 	// 执行数据库迁移
 	if err := Migrate(); err != nil {
 		return fmt.Errorf("failed to migrate database: %w", err)
@@ -100,6 +106,13 @@ func Bootstrap(configPath string) error {
 	}
 	logger.Info("Menus synced successfully")
 
+	// 回填历史表单数据的类型化值 - 每次启动都执行，只处理尚未投影的数据
+	count, err := service.BackfillTicketDataValues()
+	if err != nil {
+		return fmt.Errorf("failed to backfill ticket data values: %w", err)
+	}
+	logger.Info("Ticket data values backfilled", zap.Int("count", count))
+
 	// 初始化 JWT
 	jwt.Init(&cfg.JWT)
 
@@ -118,6 +131,12 @@ func Run() {
 	// 启动定时任务调度器
 	sched = scheduler.New()
 	sched.Register(&ssoService.TokenCleanupJob{}, time.Hour)
+	sched.Register(&service.TicketIndexJob{}, 24*time.Hour)
+	sched.Register(&service.TicketExportJobRunner{}, 30*time.Second)
+	sched.Register(&service.ReportSubscriptionJob{}, time.Minute)
+	sched.Register(&service.TicketScheduleJob{}, time.Minute)
+	sched.Register(&service.WebhookDeliveryJob{}, 10*time.Second)
+	sched.Register(&service.NotificationOutboxJob{}, 10*time.Second)
 	sched.Start()
 
 	// 设置路由
@@ -190,8 +190,23 @@ func SyncAPIDefinitions() error {
 		// 表单字段管理
 		{Name: "表单字段列表", Path: "/api/v1/form-templates/:id/fields", Method: "GET", Resource: "ticket", Description: "查看表单字段列表"},
 		{Name: "表单字段保存", Path: "/api/v1/form-templates/:id/fields", Method: "PUT", Resource: "ticket", Description: "保存表单字段"},
+		{Name: "表单模板 JSON Schema", Path: "/api/v1/form-templates/:id/schema", Method: "GET", Resource: "ticket", Description: "导出表单模板的 JSON Schema"},
+		{Name: "表单模板 Schema 导入", Path: "/api/v1/form-templates/schema", Method: "POST", Resource: "ticket", Description: "从 JSON Schema 创建表单模板"},
+		{Name: "表单模板 Schema 更新", Path: "/api/v1/form-templates/:id/schema", Method: "PUT", Resource: "ticket", Description: "用 JSON Schema 更新表单模板字段"},
+		{Name: "表单模板版本列表", Path: "/api/v1/form-templates/:id/versions", Method: "GET", Resource: "ticket", Description: "查看表单模板版本列表"},
+		{Name: "表单模板版本对比", Path: "/api/v1/form-templates/:id/versions/compare", Method: "GET", Resource: "ticket", Description: "对比表单模板两个版本的字段差异"},
+		{Name: "表单模板版本详情", Path: "/api/v1/form-templates/:id/versions/:version", Method: "GET", Resource: "ticket", Description: "查看表单模板指定版本的字段"},
+		{Name: "表单字段选项", Path: "/api/v1/form-fields/:id/options", Method: "GET", Resource: "ticket", Description: "查看表单字段选项"},
+		{Name: "表单字段选项刷新", Path: "/api/v1/form-fields/:id/options/refresh", Method: "POST", Resource: "ticket", Description: "刷新表单字段动态选项"},
 		// 工单快捷模板管理
 		{Name: "工单模板启用列表", Path: "/api/v1/ticket-templates/enabled", Method: "GET", Resource: "ticket", Description: "查看启用的工单模板"},
+		{Name: "工单模板列表", Path: "/api/v1/ticket-templates", Method: "GET", Resource: "ticket", Description: "查看全局及个人工单模板"},
+		{Name: "工单模板详情", Path: "/api/v1/ticket-templates/:id", Method: "GET", Resource: "ticket", Description: "查看工单模板详情"},
+		{Name: "工单模板预设", Path: "/api/v1/ticket-templates/:id/presets", Method: "GET", Resource: "ticket", Description: "获取替换占位符后的模板预设"},
+		{Name: "工单模板创建", Path: "/api/v1/ticket-templates", Method: "POST", Resource: "ticket", Description: "创建工单模板（普通用户创建个人模板）"},
+		{Name: "工单模板更新", Path: "/api/v1/ticket-templates/:id", Method: "PUT", Resource: "ticket", Description: "更新工单模板"},
+		{Name: "工单模板删除", Path: "/api/v1/ticket-templates/:id", Method: "DELETE", Resource: "ticket", Description: "删除工单模板"},
+		{Name: "基于模板创建工单", Path: "/api/v1/ticket-templates/:id/tickets", Method: "POST", Resource: "ticket", Description: "基于模板预设创建工单"},
 		// 工单管理
 		{Name: "工单列表", Path: "/api/v1/tickets", Method: "GET", Resource: "ticket", Description: "查看工单列表"},
 		{Name: "我的工单", Path: "/api/v1/tickets/my", Method: "GET", Resource: "ticket", Description: "查看我的工单"},
@@ -199,7 +214,27 @@ func SyncAPIDefinitions() error {
 		{Name: "我处理的工单", Path: "/api/v1/tickets/processed", Method: "GET", Resource: "ticket", Description: "查看我处理的工单"},
 		{Name: "抄送我的工单", Path: "/api/v1/tickets/cc", Method: "GET", Resource: "ticket", Description: "查看抄送我的工单"},
 		{Name: "工单统计", Path: "/api/v1/tickets/stats", Method: "GET", Resource: "ticket", Description: "查看工单统计"},
+		{Name: "工单表单字段统计", Path: "/api/v1/tickets/stats/field", Method: "GET", Resource: "ticket", Description: "统计表单数值/日期字段"},
+		{Name: "工单趋势统计", Path: "/api/v1/tickets/analytics/trends", Method: "GET", Resource: "ticket", Description: "按天/周统计新建与完成工单"},
+		{Name: "审批节点耗时统计", Path: "/api/v1/tickets/analytics/nodes", Method: "GET", Resource: "ticket", Description: "统计各审批节点平均及分位耗时"},
+		{Name: "审批人耗时统计", Path: "/api/v1/tickets/analytics/approvers", Method: "GET", Resource: "ticket", Description: "统计各审批人平均及分位耗时"},
+		{Name: "处理人吞吐量统计", Path: "/api/v1/tickets/analytics/assignees", Method: "GET", Resource: "ticket", Description: "统计各处理人完成工单数"},
+		{Name: "审批节点拒绝率统计", Path: "/api/v1/tickets/analytics/rejections", Method: "GET", Resource: "ticket", Description: "统计各审批节点拒绝/退回率"},
+		{Name: "滞留工单统计", Path: "/api/v1/tickets/analytics/slowest", Method: "GET", Resource: "ticket", Description: "查看停留最久的未完结工单"},
+		{Name: "工单全文检索", Path: "/api/v1/tickets/search", Method: "GET", Resource: "ticket", Description: "全文检索工单"},
+		{Name: "工单检索索引重建", Path: "/api/v1/tickets/search/rebuild", Method: "POST", Resource: "ticket", Description: "重建工单检索索引"},
+		{Name: "工单条件查询", Path: "/api/v1/tickets/query", Method: "POST", Resource: "ticket", Description: "按过滤条件或保存视图查询工单"},
+		{Name: "工单导出", Path: "/api/v1/tickets/export", Method: "GET", Resource: "ticket", Description: "导出工单为 CSV/XLSX"},
+		{Name: "工单导出任务列表", Path: "/api/v1/tickets/export/jobs", Method: "GET", Resource: "ticket", Description: "查看我的导出任务"},
+		{Name: "工单导出任务详情", Path: "/api/v1/tickets/export/jobs/:id", Method: "GET", Resource: "ticket", Description: "查看导出任务并下载结果"},
+		{Name: "工单导入", Path: "/api/v1/tickets/import", Method: "POST", Resource: "ticket", Description: "从 CSV/XLSX 批量导入工单"},
+		{Name: "工单批量审批", Path: "/api/v1/tickets/batch/approve", Method: "POST", Resource: "ticket", Description: "批量通过或拒绝工单"},
+		{Name: "工单批量取消", Path: "/api/v1/tickets/batch/cancel", Method: "POST", Resource: "ticket", Description: "批量取消工单"},
+		{Name: "工单批量转交", Path: "/api/v1/tickets/batch/reassign", Method: "POST", Resource: "ticket", Description: "批量转交工单处理人"},
+		{Name: "工单批量修改优先级", Path: "/api/v1/tickets/batch/priority", Method: "POST", Resource: "ticket", Description: "批量修改工单优先级"},
+		{Name: "工单批量添加关注人", Path: "/api/v1/tickets/batch/watchers", Method: "POST", Resource: "ticket", Description: "批量添加工单关注人"},
 		{Name: "工单详情", Path: "/api/v1/tickets/:id", Method: "GET", Resource: "ticket", Description: "查看工单详情"},
+		{Name: "工单关注人", Path: "/api/v1/tickets/:id/watchers", Method: "GET", Resource: "ticket", Description: "查看工单关注人"},
 		{Name: "工单创建", Path: "/api/v1/tickets", Method: "POST", Resource: "ticket", Description: "创建工单"},
 		{Name: "工单更新", Path: "/api/v1/tickets/:id", Method: "PUT", Resource: "ticket", Description: "更新工单"},
 		{Name: "工单删除", Path: "/api/v1/tickets/:id", Method: "DELETE", Resource: "ticket", Description: "删除工单"},
@@ -207,6 +242,59 @@ func SyncAPIDefinitions() error {
 		{Name: "工单审批", Path: "/api/v1/tickets/:id/approve", Method: "POST", Resource: "ticket", Description: "审批工单"},
 		{Name: "工单完成", Path: "/api/v1/tickets/:id/complete", Method: "POST", Resource: "ticket", Description: "完成工单"},
 		{Name: "工单取消", Path: "/api/v1/tickets/:id/cancel", Method: "POST", Resource: "ticket", Description: "取消工单"},
+		// 工单保存视图
+		{Name: "工单视图列表", Path: "/api/v1/ticket-views", Method: "GET", Resource: "ticket", Description: "查看可用的工单视图"},
+		{Name: "工单视图详情", Path: "/api/v1/ticket-views/:id", Method: "GET", Resource: "ticket", Description: "查看工单视图详情"},
+		{Name: "工单视图创建", Path: "/api/v1/ticket-views", Method: "POST", Resource: "ticket", Description: "保存工单视图"},
+		{Name: "工单视图更新", Path: "/api/v1/ticket-views/:id", Method: "PUT", Resource: "ticket", Description: "更新工单视图"},
+		{Name: "工单视图删除", Path: "/api/v1/ticket-views/:id", Method: "DELETE", Resource: "ticket", Description: "删除工单视图"},
+		{Name: "周期工单列表", Path: "/api/v1/ticket-schedules", Method: "GET", Resource: "ticket", Description: "查看周期工单"},
+		{Name: "周期工单详情", Path: "/api/v1/ticket-schedules/:id", Method: "GET", Resource: "ticket", Description: "查看周期工单详情"},
+		{Name: "周期工单创建", Path: "/api/v1/ticket-schedules", Method: "POST", Resource: "ticket", Description: "创建周期工单"},
+		{Name: "周期工单更新", Path: "/api/v1/ticket-schedules/:id", Method: "PUT", Resource: "ticket", Description: "更新周期工单"},
+		{Name: "周期工单删除", Path: "/api/v1/ticket-schedules/:id", Method: "DELETE", Resource: "ticket", Description: "删除周期工单"},
+		{Name: "周期工单执行记录", Path: "/api/v1/ticket-schedules/:id/runs", Method: "GET", Resource: "ticket", Description: "查看周期工单执行记录"},
+		{Name: "周期工单立即执行", Path: "/api/v1/ticket-schedules/:id/run", Method: "POST", Resource: "ticket", Description: "立即执行一次周期工单"},
+		{Name: "报表订阅列表", Path: "/api/v1/report-subscriptions", Method: "GET", Resource: "ticket", Description: "查看我的定时报表订阅"},
+		{Name: "报表订阅详情", Path: "/api/v1/report-subscriptions/:id", Method: "GET", Resource: "ticket", Description: "查看定时报表订阅详情"},
+		{Name: "报表订阅创建", Path: "/api/v1/report-subscriptions", Method: "POST", Resource: "ticket", Description: "创建定时报表订阅"},
+		{Name: "报表订阅更新", Path: "/api/v1/report-subscriptions/:id", Method: "PUT", Resource: "ticket", Description: "更新定时报表订阅"},
+		{Name: "报表订阅删除", Path: "/api/v1/report-subscriptions/:id", Method: "DELETE", Resource: "ticket", Description: "删除定时报表订阅"},
+		{Name: "报表立即发送", Path: "/api/v1/report-subscriptions/:id/send", Method: "POST", Resource: "ticket", Description: "立即发送一次定时报表"},
+		// Webhook 订阅
+		{Name: "Webhook 列表", Path: "/api/v1/webhooks", Method: "GET", Resource: "ticket", Description: "查看 Webhook 订阅"},
+		{Name: "Webhook 详情", Path: "/api/v1/webhooks/:id", Method: "GET", Resource: "ticket", Description: "查看 Webhook 订阅详情"},
+		{Name: "Webhook 创建", Path: "/api/v1/webhooks", Method: "POST", Resource: "ticket", Description: "创建 Webhook 订阅"},
+		{Name: "Webhook 更新", Path: "/api/v1/webhooks/:id", Method: "PUT", Resource: "ticket", Description: "更新 Webhook 订阅"},
+		{Name: "Webhook 删除", Path: "/api/v1/webhooks/:id", Method: "DELETE", Resource: "ticket", Description: "删除 Webhook 订阅"},
+		{Name: "Webhook 重置密钥", Path: "/api/v1/webhooks/:id/secret/rotate", Method: "POST", Resource: "ticket", Description: "重新生成 Webhook 签名密钥"},
+		{Name: "Webhook 测试", Path: "/api/v1/webhooks/:id/ping", Method: "POST", Resource: "ticket", Description: "发送 Webhook 测试事件"},
+		{Name: "Webhook 投递记录", Path: "/api/v1/webhooks/:id/deliveries", Method: "GET", Resource: "ticket", Description: "查看 Webhook 投递记录"},
+		{Name: "Webhook 投递详情", Path: "/api/v1/webhooks/deliveries/:id", Method: "GET", Resource: "ticket", Description: "查看 Webhook 投递详情"},
+		{Name: "Webhook 重新投递", Path: "/api/v1/webhooks/deliveries/:id/redeliver", Method: "POST", Resource: "ticket", Description: "重新投递 Webhook"},
+		// 通知发件箱
+		{Name: "通知发件箱列表", Path: "/api/v1/notification-outbox", Method: "GET", Resource: "ticket", Description: "查看通知发送记录"},
+		{Name: "通知发件箱详情", Path: "/api/v1/notification-outbox/:id", Method: "GET", Resource: "ticket", Description: "查看通知发送详情"},
+		{Name: "通知重新发送", Path: "/api/v1/notification-outbox/:id/resend", Method: "POST", Resource: "ticket", Description: "重新发送通知"},
+		{Name: "失败通知批量重发", Path: "/api/v1/notification-outbox/resend-failed", Method: "POST", Resource: "ticket", Description: "重新发送全部失败的通知"},
+		// 个人通知偏好
+		{Name: "通知偏好查看", Path: "/api/v1/notification-preferences/me", Method: "GET", Resource: "ticket", Description: "查看个人通知偏好"},
+		{Name: "通知偏好更新", Path: "/api/v1/notification-preferences/me", Method: "PUT", Resource: "ticket", Description: "更新个人通知渠道与免打扰时段"},
+		// 通知模板
+		{Name: "通知模板列表", Path: "/api/v1/notification-templates", Method: "GET", Resource: "ticket", Description: "查看通知模板"},
+		{Name: "内置通知模板", Path: "/api/v1/notification-templates/builtins", Method: "GET", Resource: "ticket", Description: "查看内置通知模板"},
+		{Name: "通知模板预览", Path: "/api/v1/notification-templates/preview", Method: "POST", Resource: "ticket", Description: "使用示例数据预览通知模板"},
+		{Name: "通知模板详情", Path: "/api/v1/notification-templates/:id", Method: "GET", Resource: "ticket", Description: "查看通知模板详情"},
+		{Name: "通知模板创建", Path: "/api/v1/notification-templates", Method: "POST", Resource: "ticket", Description: "创建通知模板"},
+		{Name: "通知模板更新", Path: "/api/v1/notification-templates/:id", Method: "PUT", Resource: "ticket", Description: "更新通知模板"},
+		{Name: "通知模板删除", Path: "/api/v1/notification-templates/:id", Method: "DELETE", Resource: "ticket", Description: "删除通知模板"},
+		// 站内通知
+		{Name: "站内通知列表", Path: "/api/v1/notifications", Method: "GET", Resource: "ticket", Description: "查看个人站内通知"},
+		{Name: "未读通知数", Path: "/api/v1/notifications/unread-count", Method: "GET", Resource: "ticket", Description: "查看未读站内通知数"},
+		{Name: "通知标记已读", Path: "/api/v1/notifications/:id/read", Method: "POST", Resource: "ticket", Description: "将站内通知标记为已读"},
+		{Name: "通知全部已读", Path: "/api/v1/notifications/read-all", Method: "POST", Resource: "ticket", Description: "将全部站内通知标记为已读"},
+		{Name: "通知推送票据", Path: "/api/v1/notifications/stream/ticket", Method: "POST", Resource: "ticket", Description: "签发站内通知推送连接票据"},
+		{Name: "通知实时推送", Path: "/api/v1/notifications/stream", Method: "GET", Resource: "ticket", Description: "通过 SSE 接收站内通知"},
 		// 审批流程管理
 		{Name: "审批流程列表", Path: "/api/v1/approval-flows", Method: "GET", Resource: "ticket", Description: "查看审批流程列表"},
 		{Name: "审批流程启用列表", Path: "/api/v1/approval-flows/enabled", Method: "GET", Resource: "ticket", Description: "查看启用的审批流程"},
@@ -230,6 +318,8 @@ func SyncAPIDefinitions() error {
 		// 审批记录
 		{Name: "审批记录", Path: "/api/v1/tickets/:id/records", Method: "GET", Resource: "ticket", Description: "查看审批记录"},
 		{Name: "审批权限检查", Path: "/api/v1/tickets/:id/can-approve", Method: "GET", Resource: "ticket", Description: "检查审批权限"},
+		{Name: "节点字段权限", Path: "/api/v1/tickets/:id/field-permissions", Method: "GET", Resource: "ticket", Description: "查看当前审批节点的字段权限"},
+		{Name: "表单修改记录", Path: "/api/v1/tickets/:id/data-changes", Method: "GET", Resource: "ticket", Description: "查看审批人修改表单字段的记录"},
 	}
 
 	for _, apiDef := range apiDefs {
@@ -291,13 +381,49 @@ func SyncTicketUserPolicies() error {
 		// 工单类型和模板（创建工单时需要）
 		{"/api/v1/ticket-types/enabled", "GET"},
 		{"/api/v1/ticket-templates/enabled", "GET"},
+		{"/api/v1/ticket-templates", "GET"},
+		{"/api/v1/ticket-templates/:id", "GET"},
+		{"/api/v1/ticket-templates/:id/presets", "GET"},
+		{"/api/v1/ticket-templates", "POST"},
+		{"/api/v1/ticket-templates/:id", "PUT"},
+		{"/api/v1/ticket-templates/:id", "DELETE"},
+		{"/api/v1/ticket-templates/:id/tickets", "POST"},
 		{"/api/v1/form-templates/:id/fields", "GET"},
+		{"/api/v1/form-templates/:id/schema", "GET"},
+		{"/api/v1/form-fields/:id/options", "GET"},
 		// 工单列表
 		{"/api/v1/tickets", "GET"},
 		{"/api/v1/tickets/my", "GET"},
 		{"/api/v1/tickets/pending", "GET"},
 		{"/api/v1/tickets/processed", "GET"},
 		{"/api/v1/tickets/cc", "GET"},
+		{"/api/v1/tickets/search", "GET"},
+		{"/api/v1/tickets/query", "POST"},
+		{"/api/v1/tickets/export", "GET"},
+		{"/api/v1/tickets/export/jobs", "GET"},
+		{"/api/v1/tickets/export/jobs/:id", "GET"},
+		// 工单保存视图
+		{"/api/v1/ticket-views", "GET"},
+		{"/api/v1/ticket-views/:id", "GET"},
+		{"/api/v1/ticket-views", "POST"},
+		{"/api/v1/ticket-views/:id", "PUT"},
+		{"/api/v1/ticket-views/:id", "DELETE"},
+		{"/api/v1/report-subscriptions", "GET"},
+		{"/api/v1/report-subscriptions/:id", "GET"},
+		{"/api/v1/report-subscriptions", "POST"},
+		{"/api/v1/report-subscriptions/:id", "PUT"},
+		{"/api/v1/report-subscriptions/:id", "DELETE"},
+		{"/api/v1/report-subscriptions/:id/send", "POST"},
+		// 个人通知偏好
+		{"/api/v1/notification-preferences/me", "GET"},
+		{"/api/v1/notification-preferences/me", "PUT"},
+		// 站内通知
+		{"/api/v1/notifications", "GET"},
+		{"/api/v1/notifications/unread-count", "GET"},
+		{"/api/v1/notifications/:id/read", "POST"},
+		{"/api/v1/notifications/read-all", "POST"},
+		{"/api/v1/notifications/stream/ticket", "POST"},
+		{"/api/v1/notifications/stream", "GET"},
 		// 工单操作
 		{"/api/v1/tickets/:id", "GET"},
 		{"/api/v1/tickets", "POST"},
@@ -306,6 +432,13 @@ func SyncTicketUserPolicies() error {
 		{"/api/v1/tickets/:id/submit", "POST"},
 		{"/api/v1/tickets/:id/approve", "POST"},
 		{"/api/v1/tickets/:id/cancel", "POST"},
+		{"/api/v1/tickets/:id/watchers", "GET"},
+		// 批量操作（逐个校验权限）
+		{"/api/v1/tickets/batch/approve", "POST"},
+		{"/api/v1/tickets/batch/cancel", "POST"},
+		{"/api/v1/tickets/batch/reassign", "POST"},
+		{"/api/v1/tickets/batch/priority", "POST"},
+		{"/api/v1/tickets/batch/watchers", "POST"},
 		// 附件
 		{"/api/v1/attachments/ticket/:ticket_id", "POST"},
 		{"/api/v1/attachments/ticket/:ticket_id", "GET"},
@@ -316,6 +449,8 @@ func SyncTicketUserPolicies() error {
 		// 审批记录
 		{"/api/v1/tickets/:id/records", "GET"},
 		{"/api/v1/tickets/:id/can-approve", "GET"},
+		{"/api/v1/tickets/:id/field-permissions", "GET"},
+		{"/api/v1/tickets/:id/data-changes", "GET"},
 		// 用户列表（选择审批人等场景需要）
 		{"/api/v1/users", "GET"},
 		// 角色列表（审批流程中选择角色需要）
@@ -0,0 +1,45 @@
+package global
+
+import (
+	"fmt"
+	"sync"
+
+	"backend/internal/config"
+	"backend/pkg/search"
+)
+
+var (
+	searchIndex  search.Index
+	searchConfig search.Config
+	searchMu     sync.RWMutex
+)
+
+// InitSearch 初始化全文索引
+func InitSearch(cfg *config.Config) (search.Index, error) {
+	searchConfig = search.Config{Engine: cfg.Search.Engine}
+	idx, err := search.NewIndex(&searchConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init search index: %w", err)
+	}
+	SetSearchIndex(idx)
+	return idx, nil
+}
+
+// NewSearchIndex 按当前配置创建一个新的空索引（用于全量重建后替换）
+func NewSearchIndex() (search.Index, error) {
+	return search.NewIndex(&searchConfig)
+}
+
+// GetSearchIndex 获取全文索引实例
+func GetSearchIndex() search.Index {
+	searchMu.RLock()
+	defer searchMu.RUnlock()
+	return searchIndex
+}
+
+// SetSearchIndex 替换全文索引实例
+func SetSearchIndex(idx search.Index) {
+	searchMu.Lock()
+	defer searchMu.Unlock()
+	searchIndex = idx
+}
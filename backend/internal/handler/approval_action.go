@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"backend/internal/model/request"
+	"backend/internal/model/response"
+	"backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// approvalActionPage 通知审批链接的确认页和结果页（无需登录，独立于前端渲染）
+var approvalActionPage = template.Must(template.New("approval_action").Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>工单审批</title>
+<style>
+body{font-family:-apple-system,BlinkMacSystemFont,"Segoe UI","PingFang SC","Microsoft YaHei",sans-serif;background:#f5f5f5;margin:0;padding:24px}
+.card{max-width:480px;margin:40px auto;background:#fff;border-radius:8px;padding:24px;box-shadow:0 1px 3px rgba(0,0,0,.1)}
+h1{font-size:18px;margin:0 0 16px}
+p{color:#555;margin:6px 0}
+textarea{width:100%;box-sizing:border-box;min-height:80px;margin:12px 0;padding:8px;border:1px solid #ddd;border-radius:4px}
+button{width:100%;padding:10px;border:0;border-radius:4px;color:#fff;font-size:15px;cursor:pointer}
+.approve{background:#16a34a}.reject{background:#dc2626}.error{color:#dc2626}
+</style>
+</head>
+<body>
+<div class="card">
+{{if .Error}}
+<h1>无法处理</h1>
+<p class="error">{{.Error}}</p>
+{{else if .Done}}
+<h1>已{{.Info.ActionText}}</h1>
+<p>工单 {{.Info.TicketNumber}}「{{.Info.TicketTitle}}」已{{.Info.ActionText}}。</p>
+{{else}}
+<h1>确认{{.Info.ActionText}}</h1>
+<p>工单编号：{{.Info.TicketNumber}}</p>
+<p>工单标题：{{.Info.TicketTitle}}</p>
+<p>审批节点：{{.Info.NodeName}}</p>
+<p>审批人：{{.Info.Approver}}</p>
+<form method="post" action="">
+<input type="hidden" name="token" value="{{.Token}}">
+<textarea name="comment" maxlength="500" placeholder="审批意见（可选）"></textarea>
+<button type="submit" class="{{.Info.Action}}">确认{{.Info.ActionText}}</button>
+</form>
+{{end}}
+</div>
+</body>
+</html>`))
+
+type approvalActionPageData struct {
+	Token string
+	Info  *service.ApprovalActionInfo
+	Done  bool
+	Error string
+}
+
+type ApprovalActionHandler struct {
+	svc *service.ApprovalActionService
+}
+
+func NewApprovalActionHandler() *ApprovalActionHandler {
+	return &ApprovalActionHandler{svc: service.NewApprovalActionService()}
+}
+
+// Show 审批链接确认页：仅校验链接，不执行审批（避免邮件安全扫描预取链接时误操作）
+func (h *ApprovalActionHandler) Show(c *gin.Context) {
+	token := c.Query("token")
+	info, err := h.svc.Inspect(token)
+	if wantsJSON(c) {
+		if err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		response.Success(c, info)
+		return
+	}
+	data := approvalActionPageData{Token: token, Info: info}
+	if err != nil {
+		data.Error = err.Error()
+	}
+	renderApprovalActionPage(c, data)
+}
+
+// Confirm 确认执行审批链接对应的操作
+func (h *ApprovalActionHandler) Confirm(c *gin.Context) {
+	var req request.ApprovalActionConfirmRequest
+	if err := c.ShouldBind(&req); err != nil {
+		if wantsJSON(c) {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		renderApprovalActionPage(c, approvalActionPageData{Error: "审批链接无效"})
+		return
+	}
+	info, err := h.svc.Confirm(req.Token, req.Comment)
+	if wantsJSON(c) {
+		if err != nil {
+			badRequest(c, err)
+			return
+		}
+		response.Success(c, info)
+		return
+	}
+	data := approvalActionPageData{Info: info, Done: err == nil}
+	if err != nil {
+		data.Error = err.Error()
+	}
+	renderApprovalActionPage(c, data)
+}
+
+// wantsJSON 调用方是否期望 JSON 响应（浏览器打开链接时返回 HTML 页面）
+func wantsJSON(c *gin.Context) bool {
+	return c.ContentType() == "application/json" || strings.Contains(c.GetHeader("Accept"), "application/json")
+}
+
+func renderApprovalActionPage(c *gin.Context, data approvalActionPageData) {
+	var buf bytes.Buffer
+	if err := approvalActionPage.Execute(&buf, data); err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", buf.Bytes())
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"errors"
+
+	"backend/internal/model/request"
+	"backend/internal/model/response"
+	"backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DingTalkHandler struct {
+	svc *service.DingTalkCallbackService
+}
+
+func NewDingTalkHandler() *DingTalkHandler {
+	return &DingTalkHandler{svc: service.NewDingTalkCallbackService()}
+}
+
+// Callback 钉钉企业内部应用事件回调（URL 校验、审批卡片按钮回调），返回钉钉要求的加密响应
+func (h *DingTalkHandler) Callback(c *gin.Context) {
+	var req request.DingTalkCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	signature := c.Query("msg_signature")
+	if signature == "" {
+		signature = c.Query("signature")
+	}
+	resp, err := h.svc.Handle(service.DingTalkCallbackQuery{
+		Signature: signature,
+		Timestamp: c.Query("timestamp"),
+		Nonce:     c.Query("nonce"),
+	}, req.Encrypt)
+	if err != nil {
+		if errors.Is(err, service.ErrDingTalkCallbackSignature) || errors.Is(err, service.ErrDingTalkCallbackExpired) {
+			response.Forbidden(c, err.Error())
+			return
+		}
+		response.BadRequest(c, err.Error())
+		return
+	}
+	c.JSON(200, resp)
+}
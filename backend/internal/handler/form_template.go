@@ -105,9 +105,109 @@ func (h *FormTemplateHandler) SaveFields(c *gin.Context) {
 		response.BadRequest(c, err.Error())
 		return
 	}
-	if err := h.svc.SaveFields(uint(id), fields); err != nil {
+	userID, _ := c.Get("user_id")
+	if err := h.svc.SaveFields(uint(id), fields, userID.(uint)); err != nil {
 		response.BadRequest(c, err.Error())
 		return
 	}
 	response.Success(c, nil)
 }
+
+// ExportSchema 导出表单模板的 JSON Schema
+func (h *FormTemplateHandler) ExportSchema(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	schema, err := h.svc.ExportSchema(uint(id))
+	if err != nil {
+		response.NotFound(c, err.Error())
+		return
+	}
+	response.Success(c, schema)
+}
+
+// ImportSchema 从 JSON Schema 创建表单模板
+func (h *FormTemplateHandler) ImportSchema(c *gin.Context) {
+	h.importSchema(c, 0)
+}
+
+// UpdateSchema 用 JSON Schema 更新表单模板字段（生成新版本）
+func (h *FormTemplateHandler) UpdateSchema(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	h.importSchema(c, uint(id))
+}
+
+func (h *FormTemplateHandler) importSchema(c *gin.Context, id uint) {
+	doc, err := c.GetRawData()
+	if err != nil || len(doc) == 0 {
+		response.BadRequest(c, "请提供 JSON Schema")
+		return
+	}
+	userID, _ := c.Get("user_id")
+	t, err := h.svc.ImportSchema(id, doc, userID.(uint))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	response.Success(c, t)
+}
+
+// ListVersions 表单模板版本列表
+func (h *FormTemplateHandler) ListVersions(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	versions, err := h.svc.ListVersions(uint(id))
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+	response.Success(c, versions)
+}
+
+// GetVersion 表单模板指定版本的字段快照
+func (h *FormTemplateHandler) GetVersion(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	version, _ := strconv.Atoi(c.Param("version"))
+	detail, err := h.svc.GetVersion(uint(id), version)
+	if err != nil {
+		response.NotFound(c, err.Error())
+		return
+	}
+	response.Success(c, detail)
+}
+
+// CompareVersions 对比两个版本的字段差异
+func (h *FormTemplateHandler) CompareVersions(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	from, err1 := strconv.Atoi(c.Query("from"))
+	to, err2 := strconv.Atoi(c.Query("to"))
+	if err1 != nil || err2 != nil {
+		response.BadRequest(c, "请指定对比的版本 from 和 to")
+		return
+	}
+	diff, err := h.svc.CompareVersions(uint(id), from, to)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	response.Success(c, diff)
+}
+
+// GetFieldOptions 获取字段选项（动态来源、级联字段通过 parent 传入父级值）
+func (h *FormTemplateHandler) GetFieldOptions(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	options, err := h.svc.GetFieldOptions(uint(id), c.Query("parent"))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	response.Success(c, options)
+}
+
+// RefreshFieldOptions 刷新字段的动态选项缓存
+func (h *FormTemplateHandler) RefreshFieldOptions(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	options, err := h.svc.RefreshFieldOptions(uint(id), c.Query("parent"))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	response.Success(c, options)
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"backend/internal/model/request"
+	"backend/internal/model/response"
+	"backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// streamHeartbeat 推送连接心跳间隔，避免代理断开空闲连接
+const streamHeartbeat = 30 * time.Second
+
+type NotificationHandler struct {
+	svc *service.NotificationService
+}
+
+func NewNotificationHandler() *NotificationHandler {
+	return &NotificationHandler{svc: service.NewNotificationService()}
+}
+
+// List 当前用户的站内通知
+func (h *NotificationHandler) List(c *gin.Context) {
+	var req request.ListNotificationRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	userID, _ := c.Get("user_id")
+	items, total, err := h.svc.ListInApp(userID.(uint), req.Unread, req.GetPage(), req.GetPageSize())
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+	response.Success(c, response.NewPageResponse(items, total, req.GetPage(), req.GetPageSize()))
+}
+
+// UnreadCount 未读通知数
+func (h *NotificationHandler) UnreadCount(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	count, err := h.svc.UnreadCount(userID.(uint))
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+	response.Success(c, gin.H{"count": count})
+}
+
+// MarkRead 标记已读
+func (h *NotificationHandler) MarkRead(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	userID, _ := c.Get("user_id")
+	if err := h.svc.MarkRead(userID.(uint), uint(id)); err != nil {
+		response.NotFound(c, err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+// MarkAllRead 全部标记已读
+func (h *NotificationHandler) MarkAllRead(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	count, err := h.svc.MarkAllRead(userID.(uint))
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+	response.Success(c, gin.H{"count": count})
+}
+
+// StreamTicket 签发推送连接票据，用于建立 SSE 连接（EventSource 无法设置请求头）
+func (h *NotificationHandler) StreamTicket(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	t, err := h.svc.IssueStreamTicket(userID.(uint))
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+	response.Success(c, gin.H{"ticket": t.Ticket, "expires_at": t.ExpiresAt})
+}
+
+// Stream 通过 Server-Sent Events 实时推送新通知：连接建立时发送 unread 事件，之后每条新通知发送 notification 事件
+func (h *NotificationHandler) Stream(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	uid := userID.(uint)
+	notifications, cancel := h.svc.SubscribeInApp(uid)
+	defer cancel()
+
+	// 长连接不受服务器写超时限制
+	http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	count, _ := h.svc.UnreadCount(uid)
+	c.SSEvent("unread", gin.H{"count": count})
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case n, ok := <-notifications:
+			if !ok {
+				return false
+			}
+			c.SSEvent("notification", n)
+			return true
+		case <-heartbeat.C:
+			c.SSEvent("ping", time.Now().Unix())
+			return true
+		}
+	})
+}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"strconv"
+
+	"backend/internal/model/request"
+	"backend/internal/model/response"
+	"backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type NotificationOutboxHandler struct {
+	svc *service.NotificationService
+}
+
+func NewNotificationOutboxHandler() *NotificationOutboxHandler {
+	return &NotificationOutboxHandler{svc: service.NewNotificationService()}
+}
+
+// List 发件箱列表
+func (h *NotificationOutboxHandler) List(c *gin.Context) {
+	var req request.ListNotificationOutboxRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	items, total, err := h.svc.ListOutbox(service.OutboxFilter{
+		Status:   req.Status,
+		Channel:  req.Channel,
+		Event:    req.Event,
+		TicketID: req.TicketID,
+		UserID:   req.UserID,
+	}, req.GetPage(), req.GetPageSize())
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+	response.Success(c, response.NewPageResponse(items, total, req.GetPage(), req.GetPageSize()))
+}
+
+// GetByID 发件箱通知详情
+func (h *NotificationOutboxHandler) GetByID(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	item, err := h.svc.GetOutbox(uint(id))
+	if err != nil {
+		response.NotFound(c, err.Error())
+		return
+	}
+	response.Success(c, item)
+}
+
+// Resend 重新发送通知
+func (h *NotificationOutboxHandler) Resend(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err := h.svc.Resend(uint(id)); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+// ResendFailed 重新发送全部失败的通知
+func (h *NotificationOutboxHandler) ResendFailed(c *gin.Context) {
+	count, err := h.svc.ResendFailed(c.Query("channel"))
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+	response.Success(c, gin.H{"count": count})
+}
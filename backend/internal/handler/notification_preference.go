@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"backend/internal/model/request"
+	"backend/internal/model/response"
+	"backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type NotificationPreferenceHandler struct {
+	svc *service.NotificationService
+}
+
+func NewNotificationPreferenceHandler() *NotificationPreferenceHandler {
+	return &NotificationPreferenceHandler{svc: service.NewNotificationService()}
+}
+
+// Get 当前用户的通知偏好
+func (h *NotificationPreferenceHandler) Get(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	pref, err := h.svc.GetPreference(userID.(uint))
+	if err != nil {
+		response.NotFound(c, err.Error())
+		return
+	}
+	response.Success(c, pref)
+}
+
+// Update 更新当前用户的通知偏好
+func (h *NotificationPreferenceHandler) Update(c *gin.Context) {
+	var req request.NotificationPreferenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	userID, _ := c.Get("user_id")
+	if err := h.svc.SavePreference(userID.(uint), req.EventChannels, req.QuietStart, req.QuietEnd, req.Locale); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	pref, err := h.svc.GetPreference(userID.(uint))
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+	response.Success(c, pref)
+}
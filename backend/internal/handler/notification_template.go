@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"strconv"
+
+	"backend/internal/model"
+	"backend/internal/model/request"
+	"backend/internal/model/response"
+	"backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type NotificationTemplateHandler struct {
+	svc *service.NotificationTemplateService
+}
+
+func NewNotificationTemplateHandler() *NotificationTemplateHandler {
+	return &NotificationTemplateHandler{svc: service.NewNotificationTemplateService()}
+}
+
+// List 通知模板列表
+func (h *NotificationTemplateHandler) List(c *gin.Context) {
+	templates, err := h.svc.List(c.Query("event"), c.Query("channel"), c.Query("locale"))
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+	response.Success(c, templates)
+}
+
+// Builtins 内置模板
+func (h *NotificationTemplateHandler) Builtins(c *gin.Context) {
+	response.Success(c, h.svc.Builtins())
+}
+
+func (h *NotificationTemplateHandler) GetByID(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	tpl, err := h.svc.Get(uint(id))
+	if err != nil {
+		response.NotFound(c, err.Error())
+		return
+	}
+	response.Success(c, tpl)
+}
+
+func (h *NotificationTemplateHandler) Create(c *gin.Context) {
+	var req request.NotificationTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	userID, _ := c.Get("user_id")
+	tpl := toNotificationTemplate(&req, userID.(uint))
+	if err := h.svc.Create(tpl); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	response.Success(c, tpl)
+}
+
+func (h *NotificationTemplateHandler) Update(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	var req request.NotificationTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	userID, _ := c.Get("user_id")
+	if err := h.svc.Update(uint(id), toNotificationTemplate(&req, userID.(uint))); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+func (h *NotificationTemplateHandler) Delete(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err := h.svc.Delete(uint(id)); err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+// Preview 使用示例数据或指定工单预览模板
+func (h *NotificationTemplateHandler) Preview(c *gin.Context) {
+	var req request.NotificationTemplatePreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	preview, err := h.svc.Preview(req.Event, req.Channel, req.Subject, req.Body, req.TicketID)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	response.Success(c, preview)
+}
+
+func toNotificationTemplate(req *request.NotificationTemplateRequest, operatorID uint) *model.NotificationTemplate {
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+	return &model.NotificationTemplate{
+		Event:       req.Event,
+		Channel:     req.Channel,
+		Locale:      req.Locale,
+		Subject:     req.Subject,
+		Body:        req.Body,
+		Enabled:     enabled,
+		UpdatedByID: operatorID,
+	}
+}
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"strconv"
+	"strings"
+
+	"backend/internal/model"
+	"backend/internal/model/request"
+	"backend/internal/model/response"
+	"backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReportSubscriptionHandler struct {
+	svc *service.ReportSubscriptionService
+}
+
+func NewReportSubscriptionHandler() *ReportSubscriptionHandler {
+	return &ReportSubscriptionHandler{svc: service.NewReportSubscriptionService()}
+}
+
+func (h *ReportSubscriptionHandler) List(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	subs, err := h.svc.List(userID.(uint))
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+	response.Success(c, subs)
+}
+
+func (h *ReportSubscriptionHandler) GetByID(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	userID, _ := c.Get("user_id")
+	sub, err := h.svc.Get(uint(id), userID.(uint), isAdminUser(userID.(uint)))
+	if err != nil {
+		response.NotFound(c, err.Error())
+		return
+	}
+	response.Success(c, sub)
+}
+
+func (h *ReportSubscriptionHandler) Create(c *gin.Context) {
+	var req request.ReportSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	userID, _ := c.Get("user_id")
+	sub := toReportSubscription(&req)
+	sub.OwnerID = userID.(uint)
+	if err := h.svc.Create(sub); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	response.Success(c, sub)
+}
+
+func (h *ReportSubscriptionHandler) Update(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	var req request.ReportSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	userID, _ := c.Get("user_id")
+	if err := h.svc.Update(uint(id), userID.(uint), isAdminUser(userID.(uint)), toReportSubscription(&req)); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+func (h *ReportSubscriptionHandler) Delete(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	userID, _ := c.Get("user_id")
+	if err := h.svc.Delete(uint(id), userID.(uint), isAdminUser(userID.(uint))); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+// Send 立即发送一次报表
+func (h *ReportSubscriptionHandler) Send(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	userID, _ := c.Get("user_id")
+	if err := h.svc.SendNow(uint(id), userID.(uint), isAdminUser(userID.(uint))); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+func toReportSubscription(req *request.ReportSubscriptionRequest) *model.ReportSubscription {
+	filter := ""
+	if len(req.Filter) > 0 && string(req.Filter) != "null" {
+		filter = string(req.Filter)
+	}
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+	weekday, sendHour := 1, 9
+	if req.Weekday != nil {
+		weekday = *req.Weekday
+	}
+	if req.SendHour != nil {
+		sendHour = *req.SendHour
+	}
+	return &model.ReportSubscription{
+		Name:       req.Name,
+		ReportType: req.ReportType,
+		Frequency:  req.Frequency,
+		Weekday:    weekday,
+		SendHour:   sendHour,
+		Filter:     filter,
+		Recipients: strings.Join(req.Recipients, ","),
+		Enabled:    enabled,
+	}
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"encoding/json"
 	"strconv"
 
 	"backend/internal/model"
@@ -21,26 +22,34 @@ func NewTemplateHandler() *TemplateHandler {
 }
 
 func (h *TemplateHandler) Create(c *gin.Context) {
-	var req model.TicketTemplate
+	var req request.TicketTemplateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		response.BadRequest(c, err.Error())
 		return
 	}
-	if err := h.svc.Create(&req); err != nil {
+	userID, _ := c.Get("user_id")
+	t := toTicketTemplate(&req)
+	// 普通用户只能创建个人模板
+	if req.Personal || !isAdminUser(userID.(uint)) {
+		ownerID := userID.(uint)
+		t.OwnerID = &ownerID
+	}
+	if err := h.svc.Create(t); err != nil {
 		response.BadRequest(c, err.Error())
 		return
 	}
-	response.Success(c, req)
+	response.Success(c, t)
 }
 
 func (h *TemplateHandler) Update(c *gin.Context) {
 	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
-	var req model.TicketTemplate
+	var req request.TicketTemplateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		response.BadRequest(c, err.Error())
 		return
 	}
-	if err := h.svc.Update(uint(id), &req); err != nil {
+	userID, _ := c.Get("user_id")
+	if err := h.svc.Update(uint(id), userID.(uint), isAdminUser(userID.(uint)), toTicketTemplate(&req)); err != nil {
 		response.BadRequest(c, err.Error())
 		return
 	}
@@ -49,7 +58,8 @@ func (h *TemplateHandler) Update(c *gin.Context) {
 
 func (h *TemplateHandler) Delete(c *gin.Context) {
 	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
-	if err := h.svc.Delete(uint(id)); err != nil {
+	userID, _ := c.Get("user_id")
+	if err := h.svc.Delete(uint(id), userID.(uint), isAdminUser(userID.(uint))); err != nil {
 		response.BadRequest(c, err.Error())
 		return
 	}
@@ -58,7 +68,8 @@ func (h *TemplateHandler) Delete(c *gin.Context) {
 
 func (h *TemplateHandler) GetByID(c *gin.Context) {
 	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
-	t, err := h.svc.GetByID(uint(id))
+	userID, _ := c.Get("user_id")
+	t, err := h.svc.GetByID(uint(id), userID.(uint))
 	if err != nil {
 		response.NotFound(c, "模板不存在")
 		return
@@ -72,8 +83,9 @@ func (h *TemplateHandler) List(c *gin.Context) {
 		response.BadRequest(c, err.Error())
 		return
 	}
+	userID, _ := c.Get("user_id")
 
-	templates, total, err := h.svc.List(req.GetPage(), req.GetPageSize(), req.Keyword, req.TypeID)
+	templates, total, err := h.svc.List(req.GetPage(), req.GetPageSize(), req.Keyword, req.TypeID, userID.(uint))
 	if err != nil {
 		response.InternalError(c, err.Error())
 		return
@@ -83,10 +95,72 @@ func (h *TemplateHandler) List(c *gin.Context) {
 
 func (h *TemplateHandler) ListEnabled(c *gin.Context) {
 	typeID, _ := strconv.ParseUint(c.Query("type_id"), 10, 32)
-	templates, err := h.svc.ListEnabled(uint(typeID))
+	userID, _ := c.Get("user_id")
+	templates, err := h.svc.ListEnabled(uint(typeID), userID.(uint))
 	if err != nil {
 		response.InternalError(c, err.Error())
 		return
 	}
 	response.Success(c, templates)
 }
+
+// GetPresets 获取按当前用户替换占位符后的模板预设（用于预填表单）
+func (h *TemplateHandler) GetPresets(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	userID, _ := c.Get("user_id")
+	resolved, err := h.svc.GetResolved(uint(id), userID.(uint))
+	if err != nil {
+		response.NotFound(c, err.Error())
+		return
+	}
+	response.Success(c, resolved)
+}
+
+// CreateTicket 基于模板创建工单，请求中的非空项覆盖模板预设
+func (h *TemplateHandler) CreateTicket(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	var req request.CreateFromTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	userID, _ := c.Get("user_id")
+	ticket, err := h.svc.CreateTicket(uint(id), userID.(uint), &service.TemplateTicketInput{
+		Title:       req.Title,
+		Description: req.Description,
+		Priority:    req.Priority,
+		FormData:    req.FormData,
+		Submit:      req.Submit,
+	})
+	if err != nil {
+		badRequest(c, err)
+		return
+	}
+	response.Success(c, ticket)
+}
+
+func toTicketTemplate(req *request.TicketTemplateRequest) *model.TicketTemplate {
+	presets := ""
+	if len(req.PresetValues) > 0 && string(req.PresetValues) != "null" {
+		presets = string(req.PresetValues)
+		// 兼容以 JSON 字符串形式提交的预设值
+		var raw string
+		if json.Unmarshal(req.PresetValues, &raw) == nil {
+			presets = raw
+		}
+	}
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+	return &model.TicketTemplate{
+		Name:         req.Name,
+		Description:  req.Description,
+		TypeID:       req.TypeID,
+		Title:        req.Title,
+		Priority:     req.Priority,
+		PresetValues: presets,
+		Enabled:      enabled,
+		SortOrder:    req.SortOrder,
+	}
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"strconv"
 
 	"backend/internal/global"
@@ -33,6 +34,16 @@ func isAdminUser(userID uint) bool {
 	return false
 }
 
+// badRequest 返回业务错误，表单校验错误附带字段级错误明细
+func badRequest(c *gin.Context, err error) {
+	var fe *service.FormValidationError
+	if errors.As(err, &fe) {
+		response.BadRequestWithData(c, err.Error(), gin.H{"field_errors": fe.Errors})
+		return
+	}
+	response.BadRequest(c, err.Error())
+}
+
 func (h *TicketHandler) Create(c *gin.Context) {
 	var req request.CreateTicketRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -51,7 +62,7 @@ func (h *TicketHandler) Create(c *gin.Context) {
 	}
 
 	if err := h.svc.CreateWithFormData(&ticket, req.FormData); err != nil {
-		response.BadRequest(c, err.Error())
+		badRequest(c, err)
 		return
 	}
 	response.Success(c, ticket)
@@ -59,13 +70,19 @@ func (h *TicketHandler) Create(c *gin.Context) {
 
 func (h *TicketHandler) Update(c *gin.Context) {
 	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
-	var req model.Ticket
+	var req request.UpdateTicketRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		response.BadRequest(c, err.Error())
 		return
 	}
-	if err := h.svc.Update(uint(id), &req); err != nil {
-		response.BadRequest(c, err.Error())
+	ticket := model.Ticket{
+		Title:       req.Title,
+		Description: req.Description,
+		Priority:    req.Priority,
+	}
+	userID, _ := c.Get("user_id")
+	if err := h.svc.Update(uint(id), userID.(uint), isAdminUser(userID.(uint)), &ticket, req.FormData); err != nil {
+		badRequest(c, err)
 		return
 	}
 	response.Success(c, nil)
@@ -89,6 +106,8 @@ func (h *TicketHandler) GetByID(c *gin.Context) {
 		response.NotFound(c, "工单不存在")
 		return
 	}
+	userID, _ := c.Get("user_id")
+	h.svc.ApplyNodeFieldVisibility(ticket, userID.(uint), isAdminUser(userID.(uint)))
 	response.Success(c, ticket)
 }
 
@@ -129,7 +148,7 @@ func (h *TicketHandler) List(c *gin.Context) {
 func (h *TicketHandler) Submit(c *gin.Context) {
 	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err := h.svc.Submit(uint(id)); err != nil {
-		response.BadRequest(c, err.Error())
+		badRequest(c, err)
 		return
 	}
 	response.Success(c, nil)
@@ -158,8 +177,8 @@ func (h *TicketHandler) Approve(c *gin.Context) {
 		}
 	}
 
-	if err := h.svc.Approve(uint(id), userID.(uint), req.Approved, req.Comment); err != nil {
-		response.BadRequest(c, err.Error())
+	if err := h.svc.Approve(uint(id), userID.(uint), req.Approved, req.Comment, req.FormData); err != nil {
+		badRequest(c, err)
 		return
 	}
 	response.Success(c, nil)
@@ -382,6 +401,29 @@ func (h *TicketHandler) GetApprovalRecords(c *gin.Context) {
 	response.Success(c, records)
 }
 
+// GetFieldPermissions 获取当前审批节点的字段权限
+func (h *TicketHandler) GetFieldPermissions(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	perms, err := h.svc.GetNodeFieldPermissions(uint(id))
+	if err != nil {
+		response.NotFound(c, err.Error())
+		return
+	}
+	response.Success(c, perms)
+}
+
+// GetDataChanges 获取表单数据修改记录
+func (h *TicketHandler) GetDataChanges(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	userID, _ := c.Get("user_id")
+	changes, err := h.svc.GetDataChanges(uint(id), userID.(uint), isAdminUser(userID.(uint)))
+	if err != nil {
+		response.NotFound(c, err.Error())
+		return
+	}
+	response.Success(c, changes)
+}
+
 // CanApprove 检查当前用户是否可以审批
 func (h *TicketHandler) CanApprove(c *gin.Context) {
 	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
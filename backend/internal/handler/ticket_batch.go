@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"strconv"
+
+	"backend/internal/model/request"
+	"backend/internal/model/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BatchApprove 批量审批（通过/拒绝）
+func (h *TicketHandler) BatchApprove(c *gin.Context) {
+	var req request.BatchApproveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	userID, _ := c.Get("user_id")
+	isAdmin := isAdminUser(userID.(uint))
+	response.Success(c, h.svc.BatchApprove(userID.(uint), isAdmin, req.TicketIDs, req.Approved, req.Comment))
+}
+
+// BatchCancel 批量取消
+func (h *TicketHandler) BatchCancel(c *gin.Context) {
+	var req request.BatchTicketRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	userID, _ := c.Get("user_id")
+	isAdmin := isAdminUser(userID.(uint))
+	response.Success(c, h.svc.BatchCancel(userID.(uint), isAdmin, req.TicketIDs))
+}
+
+// BatchReassign 批量转交处理人
+func (h *TicketHandler) BatchReassign(c *gin.Context) {
+	var req request.BatchReassignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	userID, _ := c.Get("user_id")
+	isAdmin := isAdminUser(userID.(uint))
+	response.Success(c, h.svc.BatchReassign(userID.(uint), isAdmin, req.TicketIDs, req.TargetUserID))
+}
+
+// BatchSetPriority 批量修改优先级
+func (h *TicketHandler) BatchSetPriority(c *gin.Context) {
+	var req request.BatchPriorityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	userID, _ := c.Get("user_id")
+	isAdmin := isAdminUser(userID.(uint))
+	response.Success(c, h.svc.BatchSetPriority(userID.(uint), isAdmin, req.TicketIDs, req.Priority))
+}
+
+// BatchAddWatchers 批量添加关注人
+func (h *TicketHandler) BatchAddWatchers(c *gin.Context) {
+	var req request.BatchWatchersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	userID, _ := c.Get("user_id")
+	isAdmin := isAdminUser(userID.(uint))
+	response.Success(c, h.svc.BatchAddWatchers(userID.(uint), isAdmin, req.TicketIDs, req.UserIDs))
+}
+
+// GetWatchers 获取工单关注人
+func (h *TicketHandler) GetWatchers(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	watchers, err := h.svc.GetWatchers(uint(id))
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+	response.Success(c, watchers)
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"net/url"
+	"strconv"
+
+	"backend/internal/model"
+	"backend/internal/model/request"
+	"backend/internal/model/response"
+	"backend/internal/service"
+	"backend/pkg/logger"
+	"backend/pkg/tabular"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type TicketExportHandler struct {
+	svc *service.TicketExportService
+}
+
+func NewTicketExportHandler() *TicketExportHandler {
+	return &TicketExportHandler{svc: service.NewTicketExportService()}
+}
+
+// Export 导出工单：数据量较小时直接下载，数据量大或指定 async 时创建后台任务
+func (h *TicketExportHandler) Export(c *gin.Context) {
+	var req request.ExportTicketRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	if req.Format == "" {
+		req.Format = tabular.FormatXLSX
+	}
+	userID, _ := c.Get("user_id")
+	filter := &service.TicketExportFilter{
+		Keyword:   req.Keyword,
+		Status:    req.Status,
+		TypeID:    req.TypeID,
+		CreatorID: req.CreatorID,
+		UserID:    userID.(uint),
+		IsAdmin:   isAdminUser(userID.(uint)),
+	}
+
+	async := req.Async
+	if !async {
+		var err error
+		if async, err = h.svc.ShouldRunAsync(filter); err != nil {
+			response.InternalError(c, err.Error())
+			return
+		}
+	}
+	if async {
+		job, err := h.svc.CreateJob(filter, req.Format)
+		if err != nil {
+			response.InternalError(c, err.Error())
+			return
+		}
+		response.Success(c, gin.H{"async": true, "job": job})
+		return
+	}
+
+	fileName := h.svc.FileName(req.Format)
+	c.Header("Content-Type", tabular.ContentType(req.Format))
+	c.Header("Content-Disposition", "attachment; filename*=UTF-8''"+url.PathEscape(fileName))
+	if _, err := h.svc.Export(filter, req.Format, c.Writer); err != nil {
+		// 响应已开始写入，只能记录日志
+		logger.Error("Ticket export failed", zap.Error(err))
+	}
+}
+
+// ListJobs 获取我的导出任务
+func (h *TicketExportHandler) ListJobs(c *gin.Context) {
+	var req request.PageRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	userID, _ := c.Get("user_id")
+	jobs, total, err := h.svc.ListJobs(userID.(uint), req.GetPage(), req.GetPageSize())
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+	response.Success(c, response.NewPageResponse(jobs, total, req.GetPage(), req.GetPageSize()))
+}
+
+// GetJob 获取导出任务详情，已完成的任务附带下载链接
+func (h *TicketExportHandler) GetJob(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	userID, _ := c.Get("user_id")
+	job, err := h.svc.GetJob(uint(id), userID.(uint))
+	if err != nil {
+		response.NotFound(c, "导出任务不存在")
+		return
+	}
+	result := gin.H{"job": job}
+	if job.Status == model.TicketExportStatusCompleted {
+		downloadURL, err := h.svc.GetJobDownloadURL(c.Request.Context(), job.ID, userID.(uint))
+		if err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		result["url"] = downloadURL
+	}
+	response.Success(c, result)
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"encoding/json"
+
+	"backend/internal/model/request"
+	"backend/internal/model/response"
+	"backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TicketImportHandler struct {
+	svc *service.TicketImportService
+}
+
+func NewTicketImportHandler() *TicketImportHandler {
+	return &TicketImportHandler{svc: service.NewTicketImportService()}
+}
+
+// Import 从 CSV/XLSX 批量导入工单，返回逐行校验结果
+func (h *TicketImportHandler) Import(c *gin.Context) {
+	var req request.ImportTicketRequest
+	if err := c.ShouldBind(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		response.BadRequest(c, "请选择要导入的文件")
+		return
+	}
+	defer file.Close()
+
+	var mapping map[string]string
+	if req.Mapping != "" {
+		if err := json.Unmarshal([]byte(req.Mapping), &mapping); err != nil {
+			response.BadRequest(c, "列映射格式错误")
+			return
+		}
+	}
+
+	userID, _ := c.Get("user_id")
+	result, err := h.svc.Import(header.Filename, file, &service.TicketImportOptions{
+		TypeID:  req.TypeID,
+		Mapping: mapping,
+		DryRun:  req.DryRun,
+		Submit:  req.Submit,
+		UserID:  userID.(uint),
+		IsAdmin: isAdminUser(userID.(uint)),
+	})
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	response.Success(c, result)
+}
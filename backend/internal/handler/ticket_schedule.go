@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"strconv"
+
+	"backend/internal/model"
+	"backend/internal/model/request"
+	"backend/internal/model/response"
+	"backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TicketScheduleHandler struct {
+	svc *service.TicketScheduleService
+}
+
+func NewTicketScheduleHandler() *TicketScheduleHandler {
+	return &TicketScheduleHandler{svc: service.NewTicketScheduleService()}
+}
+
+func (h *TicketScheduleHandler) Create(c *gin.Context) {
+	var req request.TicketScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	userID, _ := c.Get("user_id")
+	sch := toTicketSchedule(&req)
+	sch.CreatedByID = userID.(uint)
+	if err := h.svc.Create(sch); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	response.Success(c, sch)
+}
+
+func (h *TicketScheduleHandler) Update(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	var req request.TicketScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	if err := h.svc.Update(uint(id), toTicketSchedule(&req)); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+func (h *TicketScheduleHandler) Delete(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err := h.svc.Delete(uint(id)); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+func (h *TicketScheduleHandler) GetByID(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	sch, err := h.svc.GetByID(uint(id))
+	if err != nil {
+		response.NotFound(c, "周期工单不存在")
+		return
+	}
+	response.Success(c, sch)
+}
+
+func (h *TicketScheduleHandler) List(c *gin.Context) {
+	var req request.ListTicketScheduleRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	schedules, total, err := h.svc.List(req.GetPage(), req.GetPageSize(), req.Keyword)
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+	response.Success(c, response.NewPageResponse(schedules, total, req.GetPage(), req.GetPageSize()))
+}
+
+// ListRuns 周期工单执行记录
+func (h *TicketScheduleHandler) ListRuns(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	var req request.PageRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	runs, total, err := h.svc.ListRuns(uint(id), req.GetPage(), req.GetPageSize())
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+	response.Success(c, response.NewPageResponse(runs, total, req.GetPage(), req.GetPageSize()))
+}
+
+// Run 立即执行一次
+func (h *TicketScheduleHandler) Run(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	run, err := h.svc.RunNow(uint(id))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	response.Success(c, run)
+}
+
+func toTicketSchedule(req *request.TicketScheduleRequest) *model.TicketSchedule {
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+	priority := req.Priority
+	if priority == 0 {
+		priority = model.TicketPriorityMedium
+	}
+	return &model.TicketSchedule{
+		Name:        req.Name,
+		TemplateID:  req.TemplateID,
+		Cron:        req.Cron,
+		CreatorType: req.CreatorType,
+		CreatorID:   req.CreatorID,
+		RoleID:      req.RoleID,
+		Title:       req.Title,
+		Priority:    priority,
+		AutoSubmit:  req.AutoSubmit,
+		SkipIfOpen:  req.SkipIfOpen,
+		Enabled:     enabled,
+	}
+}
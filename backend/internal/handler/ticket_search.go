@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"backend/internal/model/request"
+	"backend/internal/model/response"
+	"backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TicketSearchHandler struct {
+	svc *service.TicketSearchService
+}
+
+func NewTicketSearchHandler() *TicketSearchHandler {
+	return &TicketSearchHandler{svc: service.NewTicketSearchService()}
+}
+
+// Search 全文检索工单（标题、描述、表单数据、评论、附件名）
+func (h *TicketSearchHandler) Search(c *gin.Context) {
+	var req request.SearchTicketRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	userID, _ := c.Get("user_id")
+	isAdmin := isAdminUser(userID.(uint))
+
+	results, total, err := h.svc.Search(userID.(uint), isAdmin, req.Keyword, req.GetPage(), req.GetPageSize())
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+	response.Success(c, response.NewPageResponse(results, total, req.GetPage(), req.GetPageSize()))
+}
+
+// Rebuild 全量重建检索索引
+func (h *TicketSearchHandler) Rebuild(c *gin.Context) {
+	count, err := h.svc.Rebuild()
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+	response.Success(c, gin.H{"count": count})
+}
@@ -1,11 +1,17 @@
 package handler
 
 import (
+	"fmt"
+	"time"
+
 	"backend/internal/global"
 	"backend/internal/model"
+	"backend/internal/model/request"
 	"backend/internal/model/response"
+	"backend/internal/service"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type TicketStatsHandler struct{}
@@ -14,8 +20,31 @@ func NewTicketStatsHandler() *TicketStatsHandler {
 	return &TicketStatsHandler{}
 }
 
-// GetStats 获取工单统计
+// GetStats 获取工单统计（可选按创建时间范围和工单类型过滤）
 func (h *TicketStatsHandler) GetStats(c *gin.Context) {
+	var req request.TicketAnalyticsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	start, end, err := parseAnalyticsRange(req.Start, req.End)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	scope := func(db *gorm.DB) *gorm.DB {
+		if !start.IsZero() {
+			db = db.Where("tickets.created_at >= ?", start)
+		}
+		if !end.IsZero() {
+			db = db.Where("tickets.created_at < ?", end)
+		}
+		if req.TypeID > 0 {
+			db = db.Where("tickets.type_id = ?", req.TypeID)
+		}
+		return db
+	}
+
 	var stats struct {
 		Total      int64            `json:"total"`
 		ByStatus   map[string]int64 `json:"by_status"`
@@ -26,7 +55,7 @@ func (h *TicketStatsHandler) GetStats(c *gin.Context) {
 	db := global.GetDB()
 
 	// 总数
-	db.Model(&model.Ticket{}).Count(&stats.Total)
+	db.Model(&model.Ticket{}).Scopes(scope).Count(&stats.Total)
 
 	// 按状态统计
 	stats.ByStatus = make(map[string]int64)
@@ -34,13 +63,13 @@ func (h *TicketStatsHandler) GetStats(c *gin.Context) {
 		Status string
 		Count  int64
 	}
-	db.Model(&model.Ticket{}).Select("status, count(*) as count").Group("status").Scan(&statusCounts)
+	db.Model(&model.Ticket{}).Scopes(scope).Select("status, count(*) as count").Group("status").Scan(&statusCounts)
 	for _, sc := range statusCounts {
 		stats.ByStatus[sc.Status] = sc.Count
 	}
 
 	// 按类型统计
-	db.Model(&model.Ticket{}).
+	db.Model(&model.Ticket{}).Scopes(scope).
 		Select("ticket_types.name as type_name, count(*) as count").
 		Joins("LEFT JOIN ticket_types ON tickets.type_id = ticket_types.id").
 		Group("tickets.type_id").
@@ -52,7 +81,7 @@ func (h *TicketStatsHandler) GetStats(c *gin.Context) {
 		Priority int
 		Count    int64
 	}
-	db.Model(&model.Ticket{}).Select("priority, count(*) as count").Group("priority").Scan(&priorityCounts)
+	db.Model(&model.Ticket{}).Scopes(scope).Select("priority, count(*) as count").Group("priority").Scan(&priorityCounts)
 	for _, pc := range priorityCounts {
 		stats.ByPriority[pc.Priority] = pc.Count
 	}
@@ -60,6 +89,145 @@ func (h *TicketStatsHandler) GetStats(c *gin.Context) {
 	response.Success(c, stats)
 }
 
+// GetFieldStats 统计表单数值/时间字段（合计、平均、最小、最大）
+func (h *TicketStatsHandler) GetFieldStats(c *gin.Context) {
+	var req request.FormFieldStatsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	stats, err := service.NewTicketService().AggregateFormField(req.Field, req.TypeID, req.Status)
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+	response.Success(c, stats)
+}
+
+// analyticsFilter 解析统计分析请求参数
+func analyticsFilter(c *gin.Context) (service.AnalyticsFilter, bool) {
+	var req request.TicketAnalyticsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return service.AnalyticsFilter{}, false
+	}
+	start, end, err := parseAnalyticsRange(req.Start, req.End)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return service.AnalyticsFilter{}, false
+	}
+	return service.AnalyticsFilter{Start: start, End: end, TypeID: req.TypeID, Interval: req.Interval, Limit: req.Limit}, true
+}
+
+// parseAnalyticsRange 解析统计时间范围；仅指定日期的结束时间包含当天
+func parseAnalyticsRange(startStr, endStr string) (start, end time.Time, err error) {
+	if startStr != "" {
+		if start, err = parseAnalyticsTime(startStr); err != nil {
+			return start, end, fmt.Errorf("开始时间格式错误: %s", startStr)
+		}
+	}
+	if endStr != "" {
+		if end, err = parseAnalyticsTime(endStr); err != nil {
+			return start, end, fmt.Errorf("结束时间格式错误: %s", endStr)
+		}
+		if len(endStr) == len("2006-01-02") {
+			end = end.AddDate(0, 0, 1)
+		}
+	}
+	return start, end, nil
+}
+
+func parseAnalyticsTime(value string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// GetTrends 新建/完成工单趋势
+func (h *TicketStatsHandler) GetTrends(c *gin.Context) {
+	filter, ok := analyticsFilter(c)
+	if !ok {
+		return
+	}
+	data, err := service.NewTicketAnalyticsService().Trends(filter)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	response.Success(c, data)
+}
+
+// GetNodeDurations 各审批节点耗时
+func (h *TicketStatsHandler) GetNodeDurations(c *gin.Context) {
+	filter, ok := analyticsFilter(c)
+	if !ok {
+		return
+	}
+	data, err := service.NewTicketAnalyticsService().NodeDurations(filter)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	response.Success(c, data)
+}
+
+// GetApproverDurations 各审批人耗时
+func (h *TicketStatsHandler) GetApproverDurations(c *gin.Context) {
+	filter, ok := analyticsFilter(c)
+	if !ok {
+		return
+	}
+	data, err := service.NewTicketAnalyticsService().ApproverDurations(filter)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	response.Success(c, data)
+}
+
+// GetAssigneeThroughput 处理人吞吐量
+func (h *TicketStatsHandler) GetAssigneeThroughput(c *gin.Context) {
+	filter, ok := analyticsFilter(c)
+	if !ok {
+		return
+	}
+	data, err := service.NewTicketAnalyticsService().AssigneeThroughput(filter)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	response.Success(c, data)
+}
+
+// GetNodeRejections 各审批节点拒绝/退回率
+func (h *TicketStatsHandler) GetNodeRejections(c *gin.Context) {
+	filter, ok := analyticsFilter(c)
+	if !ok {
+		return
+	}
+	data, err := service.NewTicketAnalyticsService().NodeRejections(filter)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	response.Success(c, data)
+}
+
+// GetSlowestOpen 停留最久的未完结工单
+func (h *TicketStatsHandler) GetSlowestOpen(c *gin.Context) {
+	filter, ok := analyticsFilter(c)
+	if !ok {
+		return
+	}
+	data, err := service.NewTicketAnalyticsService().SlowestOpen(filter)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	response.Success(c, data)
+}
+
 type TypeCount struct {
 	TypeName string `json:"type_name"`
 	Count    int64  `json:"count"`
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"strconv"
+
+	"backend/internal/model"
+	"backend/internal/model/request"
+	"backend/internal/model/response"
+	"backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TicketViewHandler struct {
+	svc *service.TicketViewService
+}
+
+func NewTicketViewHandler() *TicketViewHandler {
+	return &TicketViewHandler{svc: service.NewTicketViewService()}
+}
+
+// Query 按结构化过滤条件（可基于保存视图）查询工单
+func (h *TicketViewHandler) Query(c *gin.Context) {
+	var req request.QueryTicketRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	userID, _ := c.Get("user_id")
+	isAdmin := isAdminUser(userID.(uint))
+
+	tickets, total, err := h.svc.Run(userID.(uint), isAdmin, req.ViewID, string(req.Filter),
+		req.SortBy, req.SortOrder, req.GetPage(), req.GetPageSize())
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	response.Success(c, response.NewPageResponse(tickets, total, req.GetPage(), req.GetPageSize()))
+}
+
+func (h *TicketViewHandler) List(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	views, err := h.svc.List(userID.(uint))
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+	response.Success(c, views)
+}
+
+func (h *TicketViewHandler) GetByID(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	userID, _ := c.Get("user_id")
+	view, err := h.svc.GetVisible(uint(id), userID.(uint), isAdminUser(userID.(uint)))
+	if err != nil {
+		response.NotFound(c, "视图不存在")
+		return
+	}
+	response.Success(c, view)
+}
+
+func (h *TicketViewHandler) Create(c *gin.Context) {
+	var req request.TicketViewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	userID, _ := c.Get("user_id")
+	view := toTicketView(&req)
+	view.OwnerID = userID.(uint)
+	if err := h.svc.Create(view); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	response.Success(c, view)
+}
+
+func (h *TicketViewHandler) Update(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	var req request.TicketViewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	userID, _ := c.Get("user_id")
+	if err := h.svc.Update(uint(id), userID.(uint), isAdminUser(userID.(uint)), toTicketView(&req)); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+func (h *TicketViewHandler) Delete(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	userID, _ := c.Get("user_id")
+	if err := h.svc.Delete(uint(id), userID.(uint), isAdminUser(userID.(uint))); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+func toTicketView(req *request.TicketViewRequest) *model.TicketView {
+	filter := ""
+	if len(req.Filter) > 0 && string(req.Filter) != "null" {
+		filter = string(req.Filter)
+	}
+	return &model.TicketView{
+		Name:        req.Name,
+		Description: req.Description,
+		Visibility:  req.Visibility,
+		RoleID:      req.RoleID,
+		Filter:      filter,
+		SortBy:      req.SortBy,
+		SortOrder:   req.SortOrder,
+	}
+}
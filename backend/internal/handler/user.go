@@ -33,11 +33,14 @@ func (h *UserHandler) Create(c *gin.Context) {
 
 	// 构建 User 对象
 	user := model.User{
-		Username: req.Username,
-		Email:    req.Email,
-		Phone:    req.Phone,
-		Password: req.Password,
-		Status:   req.Status,
+		Username:       req.Username,
+		Email:          req.Email,
+		Phone:          req.Phone,
+		Department:     req.Department,
+		DingTalkUserID: req.DingTalkUserID,
+		WeChatUserID:   req.WeChatUserID,
+		Password:       req.Password,
+		Status:         req.Status,
 	}
 
 	// 创建用户
@@ -83,9 +86,23 @@ func (h *UserHandler) Update(c *gin.Context) {
 		Status:   req.Status,
 		Password: req.Password,
 	}
+	// 可选字段仅在请求中提供时更新
+	var optionalFields []string
+	if req.Department != nil {
+		user.Department = *req.Department
+		optionalFields = append(optionalFields, "department")
+	}
+	if req.DingTalkUserID != nil {
+		user.DingTalkUserID = *req.DingTalkUserID
+		optionalFields = append(optionalFields, "ding_talk_user_id")
+	}
+	if req.WeChatUserID != nil {
+		user.WeChatUserID = *req.WeChatUserID
+		optionalFields = append(optionalFields, "we_chat_user_id")
+	}
 
 	// 更新用户基本信息
-	if err := h.userService.Update(userID, &user); err != nil {
+	if err := h.userService.Update(userID, &user, optionalFields...); err != nil {
 		response.BadRequest(c, err.Error())
 		return
 	}
@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"strconv"
+	"strings"
+
+	"backend/internal/model"
+	"backend/internal/model/request"
+	"backend/internal/model/response"
+	"backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookHandler struct {
+	svc *service.WebhookService
+}
+
+func NewWebhookHandler() *WebhookHandler {
+	return &WebhookHandler{svc: service.NewWebhookService()}
+}
+
+// webhookWithSecret 包含签名密钥的 Webhook，仅在创建和重置密钥时返回
+type webhookWithSecret struct {
+	*model.Webhook
+	Secret string `json:"secret"`
+}
+
+func (h *WebhookHandler) List(c *gin.Context) {
+	hooks, err := h.svc.List()
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+	response.Success(c, hooks)
+}
+
+func (h *WebhookHandler) GetByID(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	hook, err := h.svc.Get(uint(id))
+	if err != nil {
+		response.NotFound(c, err.Error())
+		return
+	}
+	response.Success(c, hook)
+}
+
+func (h *WebhookHandler) Create(c *gin.Context) {
+	var req request.WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	userID, _ := c.Get("user_id")
+	hook := toWebhook(&req)
+	hook.CreatedByID = userID.(uint)
+	if err := h.svc.Create(hook); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	response.Success(c, webhookWithSecret{Webhook: hook, Secret: hook.Secret})
+}
+
+func (h *WebhookHandler) Update(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	var req request.WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	if err := h.svc.Update(uint(id), toWebhook(&req)); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+// RotateSecret 重置签名密钥
+func (h *WebhookHandler) RotateSecret(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	hook, err := h.svc.RotateSecret(uint(id))
+	if err != nil {
+		response.NotFound(c, err.Error())
+		return
+	}
+	response.Success(c, webhookWithSecret{Webhook: hook, Secret: hook.Secret})
+}
+
+func (h *WebhookHandler) Delete(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err := h.svc.Delete(uint(id)); err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+// Ping 发送测试事件
+func (h *WebhookHandler) Ping(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	d, err := h.svc.Ping(uint(id))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	response.Success(c, d)
+}
+
+// ListDeliveries 投递记录
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	var req request.ListWebhookDeliveryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	deliveries, total, err := h.svc.ListDeliveries(uint(id), req.Status, req.GetPage(), req.GetPageSize())
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+	response.Success(c, response.NewPageResponse(deliveries, total, req.GetPage(), req.GetPageSize()))
+}
+
+// GetDelivery 投递记录详情
+func (h *WebhookHandler) GetDelivery(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	d, err := h.svc.GetDelivery(uint(id))
+	if err != nil {
+		response.NotFound(c, err.Error())
+		return
+	}
+	response.Success(c, d)
+}
+
+// Redeliver 重新投递
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	d, err := h.svc.Redeliver(uint(id))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	response.Success(c, d)
+}
+
+func toWebhook(req *request.WebhookRequest) *model.Webhook {
+	typeIDs := make([]string, 0, len(req.TypeIDs))
+	for _, id := range req.TypeIDs {
+		typeIDs = append(typeIDs, strconv.FormatUint(uint64(id), 10))
+	}
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+	return &model.Webhook{
+		Name:    req.Name,
+		URL:     req.URL,
+		Secret:  req.Secret,
+		Events:  strings.Join(req.Events, ","),
+		TypeIDs: strings.Join(typeIDs, ","),
+		Enabled: enabled,
+	}
+}
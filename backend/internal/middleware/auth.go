@@ -3,6 +3,7 @@ package middleware
 import (
 	"strings"
 
+	"backend/internal/service"
 	"backend/internal/service/sso"
 	"backend/pkg/jwt"
 	"backend/internal/model/response"
@@ -45,6 +46,28 @@ func AuthMiddleware() gin.HandlerFunc {
 	}
 }
 
+// StreamAuthMiddleware 推送连接认证中间件：EventSource 无法设置请求头，
+// 允许通过 ticket 查询参数传递推送连接票据（短期有效、仅可使用一次）
+func StreamAuthMiddleware() gin.HandlerFunc {
+	auth := AuthMiddleware()
+	svc := service.NewNotificationService()
+	return func(c *gin.Context) {
+		ticket := c.Query("ticket")
+		if c.GetHeader("Authorization") != "" || ticket == "" {
+			auth(c)
+			return
+		}
+		userID, err := svc.RedeemStreamTicket(ticket)
+		if err != nil {
+			response.Unauthorized(c, err.Error())
+			c.Abort()
+			return
+		}
+		c.Set("user_id", userID)
+		c.Next()
+	}
+}
+
 // SSOSessionMiddleware SSO 会话中间件
 func SSOSessionMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
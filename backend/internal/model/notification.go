@@ -0,0 +1,114 @@
+package model
+
+import "time"
+
+// NotificationChannel 通知渠道常量
+const (
+	NotificationChannelEmail    = "email"    // 邮件
+	NotificationChannelDingTalk = "dingtalk" // 钉钉
+	NotificationChannelWeChat   = "wechat"   // 企业微信
+	NotificationChannelInApp    = "in_app"   // 站内通知
+)
+
+// NotificationStatus 通知发送状态常量
+const (
+	NotificationStatusPending = "pending" // 等待发送（含等待重试）
+	NotificationStatusSending = "sending" // 发送中
+	NotificationStatusSent    = "sent"    // 已发送
+	NotificationStatusFailed  = "failed"  // 重试次数用尽
+)
+
+// NotificationOutbox 待发送通知（发件箱），由定时任务发送并按指数退避重试
+type NotificationOutbox struct {
+	BaseModel
+	Channel       string     `gorm:"type:varchar(20);not null;index" json:"channel"` // 通知渠道
+	Recipient     string     `gorm:"type:text" json:"recipient"`                     // 接收方：邮箱地址（多个以逗号分隔）、企业微信账号，钉钉群机器人为空
+	UserID        *uint      `gorm:"index" json:"user_id"`                           // 接收用户，广播时为空
+	Event         string     `gorm:"type:varchar(30);index" json:"event"`            // 通知事件
+	TicketID      *uint      `gorm:"index" json:"ticket_id"`                         // 关联工单
+	Subject       string     `gorm:"type:varchar(255)" json:"subject"`               // 标题
+	Payload       string     `gorm:"type:longtext" json:"payload"`                   // 消息内容 JSON
+	Status        string     `gorm:"type:varchar(20);default:'pending';index" json:"status"`
+	Attempts      int        `gorm:"default:0" json:"attempts"`    // 已尝试次数
+	NextAttemptAt *time.Time `gorm:"index" json:"next_attempt_at"` // 下次发送时间
+	LastError     string     `gorm:"type:text" json:"last_error"`  // 最近一次错误
+	SentAt        *time.Time `json:"sent_at"`
+}
+
+// TableName 指定表名
+func (NotificationOutbox) TableName() string {
+	return "notification_outbox"
+}
+
+// NotificationEvent 通知事件常量
+const (
+	NotificationEventTicketCreated   = "ticket_created"   // 工单提交
+	NotificationEventTicketApproved  = "ticket_approved"  // 审批结果
+	NotificationEventTicketCompleted = "ticket_completed" // 工单完成
+	NotificationEventPendingApproval = "pending_approval" // 待审批
+	NotificationEventTicketCC        = "ticket_cc"        // 抄送
+	NotificationEventTicketUrge      = "ticket_urge"      // 催办
+	NotificationEventReport          = "report"           // 定时报表
+)
+
+// NotificationPreference 用户通知偏好：各事件接收的渠道及免打扰时段
+type NotificationPreference struct {
+	BaseModel
+	UserID        uint   `gorm:"not null;uniqueIndex" json:"user_id"`
+	EventChannels string `gorm:"type:text" json:"-"`                 // 事件 -> 渠道列表 JSON，未配置的事件接收全部渠道
+	QuietStart    string `gorm:"type:varchar(5)" json:"quiet_start"` // 免打扰开始时间 HH:MM
+	QuietEnd      string `gorm:"type:varchar(5)" json:"quiet_end"`   // 免打扰结束时间 HH:MM，早于开始时间表示跨天
+	Locale        string `gorm:"type:varchar(10)" json:"locale"`     // 通知语言，为空时使用默认语言
+}
+
+// TableName 指定表名
+func (NotificationPreference) TableName() string {
+	return "notification_preferences"
+}
+
+// NotificationTemplate 通知模板：按事件、渠道、语言定制标题和内容（Go 模板语法）
+type NotificationTemplate struct {
+	BaseModel
+	Event       string `gorm:"type:varchar(30);not null;uniqueIndex:idx_notification_template" json:"event"`
+	Channel     string `gorm:"type:varchar(20);not null;uniqueIndex:idx_notification_template" json:"channel"`
+	Locale      string `gorm:"type:varchar(10);not null;uniqueIndex:idx_notification_template" json:"locale"`
+	Subject     string `gorm:"type:text" json:"subject"`  // 标题模板
+	Body        string `gorm:"type:longtext" json:"body"` // 内容模板：邮件为 HTML，钉钉/企业微信为 Markdown
+	Enabled     bool   `json:"enabled"`
+	UpdatedByID uint   `json:"updated_by_id"`
+}
+
+// TableName 指定表名
+func (NotificationTemplate) TableName() string {
+	return "notification_templates"
+}
+
+// UserNotification 站内通知
+type UserNotification struct {
+	BaseModel
+	UserID   uint       `gorm:"not null;index:idx_user_notification_read" json:"user_id"`
+	Event    string     `gorm:"type:varchar(30)" json:"event"`
+	TicketID *uint      `gorm:"index" json:"ticket_id"`
+	Title    string     `gorm:"type:varchar(255)" json:"title"`
+	Content  string     `gorm:"type:text" json:"content"`
+	ReadAt   *time.Time `gorm:"index:idx_user_notification_read" json:"read_at"` // 为空表示未读
+}
+
+// TableName 指定表名
+func (UserNotification) TableName() string {
+	return "user_notifications"
+}
+
+// NotificationStreamTicket 站内通知推送连接票据：短期有效、仅可使用一次，代替在查询参数中传递登录令牌
+type NotificationStreamTicket struct {
+	BaseModel
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	Ticket    string     `gorm:"type:varchar(64);not null;uniqueIndex" json:"ticket"`
+	ExpiresAt time.Time  `gorm:"not null;index" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+}
+
+// TableName 指定表名
+func (NotificationStreamTicket) TableName() string {
+	return "notification_stream_tickets"
+}
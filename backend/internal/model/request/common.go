@@ -2,8 +2,8 @@ package request
 
 // PageRequest 分页请求基础结构
 type PageRequest struct {
-	Page     int `form:"page" binding:"omitempty,min=1"`
-	PageSize int `form:"page_size" binding:"omitempty,min=1,max=1000"`
+	Page     int `form:"page" json:"page" binding:"omitempty,min=1"`
+	PageSize int `form:"page_size" json:"page_size" binding:"omitempty,min=1,max=1000"`
 }
 
 // GetPage 获取页码，默认为 1
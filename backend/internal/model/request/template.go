@@ -1,8 +1,53 @@
 package request
 
+import "encoding/json"
+
 // ListTemplateRequest 模板列表请求
 type ListTemplateRequest struct {
 	PageRequest
 	Keyword string `form:"keyword"`
 	TypeID  uint   `form:"type_id"`
 }
+
+// ListTicketScheduleRequest 周期工单列表请求
+type ListTicketScheduleRequest struct {
+	PageRequest
+	Keyword string `form:"keyword"`
+}
+
+// TicketScheduleRequest 创建/更新周期工单请求
+type TicketScheduleRequest struct {
+	Name        string `json:"name" binding:"required,max=100"`
+	TemplateID  uint   `json:"template_id" binding:"required"`
+	Cron        string `json:"cron" binding:"required,max=100"`
+	CreatorType string `json:"creator_type" binding:"required,oneof=user role"`
+	CreatorID   *uint  `json:"creator_id"`
+	RoleID      *uint  `json:"role_id"`
+	Title       string `json:"title" binding:"max=200"`
+	Priority    int    `json:"priority" binding:"omitempty,min=1,max=4"`
+	AutoSubmit  bool   `json:"auto_submit"`
+	SkipIfOpen  bool   `json:"skip_if_open"`
+	Enabled     *bool  `json:"enabled"`
+}
+
+// TicketTemplateRequest 创建/更新工单模板请求
+type TicketTemplateRequest struct {
+	Name         string          `json:"name" binding:"required,max=100"`
+	Description  string          `json:"description" binding:"max=500"`
+	TypeID       uint            `json:"type_id" binding:"required"`
+	Title        string          `json:"title" binding:"max=200"`
+	Priority     int             `json:"priority" binding:"min=0,max=4"`
+	PresetValues json.RawMessage `json:"preset_values"`
+	Enabled      *bool           `json:"enabled"`
+	SortOrder    int             `json:"sort_order"`
+	Personal     bool            `json:"personal"` // 管理员创建个人模板时设置；普通用户只能创建个人模板
+}
+
+// CreateFromTemplateRequest 基于模板创建工单请求，非空项覆盖模板预设
+type CreateFromTemplateRequest struct {
+	Title       string                 `json:"title" binding:"max=200"`
+	Description string                 `json:"description"`
+	Priority    int                    `json:"priority" binding:"min=0,max=4"`
+	FormData    map[string]interface{} `json:"form_data"`
+	Submit      bool                   `json:"submit"` // 创建后立即提交
+}
@@ -1,5 +1,7 @@
 package request
 
+import "encoding/json"
+
 // CreateTicketRequest 创建工单请求
 type CreateTicketRequest struct {
 	Title       string                 `json:"title" binding:"required"`
@@ -9,6 +11,14 @@ type CreateTicketRequest struct {
 	FormData    map[string]interface{} `json:"form_data"`
 }
 
+// UpdateTicketRequest 更新工单请求（form_data 中仅提交需要修改的字段）
+type UpdateTicketRequest struct {
+	Title       string                 `json:"title"`
+	Description string                 `json:"description"`
+	Priority    int                    `json:"priority"`
+	FormData    map[string]interface{} `json:"form_data"`
+}
+
 // ListTicketRequest 工单列表请求
 type ListTicketRequest struct {
 	PageRequest
@@ -20,8 +30,9 @@ type ListTicketRequest struct {
 
 // ApproveRequest 审批请求
 type ApproveRequest struct {
-	Approved bool   `json:"approved"`
-	Comment  string `json:"comment"`
+	Approved bool                   `json:"approved"`
+	Comment  string                 `json:"comment"`
+	FormData map[string]interface{} `json:"form_data"` // 当前节点可编辑的字段
 }
 
 // TransferRequest 转交请求
@@ -46,3 +57,175 @@ type AddSignRequest struct {
 	TargetUserID uint   `json:"target_user_id" binding:"required"`
 	Comment      string `json:"comment"`
 }
+
+// SearchTicketRequest 工单全文检索请求
+type SearchTicketRequest struct {
+	PageRequest
+	Keyword string `form:"keyword" binding:"required"`
+}
+
+// QueryTicketRequest 工单结构化查询请求（可基于保存视图，filter 与视图条件取交集）
+type QueryTicketRequest struct {
+	PageRequest
+	ViewID    uint            `json:"view_id"`
+	Filter    json.RawMessage `json:"filter"`
+	SortBy    string          `json:"sort_by"`
+	SortOrder string          `json:"sort_order" binding:"omitempty,oneof=asc desc"`
+}
+
+// TicketViewRequest 创建/更新保存视图请求
+type TicketViewRequest struct {
+	Name        string          `json:"name" binding:"required,max=100"`
+	Description string          `json:"description"`
+	Visibility  string          `json:"visibility" binding:"omitempty,oneof=private role"`
+	RoleID      *uint           `json:"role_id"`
+	Filter      json.RawMessage `json:"filter"`
+	SortBy      string          `json:"sort_by"`
+	SortOrder   string          `json:"sort_order" binding:"omitempty,oneof=asc desc"`
+}
+
+// FormFieldStatsRequest 表单字段统计请求
+type FormFieldStatsRequest struct {
+	Field  string `form:"field" binding:"required"`
+	TypeID uint   `form:"type_id"`
+	Status string `form:"status"`
+}
+
+// TicketAnalyticsRequest 工单统计分析请求（时间支持 2006-01-02 或 RFC3339 格式）
+type TicketAnalyticsRequest struct {
+	Start    string `form:"start"`
+	End      string `form:"end"`
+	TypeID   uint   `form:"type_id"`
+	Interval string `form:"interval"` // day/week
+	Limit    int    `form:"limit"`
+}
+
+// BatchTicketRequest 批量操作的工单ID列表
+type BatchTicketRequest struct {
+	TicketIDs []uint `json:"ticket_ids" binding:"required,min=1,max=100,dive,min=1"`
+}
+
+// BatchApproveRequest 批量审批请求
+type BatchApproveRequest struct {
+	BatchTicketRequest
+	Approved bool   `json:"approved"`
+	Comment  string `json:"comment"`
+}
+
+// BatchReassignRequest 批量转交请求
+type BatchReassignRequest struct {
+	BatchTicketRequest
+	TargetUserID uint `json:"target_user_id" binding:"required"`
+}
+
+// BatchPriorityRequest 批量修改优先级请求
+type BatchPriorityRequest struct {
+	BatchTicketRequest
+	Priority int `json:"priority" binding:"required,min=1,max=4"`
+}
+
+// BatchWatchersRequest 批量添加关注人请求
+type BatchWatchersRequest struct {
+	BatchTicketRequest
+	UserIDs []uint `json:"user_ids" binding:"required,min=1,max=50,dive,min=1"`
+}
+
+// ExportTicketRequest 工单导出请求（过滤条件与工单列表一致）
+type ExportTicketRequest struct {
+	Keyword   string `form:"keyword"`
+	Status    string `form:"status"`
+	TypeID    uint   `form:"type_id"`
+	CreatorID uint   `form:"creator_id"`
+	Format    string `form:"format" binding:"omitempty,oneof=csv xlsx"`
+	Async     bool   `form:"async"` // 强制后台导出
+}
+
+// ImportTicketRequest 工单导入请求（multipart，文件字段为 file）
+type ImportTicketRequest struct {
+	TypeID  uint   `form:"type_id" binding:"required"`
+	Mapping string `form:"mapping"` // 列映射 JSON：{"表头": "title|description|priority|creator|assignee|form.<字段标识>"}
+	DryRun  bool   `form:"dry_run"`
+	Submit  bool   `form:"submit"`
+}
+
+// ReportSubscriptionRequest 创建/更新定时报表订阅请求
+type ReportSubscriptionRequest struct {
+	Name       string          `json:"name" binding:"required,max=100"`
+	ReportType string          `json:"report_type" binding:"required,oneof=ticket_summary pending_digest"`
+	Frequency  string          `json:"frequency" binding:"required,oneof=daily weekly"`
+	Weekday    *int            `json:"weekday" binding:"omitempty,min=0,max=6"`    // 默认周一
+	SendHour   *int            `json:"send_hour" binding:"omitempty,min=0,max=23"` // 默认 9 点
+	Filter     json.RawMessage `json:"filter"`
+	Recipients []string        `json:"recipients"`
+	Enabled    *bool           `json:"enabled"`
+}
+
+// WebhookRequest 创建/更新 Webhook 请求
+type WebhookRequest struct {
+	Name    string   `json:"name" binding:"required,max=100"`
+	URL     string   `json:"url" binding:"required,max=500"`
+	Secret  string   `json:"secret" binding:"max=100"` // 为空时创建自动生成、更新保留原密钥
+	Events  []string `json:"events"`                   // 为空表示全部事件
+	TypeIDs []uint   `json:"type_ids"`                 // 为空表示全部工单类型
+	Enabled *bool    `json:"enabled"`
+}
+
+// ListWebhookDeliveryRequest Webhook 投递记录列表请求
+type ListWebhookDeliveryRequest struct {
+	PageRequest
+	Status string `form:"status"`
+}
+
+// ListNotificationOutboxRequest 通知发件箱列表请求
+type ListNotificationOutboxRequest struct {
+	PageRequest
+	Status   string `form:"status"`
+	Channel  string `form:"channel"`
+	Event    string `form:"event"`
+	TicketID uint   `form:"ticket_id"`
+	UserID   uint   `form:"user_id"`
+}
+
+// NotificationPreferenceRequest 更新通知偏好请求
+type NotificationPreferenceRequest struct {
+	EventChannels map[string][]string `json:"event_channels"` // 事件 -> 接收渠道，空列表表示不接收
+	QuietStart    string              `json:"quiet_start"`    // 免打扰开始时间 HH:MM，为空表示不启用
+	QuietEnd      string              `json:"quiet_end"`      // 免打扰结束时间 HH:MM
+	Locale        string              `json:"locale"`         // 通知语言，如 zh-CN、en-US，为空使用默认语言
+}
+
+// NotificationTemplateRequest 创建/更新通知模板请求
+type NotificationTemplateRequest struct {
+	Event   string `json:"event" binding:"required"`
+	Channel string `json:"channel" binding:"required,oneof=email dingtalk wechat in_app"`
+	Locale  string `json:"locale" binding:"max=10"` // 为空时为默认语言
+	Subject string `json:"subject" binding:"required"`
+	Body    string `json:"body" binding:"required"`
+	Enabled *bool  `json:"enabled"`
+}
+
+// NotificationTemplatePreviewRequest 通知模板预览请求
+type NotificationTemplatePreviewRequest struct {
+	Event    string `json:"event" binding:"required"`
+	Channel  string `json:"channel" binding:"required,oneof=email dingtalk wechat in_app"`
+	Subject  string `json:"subject"` // 标题和内容为空时预览内置模板
+	Body     string `json:"body"`
+	TicketID uint   `json:"ticket_id"` // 指定时使用该工单数据渲染，否则使用示例数据
+}
+
+// ListNotificationRequest 站内通知列表请求
+type ListNotificationRequest struct {
+	PageRequest
+	Unread bool `form:"unread"` // 仅显示未读
+}
+
+// ApprovalActionConfirmRequest 通过通知链接确认审批请求
+type ApprovalActionConfirmRequest struct {
+	Token   string `form:"token" json:"token" binding:"required"`
+	Comment string `form:"comment" json:"comment" binding:"max=500"`
+}
+
+// DingTalkCallbackRequest 钉钉事件回调请求（加密内容）
+type DingTalkCallbackRequest struct {
+	Encrypt string `json:"encrypt" binding:"required"`
+}
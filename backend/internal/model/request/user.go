@@ -8,22 +8,28 @@ type ListUserRequest struct {
 
 // CreateUserRequest 创建用户请求
 type CreateUserRequest struct {
-	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required,min=6"`
-	Email    string `json:"email" binding:"required,email"`
-	Phone    string `json:"phone"`
-	Status   int    `json:"status"`
-	RoleIDs  []uint `json:"role_ids"`
+	Username       string `json:"username" binding:"required"`
+	Password       string `json:"password" binding:"required,min=6"`
+	Email          string `json:"email" binding:"required,email"`
+	Phone          string `json:"phone"`
+	Department     string `json:"department"`
+	DingTalkUserID string `json:"dingtalk_user_id"`
+	WeChatUserID   string `json:"wechat_user_id"`
+	Status         int    `json:"status"`
+	RoleIDs        []uint `json:"role_ids"`
 }
 
-// UpdateUserRequest 更新用户请求
+// UpdateUserRequest 更新用户请求（部门、钉钉/企业微信用户ID 未提供时保持不变）
 type UpdateUserRequest struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
-	Email    string `json:"email" binding:"omitempty,email"`
-	Phone    string `json:"phone"`
-	Status   int    `json:"status"`
-	RoleIDs  []uint `json:"role_ids"`
+	Username       string  `json:"username"`
+	Password       string  `json:"password"`
+	Email          string  `json:"email" binding:"omitempty,email"`
+	Phone          string  `json:"phone"`
+	Department     *string `json:"department"`
+	DingTalkUserID *string `json:"dingtalk_user_id"`
+	WeChatUserID   *string `json:"wechat_user_id"`
+	Status         int     `json:"status"`
+	RoleIDs        []uint  `json:"role_ids"`
 }
 
 // AssignRolesRequest 分配角色请求
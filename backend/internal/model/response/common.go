@@ -79,6 +79,15 @@ func BadRequest(c *gin.Context, message string) {
 	Error(c, CodeBadRequest, message)
 }
 
+// BadRequestWithData 400 错误（携带错误明细，如表单字段校验错误）
+func BadRequestWithData(c *gin.Context, message string, data interface{}) {
+	c.JSON(http.StatusOK, Response{
+		Code:    CodeBadRequest,
+		Message: message,
+		Data:    data,
+	})
+}
+
 // Unauthorized 401 错误
 func Unauthorized(c *gin.Context, message string) {
 	if message == "" {
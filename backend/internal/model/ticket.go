@@ -10,6 +10,7 @@ type FormTemplate struct {
 	Name        string      `gorm:"type:varchar(100);not null" json:"name"`
 	Description string      `gorm:"type:varchar(500)" json:"description"`
 	Enabled     bool        `gorm:"default:true" json:"enabled"`
+	Version     int         `gorm:"default:1" json:"version"` // 当前字段版本号，保存字段时递增
 	Fields      []FormField `gorm:"foreignKey:TemplateID" json:"fields,omitempty"`
 }
 
@@ -27,6 +28,8 @@ const (
 	FormFieldTypeUser       = "user"       // 用户选择
 	FormFieldTypeAttachment = "attachment" // 附件上传
 	FormFieldTypeMoney      = "money"      // 金额
+	FormFieldTypeTable      = "table"      // 表格（明细行，列定义配置在 Options）
+	FormFieldTypeFormula    = "formula"    // 公式（由其他字段计算，公式配置在 Options）
 )
 
 // FormField 表单字段
@@ -42,11 +45,25 @@ type FormField struct {
 	Validation   string `gorm:"type:text" json:"validation"`                     // 校验规则 JSON
 	Placeholder  string `gorm:"type:varchar(200)" json:"placeholder"`            // 占位提示
 	ShowCondition string `gorm:"type:text" json:"show_condition"`                // 显示条件 JSON
+	OptionSource string  `gorm:"type:text" json:"option_source"`                 // 动态选项来源 JSON，为空时使用 Options
 	SortOrder    int    `gorm:"default:0" json:"sort_order"`                     // 排序
 }
 
 func (FormField) TableName() string { return "form_fields" }
 
+// FormTemplateVersion 表单模板版本（每次保存字段生成一个版本，保存字段快照，历史工单按创建时的版本渲染）
+type FormTemplateVersion struct {
+	BaseModel
+	TemplateID  uint   `gorm:"not null;uniqueIndex:idx_form_template_version" json:"template_id"`
+	Version     int    `gorm:"not null;uniqueIndex:idx_form_template_version" json:"version"`
+	Fields      string `gorm:"type:longtext" json:"-"`              // 字段快照 JSON
+	FieldCount  int    `gorm:"default:0" json:"field_count"`        // 字段数量
+	CreatedByID *uint  `json:"created_by_id"`                       // 保存人（补录的历史版本为空）
+	CreatedBy   *User  `gorm:"foreignKey:CreatedByID" json:"created_by,omitempty"`
+}
+
+func (FormTemplateVersion) TableName() string { return "form_template_versions" }
+
 // ==================== 工单类型相关 ====================
 
 // TicketType 工单类型
@@ -59,10 +76,37 @@ type TicketType struct {
 	Template    *FormTemplate `gorm:"foreignKey:TemplateID" json:"template,omitempty"`
 	FlowID      *uint         `gorm:"index" json:"flow_id"`                 // 关联审批流程
 	Enabled     bool          `gorm:"default:true" json:"enabled"`
+	NumberFormat string       `gorm:"type:varchar(100)" json:"number_format"`                   // 工单编号格式，如 IT-{yyyy}-{seq:6}，为空时不生成编号
+	NumberReset  string       `gorm:"type:varchar(20);default:'never'" json:"number_reset"`     // 序号重置周期
+	NumberOn     string       `gorm:"type:varchar(20);default:'create'" json:"number_on"`       // 编号生成时机：创建或提交
 }
 
 func (TicketType) TableName() string { return "ticket_types" }
 
+// TicketNumberReset 工单编号序号重置周期常量
+const (
+	TicketNumberResetNever   = "never"   // 不重置
+	TicketNumberResetYearly  = "yearly"  // 每年
+	TicketNumberResetMonthly = "monthly" // 每月
+	TicketNumberResetDaily   = "daily"   // 每天
+)
+
+// TicketNumberOn 工单编号生成时机常量
+const (
+	TicketNumberOnCreate = "create" // 创建时
+	TicketNumberOnSubmit = "submit" // 提交时
+)
+
+// TicketNumberSequence 工单编号序号（按类型和重置周期分别计数）
+type TicketNumberSequence struct {
+	ID     uint   `gorm:"primarykey" json:"id"`
+	TypeID uint   `gorm:"not null;uniqueIndex:idx_ticket_number_seq" json:"type_id"`
+	Period string `gorm:"type:varchar(20);not null;uniqueIndex:idx_ticket_number_seq" json:"period"` // 重置周期标识，如 2026、202610
+	Value  int64  `gorm:"not null;default:0" json:"value"`                                         // 当前已分配的最大序号
+}
+
+func (TicketNumberSequence) TableName() string { return "ticket_number_sequences" }
+
 // ==================== 审批流程相关 ====================
 
 // ApprovalFlow 审批流程（重构：与工单类型解耦，支持版本管理）
@@ -106,6 +150,7 @@ type FlowNode struct {
 	TrueBranchID  *uint  `gorm:"index" json:"true_branch_id"`                      // 条件为真时的分支节点ID
 	FalseBranchID *uint  `gorm:"index" json:"false_branch_id"`                     // 条件为假时的分支节点ID
 	SortOrder     int    `gorm:"default:0" json:"sort_order"`                      // 排序
+	FieldPermissions string `gorm:"type:text" json:"field_permissions"`             // 字段权限 JSON：{"字段标识": "hidden|readonly|editable|required"}
 	// 可视化编辑器位置信息
 	PositionX     int    `gorm:"default:0" json:"position_x"`
 	PositionY     int    `gorm:"default:0" json:"position_y"`
@@ -113,6 +158,14 @@ type FlowNode struct {
 
 func (FlowNode) TableName() string { return "flow_nodes" }
 
+// FieldPermission 节点字段权限常量（未配置的字段默认只读）
+const (
+	FieldPermissionHidden   = "hidden"   // 隐藏
+	FieldPermissionReadOnly = "readonly" // 只读
+	FieldPermissionEditable = "editable" // 可编辑
+	FieldPermissionRequired = "required" // 必填（审批通过时必须有值）
+)
+
 // 兼容旧模型，保留 ApprovalNode 别名
 type ApprovalNode = FlowNode
 
@@ -143,6 +196,8 @@ const (
 // Ticket 工单
 type Ticket struct {
 	BaseModel
+	Number          string           `gorm:"type:varchar(64);index" json:"number"`      // 工单编号（按类型配置生成）
+	NumberKey       *string          `gorm:"->;type:varchar(64) GENERATED ALWAYS AS (NULLIF(number, '')) STORED;uniqueIndex:idx_ticket_number" json:"-"` // 编号唯一约束（未分配编号时为 NULL，不参与约束）
 	Title           string           `gorm:"type:varchar(200);not null" json:"title"`
 	Description     string           `gorm:"type:text" json:"description"`
 	TypeID          uint             `gorm:"not null;index" json:"type_id"`
@@ -158,6 +213,10 @@ type Ticket struct {
 	CurrentNodeID   *uint            `gorm:"index" json:"current_node_id"`        // 当前节点ID
 	CurrentNode     *FlowNode        `gorm:"foreignKey:CurrentNodeID" json:"current_node,omitempty"`
 	CompletedAt     *time.Time       `json:"completed_at"`
+	ScheduleID      *uint            `gorm:"index" json:"schedule_id"`            // 由周期工单创建时的来源
+	FormTemplateID  *uint            `gorm:"index" json:"form_template_id"`       // 创建时的表单模板
+	FormVersion     int              `gorm:"default:0" json:"form_version"`       // 创建时的表单模板版本
+	FormFields      []FormField      `gorm:"-" json:"form_fields,omitempty"`      // 工单绑定版本的表单字段（详情返回）
 	Data            []TicketData     `gorm:"foreignKey:TicketID" json:"data,omitempty"`
 	Comments        []TicketComment  `gorm:"foreignKey:TicketID" json:"comments,omitempty"`
 	Attachments     []TicketAttachment `gorm:"foreignKey:TicketID" json:"attachments,omitempty"`
@@ -168,17 +227,51 @@ func (Ticket) TableName() string { return "tickets" }
 
 // ==================== 工单表单数据 ====================
 
+// TicketDataValueType 表单数据类型化投影的类型常量
+const (
+	TicketDataValueNone   = "none"   // 无法投影（空值、多值等）
+	TicketDataValueNumber = "number" // 数值（数字、金额）
+	TicketDataValueTime   = "time"   // 时间（日期、日期时间）
+	TicketDataValueString = "string" // 字符串（文本、单选）
+	TicketDataValueUser   = "user"   // 用户ID（单个用户）
+)
+
 // TicketData 工单表单数据
+//
+// Value 保存原始值；NumValue/TimeValue/StrValue/UserValue 为按字段类型解析后的投影，
+// 与 FieldID 组成联合索引，用于过滤、排序、统计和流程条件判断。
+// ValueType 为空表示尚未投影（历史数据，启动时回填）。
 type TicketData struct {
 	BaseModel
-	TicketID uint   `gorm:"not null;index" json:"ticket_id"`
-	FieldID  uint   `gorm:"not null;index" json:"field_id"`          // 关联表单字段
-	Field    *FormField `gorm:"foreignKey:FieldID" json:"field,omitempty"`
-	Value    string `gorm:"type:text" json:"value"`                  // 字段值
+	TicketID  uint       `gorm:"not null;index" json:"ticket_id"`
+	FieldID   uint       `gorm:"not null;index;index:idx_ticket_data_field_num,priority:1;index:idx_ticket_data_field_time,priority:1;index:idx_ticket_data_field_str,priority:1;index:idx_ticket_data_field_user,priority:1" json:"field_id"` // 关联表单字段
+	Field     *FormField `gorm:"foreignKey:FieldID" json:"field,omitempty"`
+	Value     string     `gorm:"type:text" json:"value"`                                                  // 字段值
+	ValueType string     `gorm:"type:varchar(20);index" json:"value_type"`                                // 投影类型
+	NumValue  *float64   `gorm:"type:decimal(20,4);index:idx_ticket_data_field_num,priority:2" json:"num_value,omitempty"`
+	TimeValue *time.Time `gorm:"index:idx_ticket_data_field_time,priority:2" json:"time_value,omitempty"`
+	StrValue  string     `gorm:"type:varchar(255);index:idx_ticket_data_field_str,priority:2" json:"str_value,omitempty"`
+	UserValue *uint      `gorm:"index:idx_ticket_data_field_user,priority:2" json:"user_value,omitempty"`
 }
 
 func (TicketData) TableName() string { return "ticket_data" }
 
+// TicketDataChange 表单数据修改记录（审批人在节点上修改字段时记录）
+type TicketDataChange struct {
+	BaseModel
+	TicketID    uint   `gorm:"not null;index" json:"ticket_id"`
+	NodeID      *uint  `gorm:"index" json:"node_id"`                          // 修改时所在节点
+	FieldID     uint   `gorm:"not null" json:"field_id"`
+	FieldName   string `gorm:"type:varchar(50)" json:"field_name"`
+	FieldLabel  string `gorm:"type:varchar(100)" json:"field_label"`
+	OldValue    string `gorm:"type:text" json:"old_value"`
+	NewValue    string `gorm:"type:text" json:"new_value"`
+	ChangedByID uint   `gorm:"not null;index" json:"changed_by_id"`
+	ChangedBy   *User  `gorm:"foreignKey:ChangedByID" json:"changed_by,omitempty"`
+}
+
+func (TicketDataChange) TableName() string { return "ticket_data_changes" }
+
 // ==================== 审批记录相关 ====================
 
 // ApprovalAction 审批操作类型常量
@@ -209,6 +302,35 @@ type ApprovalRecord struct {
 
 func (ApprovalRecord) TableName() string { return "approval_records" }
 
+// ==================== 工单事件 ====================
+
+// TicketEventType 工单生命周期事件类型常量
+const (
+	TicketEventCreated     = "created"      // 创建
+	TicketEventSubmitted   = "submitted"    // 提交
+	TicketEventNodeEntered = "node_entered" // 进入审批节点
+	TicketEventApproved    = "approved"     // 审批通过，进入处理
+	TicketEventRejected    = "rejected"     // 审批拒绝
+	TicketEventReturned    = "returned"     // 退回
+	TicketEventWithdrawn   = "withdrawn"    // 撤回
+	TicketEventReassigned  = "reassigned"   // 转交处理人
+	TicketEventCompleted   = "completed"    // 完成
+	TicketEventCancelled   = "cancelled"    // 取消
+)
+
+// TicketEvent 工单生命周期事件（用于统计节点停留时长、处理效率等）
+type TicketEvent struct {
+	BaseModel
+	TicketID   uint   `gorm:"not null;index" json:"ticket_id"`
+	EventType  string `gorm:"type:varchar(30);not null;index" json:"event_type"`
+	NodeID     *uint  `gorm:"index" json:"node_id"`                        // 相关节点
+	ActorID    *uint  `gorm:"index" json:"actor_id"`                       // 操作人
+	FromStatus string `gorm:"type:varchar(20)" json:"from_status"`
+	ToStatus   string `gorm:"type:varchar(20)" json:"to_status"`
+}
+
+func (TicketEvent) TableName() string { return "ticket_events" }
+
 // ==================== 工单评论 ====================
 
 // CommentType 评论类型常量
@@ -250,15 +372,238 @@ func (TicketAttachment) TableName() string { return "ticket_attachments" }
 // ==================== 工单模板（快捷模板，复用表单模板） ====================
 
 // TicketTemplate 工单快捷模板（用于快速创建工单的预设值）
+// 预设值中的字符串支持占位符，如 {{today}}、{{user.username}}、{{user.department}}
 type TicketTemplate struct {
 	BaseModel
 	Name         string `gorm:"type:varchar(100);not null" json:"name"`
 	Description  string `gorm:"type:varchar(500)" json:"description"`
 	TypeID       uint   `gorm:"not null;index" json:"type_id"`
 	Type         *TicketType `gorm:"foreignKey:TypeID" json:"type,omitempty"`
+	OwnerID      *uint  `gorm:"index" json:"owner_id"`                   // 个人模板所有者，为空表示全局模板
+	Owner        *User  `gorm:"foreignKey:OwnerID" json:"owner,omitempty"`
+	Title        string `gorm:"type:varchar(200)" json:"title"`          // 预设的工单标题
+	Priority     int    `gorm:"default:0" json:"priority"`               // 预设的优先级，0 表示不预设
 	PresetValues string `gorm:"type:text" json:"preset_values"`          // 预设的表单值 JSON
-	Enabled      bool   `gorm:"default:true" json:"enabled"`
+	Enabled      bool   `json:"enabled"`
 	SortOrder    int    `gorm:"default:0" json:"sort_order"`
 }
 
 func (TicketTemplate) TableName() string { return "ticket_templates" }
+
+// ==================== 工单关注人 ====================
+
+// TicketWatcher 工单关注人（可查看工单并接收动态）
+type TicketWatcher struct {
+	BaseModel
+	TicketID  uint  `gorm:"not null;uniqueIndex:idx_ticket_watcher" json:"ticket_id"`
+	UserID    uint  `gorm:"not null;uniqueIndex:idx_ticket_watcher;index" json:"user_id"`
+	User      *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	AddedByID uint  `gorm:"not null" json:"added_by_id"` // 添加人
+}
+
+func (TicketWatcher) TableName() string { return "ticket_watchers" }
+
+// ==================== 工单保存视图 ====================
+
+// TicketViewVisibility 视图可见范围常量
+const (
+	TicketViewVisibilityPrivate = "private" // 仅自己可见
+	TicketViewVisibilityRole    = "role"    // 共享给指定角色
+)
+
+// TicketView 工单保存视图（命名的过滤条件 + 排序）
+type TicketView struct {
+	BaseModel
+	Name        string `gorm:"type:varchar(100);not null" json:"name"`
+	Description string `gorm:"type:varchar(500)" json:"description"`
+	OwnerID     uint   `gorm:"not null;index" json:"owner_id"`
+	Owner       *User  `gorm:"foreignKey:OwnerID" json:"owner,omitempty"`
+	Visibility  string `gorm:"type:varchar(20);default:'private'" json:"visibility"` // 可见范围
+	RoleID      *uint  `gorm:"index" json:"role_id"`                                 // 共享角色ID
+	Filter      string `gorm:"type:text" json:"filter"`                              // 过滤条件 JSON
+	SortBy      string `gorm:"type:varchar(50)" json:"sort_by"`
+	SortOrder   string `gorm:"type:varchar(10)" json:"sort_order"`
+}
+
+func (TicketView) TableName() string { return "ticket_views" }
+
+// ==================== 工单导出任务 ====================
+
+// TicketExportStatus 导出任务状态常量
+const (
+	TicketExportStatusPending   = "pending"   // 等待执行
+	TicketExportStatusRunning   = "running"   // 执行中
+	TicketExportStatusCompleted = "completed" // 已完成
+	TicketExportStatusFailed    = "failed"    // 失败
+)
+
+// TicketExportJob 工单导出任务（大批量导出在后台执行，结果保存到存储服务）
+type TicketExportJob struct {
+	BaseModel
+	CreatorID   uint       `gorm:"not null;index" json:"creator_id"`
+	Format      string     `gorm:"type:varchar(10);not null" json:"format"`                    // csv / xlsx
+	Filter      string     `gorm:"type:text" json:"filter"`                                    // 过滤条件 JSON
+	Status      string     `gorm:"type:varchar(20);default:'pending';index" json:"status"`     // 任务状态
+	RowCount    int        `gorm:"default:0" json:"row_count"`                                 // 导出行数
+	FileName    string     `gorm:"type:varchar(255)" json:"file_name"`                         // 下载文件名
+	StoragePath string     `gorm:"type:varchar(500)" json:"-"`                                 // 存储路径
+	Error       string     `gorm:"type:text" json:"error"`                                     // 失败原因
+	HeartbeatAt *time.Time `json:"-"`                                                          // 执行中任务的心跳时间，超时未更新视为执行中断
+	FinishedAt  *time.Time `json:"finished_at"`
+}
+
+func (TicketExportJob) TableName() string { return "ticket_export_jobs" }
+
+// ==================== 定时报表订阅 ====================
+
+// ReportType 报表类型常量
+const (
+	ReportTypeTicketSummary = "ticket_summary" // 工单汇总（按过滤条件统计周期内新建/完成/未完结工单）
+	ReportTypePendingDigest = "pending_digest" // 待审批摘要（订阅后不再逐条发送待审批邮件）
+)
+
+// ReportFrequency 报表发送频率常量
+const (
+	ReportFrequencyDaily  = "daily"  // 每天
+	ReportFrequencyWeekly = "weekly" // 每周
+)
+
+// ReportSubscription 定时报表订阅
+type ReportSubscription struct {
+	BaseModel
+	Name       string     `gorm:"type:varchar(100);not null" json:"name"`
+	OwnerID    uint       `gorm:"not null;index" json:"owner_id"`
+	Owner      *User      `gorm:"foreignKey:OwnerID" json:"owner,omitempty"`
+	ReportType string     `gorm:"type:varchar(30);not null" json:"report_type"`         // 报表类型
+	Frequency  string     `gorm:"type:varchar(20);not null" json:"frequency"`           // daily / weekly
+	Weekday    int        `json:"weekday"`                                               // 每周发送日（0=周日）
+	SendHour   int        `json:"send_hour"`                                             // 发送时刻（0-23）
+	Filter     string     `gorm:"type:text" json:"filter"`                               // 工单过滤条件 JSON（同条件查询）
+	Recipients string     `gorm:"type:text" json:"recipients"`                           // 收件人邮箱，逗号分隔；为空时发送给订阅人
+	Enabled    bool       `json:"enabled"`
+	NextRunAt  *time.Time `gorm:"index" json:"next_run_at"`
+	LastRunAt  *time.Time `json:"last_run_at"`
+	LastError  string     `gorm:"type:text" json:"last_error"`
+}
+
+func (ReportSubscription) TableName() string { return "report_subscriptions" }
+
+// ==================== 周期工单 ====================
+
+// ScheduleCreatorType 周期工单发起人类型常量
+const (
+	ScheduleCreatorUser = "user" // 指定用户
+	ScheduleCreatorRole = "role" // 指定角色（为角色下每个用户各创建一个工单）
+)
+
+// TicketScheduleRunStatus 周期工单执行结果常量
+const (
+	TicketScheduleRunSuccess = "success" // 全部创建成功
+	TicketScheduleRunPartial = "partial" // 部分创建成功
+	TicketScheduleRunSkipped = "skipped" // 上一次的工单未完结，已跳过
+	TicketScheduleRunFailed  = "failed"  // 创建失败
+)
+
+// TicketSchedule 周期工单：按 cron 表达式由工单模板定期创建工单
+type TicketSchedule struct {
+	BaseModel
+	Name        string          `gorm:"type:varchar(100);not null" json:"name"`
+	TemplateID  uint            `gorm:"not null;index" json:"template_id"`
+	Template    *TicketTemplate `gorm:"foreignKey:TemplateID" json:"template,omitempty"`
+	Cron        string          `gorm:"type:varchar(100);not null" json:"cron"`           // cron 表达式（分 时 日 月 周）
+	CreatorType string          `gorm:"type:varchar(20);not null" json:"creator_type"`    // user / role
+	CreatorID   *uint           `gorm:"index" json:"creator_id"`                          // 发起人（CreatorType=user）
+	RoleID      *uint           `gorm:"index" json:"role_id"`                             // 发起角色（CreatorType=role）
+	Title       string          `gorm:"type:varchar(200)" json:"title"`                   // 工单标题，为空时使用模板名称
+	Priority    int             `gorm:"default:2" json:"priority"`
+	AutoSubmit  bool            `gorm:"default:false" json:"auto_submit"`                 // 创建后自动提交
+	SkipIfOpen  bool            `gorm:"default:false" json:"skip_if_open"`                // 上一次创建的工单未完结时跳过
+	Enabled     bool            `json:"enabled"`
+	NextRunAt   *time.Time      `gorm:"index" json:"next_run_at"`
+	LastRunAt   *time.Time      `json:"last_run_at"`
+	CreatedByID uint            `gorm:"not null" json:"created_by_id"`
+}
+
+func (TicketSchedule) TableName() string { return "ticket_schedules" }
+
+// TicketScheduleRun 周期工单执行记录
+type TicketScheduleRun struct {
+	BaseModel
+	ScheduleID  uint      `gorm:"not null;index" json:"schedule_id"`
+	ScheduledAt time.Time `json:"scheduled_at"`                             // 计划执行时间
+	Manual      bool      `gorm:"default:false" json:"manual"`              // 是否手动触发
+	Status      string    `gorm:"type:varchar(20);not null" json:"status"`  // 执行结果
+	TicketIDs   string    `gorm:"type:varchar(1000)" json:"ticket_ids"`     // 创建的工单ID，逗号分隔
+	Message     string    `gorm:"type:text" json:"message"`                 // 跳过原因或错误信息
+}
+
+func (TicketScheduleRun) TableName() string { return "ticket_schedule_runs" }
+
+// ==================== Webhook ====================
+
+// WebhookEventCommented 评论事件（其余 Webhook 事件与工单生命周期事件类型一致）
+const WebhookEventCommented = "commented"
+
+// WebhookEventPing 测试事件（手动触发，不受订阅事件过滤）
+const WebhookEventPing = "ping"
+
+// WebhookDeliveryStatus Webhook 投递状态常量
+const (
+	WebhookDeliveryPending = "pending" // 等待投递（含等待重试）
+	WebhookDeliverySending = "sending" // 投递中
+	WebhookDeliverySuccess = "success" // 投递成功
+	WebhookDeliveryFailed  = "failed"  // 重试次数用尽
+)
+
+// Webhook 工单事件订阅，事件发生时向 URL 推送 JSON 并使用 HMAC-SHA256 签名
+type Webhook struct {
+	BaseModel
+	Name        string `gorm:"type:varchar(100);not null" json:"name"`
+	URL         string `gorm:"type:varchar(500);not null" json:"url"`
+	Secret      string `gorm:"type:varchar(100)" json:"-"`            // 签名密钥，仅在创建和重置时返回
+	Events      string `gorm:"type:varchar(500)" json:"events"`       // 订阅的事件，逗号分隔，为空表示全部
+	TypeIDs     string `gorm:"type:varchar(500)" json:"type_ids"`     // 工单类型ID，逗号分隔，为空表示全部
+	Enabled     bool   `json:"enabled"`
+	CreatedByID uint   `gorm:"not null" json:"created_by_id"`
+}
+
+func (Webhook) TableName() string { return "webhooks" }
+
+// WebhookDelivery Webhook 投递记录（持久化队列，由定时任务投递并按指数退避重试）
+type WebhookDelivery struct {
+	BaseModel
+	WebhookID     uint       `gorm:"not null;index" json:"webhook_id"`
+	Webhook       *Webhook   `gorm:"foreignKey:WebhookID" json:"webhook,omitempty"`
+	EventID       string     `gorm:"type:varchar(64);index" json:"event_id"`                             // 事件ID，重新投递时不变
+	Event         string     `gorm:"type:varchar(30);not null" json:"event"`
+	TicketID      uint       `gorm:"index" json:"ticket_id"`
+	Payload       string     `gorm:"type:longtext" json:"payload"`
+	Status        string     `gorm:"type:varchar(20);default:'pending';index" json:"status"`
+	Attempts      int        `gorm:"default:0" json:"attempts"`                                          // 已尝试次数
+	NextAttemptAt *time.Time `gorm:"index" json:"next_attempt_at"`                                       // 下次投递时间
+	ResponseCode  int        `gorm:"default:0" json:"response_code"`                                     // 最近一次响应状态码
+	ResponseBody  string     `gorm:"type:text" json:"response_body"`                                     // 最近一次响应内容（截断）
+	Error         string     `gorm:"type:text" json:"error"`                                             // 最近一次错误
+	DurationMs    int64      `gorm:"default:0" json:"duration_ms"`                                       // 最近一次耗时
+	DeliveredAt   *time.Time `json:"delivered_at"`                                                       // 投递成功时间
+	RedeliveryOf  *uint      `gorm:"index" json:"redelivery_of"`                                         // 手动重新投递的原记录
+}
+
+func (WebhookDelivery) TableName() string { return "webhook_deliveries" }
+
+// ==================== 审批操作链接 ====================
+
+// ApprovalActionToken 通知中审批链接的令牌（一次性，绑定工单、节点、审批人和操作）
+type ApprovalActionToken struct {
+	BaseModel
+	Nonce       string     `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	TicketID    uint       `gorm:"not null;index:idx_approval_action_token" json:"ticket_id"`
+	NodeID      uint       `gorm:"not null;index:idx_approval_action_token" json:"node_id"`
+	ApproverID  uint       `gorm:"not null;index:idx_approval_action_token" json:"approver_id"`
+	NodeEventID uint       `gorm:"not null;default:0;index:idx_approval_action_token" json:"-"` // 签发时工单最近一次进入节点的事件，工单重新进入节点后令牌失效
+	Action      string     `gorm:"type:varchar(20);not null" json:"action"`                      // approve / reject
+	ExpiresAt   time.Time  `json:"expires_at"`
+	UsedAt      *time.Time `json:"used_at"` // 使用时间，同一审批人在该节点的其他令牌同时失效
+}
+
+func (ApprovalActionToken) TableName() string { return "approval_action_tokens" }
@@ -3,13 +3,18 @@ package model
 // User 用户模型
 type User struct {
 	BaseModel
-	Username string `gorm:"type:varchar(50);not null;index:idx_user_username" json:"username"`
-	Password string `gorm:"type:varchar(255);not null" json:"-"`
-	Email    string `gorm:"type:varchar(100);index:idx_user_email" json:"email"`
-	Avatar   string `gorm:"type:varchar(255)" json:"avatar"`
-	Phone    string `gorm:"type:varchar(20)" json:"phone"`
-	Status   int    `gorm:"type:tinyint;default:1;index:idx_user_status;comment:状态 1-启用 0-禁用" json:"status"`
-	Roles    []Role `gorm:"many2many:user_roles;" json:"roles,omitempty"`
+	Username       string `gorm:"type:varchar(50);not null;index:idx_user_username" json:"username"`
+	Password       string `gorm:"type:varchar(255);not null" json:"-"`
+	Email          string `gorm:"type:varchar(100);index:idx_user_email" json:"email"`
+	Avatar         string `gorm:"type:varchar(255)" json:"avatar"`
+	Phone          string `gorm:"type:varchar(20)" json:"phone"`
+	Department     string `gorm:"type:varchar(100)" json:"department"`      // 所属部门
+	DingTalkUserID string `gorm:"type:varchar(64)" json:"dingtalk_user_id"` // 钉钉用户ID（用于通知）
+	WeChatUserID   string `gorm:"type:varchar(64)" json:"wechat_user_id"`   // 企业微信用户ID（用于通知）
+	Status         int    `gorm:"type:tinyint;default:1;index:idx_user_status;comment:状态 1-启用 0-禁用" json:"status"`
+	Roles          []Role `gorm:"many2many:user_roles;" json:"roles,omitempty"`
+	// 钉钉用户ID唯一约束（未填写或用户已删除时为 NULL，不参与约束），钉钉回调按此ID识别操作人
+	DingTalkUserIDKey *string `gorm:"->;type:varchar(64) GENERATED ALWAYS AS (IF(deleted_at IS NULL, NULLIF(ding_talk_user_id, ''), NULL)) STORED;uniqueIndex:idx_user_dingtalk_user_id" json:"-"`
 }
 
 // TableName 指定表名
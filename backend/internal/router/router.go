@@ -39,6 +39,12 @@ func SetupRouter() *gin.Engine {
 			})
 			// OIDC 回调路由（始终注册，在 handler 中检查配置）
 			public.GET("/auth/oidc/callback", handler.NewOIDCCallbackHandler().Callback)
+			// 通知中的免登录审批链接（令牌签名校验，一次性）
+			approvalActionHandler := handler.NewApprovalActionHandler()
+			public.GET("/approval-actions", approvalActionHandler.Show)
+			public.POST("/approval-actions", approvalActionHandler.Confirm)
+			// 钉钉企业内部应用事件回调（签名校验）
+			public.POST("/dingtalk/callback", handler.NewDingTalkHandler().Callback)
 		}
 
 		// 需要认证的路由
@@ -158,6 +164,18 @@ func SetupRouter() *gin.Engine {
 				formTemplate.DELETE("/:id", formTemplateHandler.Delete)
 				formTemplate.GET("/:id/fields", formTemplateHandler.GetFields)
 				formTemplate.PUT("/:id/fields", formTemplateHandler.SaveFields)
+				formTemplate.GET("/:id/schema", formTemplateHandler.ExportSchema)
+				formTemplate.POST("/schema", formTemplateHandler.ImportSchema)
+				formTemplate.PUT("/:id/schema", formTemplateHandler.UpdateSchema)
+				formTemplate.GET("/:id/versions", formTemplateHandler.ListVersions)
+				formTemplate.GET("/:id/versions/compare", formTemplateHandler.CompareVersions)
+				formTemplate.GET("/:id/versions/:version", formTemplateHandler.GetVersion)
+			}
+			formField := auth.Group("/form-fields")
+			formField.Use(middleware.CasbinRBACMiddleware())
+			{
+				formField.GET("/:id/options", formTemplateHandler.GetFieldOptions)
+				formField.POST("/:id/options/refresh", formTemplateHandler.RefreshFieldOptions)
 			}
 
 			// 工单类型管理
@@ -179,10 +197,20 @@ func SetupRouter() *gin.Engine {
 			template.Use(middleware.CasbinRBACMiddleware())
 			{
 				template.GET("/enabled", templateHandler.ListEnabled)
+				template.GET("", templateHandler.List)
+				template.GET("/:id", templateHandler.GetByID)
+				template.GET("/:id/presets", templateHandler.GetPresets)
+				template.POST("", templateHandler.Create)
+				template.PUT("/:id", templateHandler.Update)
+				template.DELETE("/:id", templateHandler.Delete)
+				template.POST("/:id/tickets", templateHandler.CreateTicket)
 			}
 
 			// 工单管理
 			ticketHandler := handler.NewTicketHandler()
+			ticketSearchHandler := handler.NewTicketSearchHandler()
+			ticketViewHandler := handler.NewTicketViewHandler()
+			ticketExportHandler := handler.NewTicketExportHandler()
 			ticket := auth.Group("/tickets")
 			ticket.Use(middleware.CasbinRBACMiddleware())
 			{
@@ -192,9 +220,31 @@ func SetupRouter() *gin.Engine {
 				ticket.GET("/processed", ticketHandler.GetProcessedTickets)
 				ticket.GET("/cc", ticketHandler.GetCCTickets)
 				ticket.GET("/stats", handler.NewTicketStatsHandler().GetStats)
+				ticket.GET("/stats/field", handler.NewTicketStatsHandler().GetFieldStats)
+				ticket.GET("/analytics/trends", handler.NewTicketStatsHandler().GetTrends)
+				ticket.GET("/analytics/nodes", handler.NewTicketStatsHandler().GetNodeDurations)
+				ticket.GET("/analytics/approvers", handler.NewTicketStatsHandler().GetApproverDurations)
+				ticket.GET("/analytics/assignees", handler.NewTicketStatsHandler().GetAssigneeThroughput)
+				ticket.GET("/analytics/rejections", handler.NewTicketStatsHandler().GetNodeRejections)
+				ticket.GET("/analytics/slowest", handler.NewTicketStatsHandler().GetSlowestOpen)
+				ticket.GET("/search", ticketSearchHandler.Search)
+				ticket.POST("/search/rebuild", ticketSearchHandler.Rebuild)
+				ticket.POST("/query", ticketViewHandler.Query)
+				ticket.GET("/export", ticketExportHandler.Export)
+				ticket.GET("/export/jobs", ticketExportHandler.ListJobs)
+				ticket.GET("/export/jobs/:id", ticketExportHandler.GetJob)
+				ticket.POST("/import", handler.NewTicketImportHandler().Import)
+				ticket.POST("/batch/approve", ticketHandler.BatchApprove)
+				ticket.POST("/batch/cancel", ticketHandler.BatchCancel)
+				ticket.POST("/batch/reassign", ticketHandler.BatchReassign)
+				ticket.POST("/batch/priority", ticketHandler.BatchSetPriority)
+				ticket.POST("/batch/watchers", ticketHandler.BatchAddWatchers)
 				ticket.GET("/:id", ticketHandler.GetByID)
 				ticket.GET("/:id/records", ticketHandler.GetApprovalRecords)
 				ticket.GET("/:id/can-approve", ticketHandler.CanApprove)
+				ticket.GET("/:id/field-permissions", ticketHandler.GetFieldPermissions)
+				ticket.GET("/:id/data-changes", ticketHandler.GetDataChanges)
+				ticket.GET("/:id/watchers", ticketHandler.GetWatchers)
 				ticket.POST("", ticketHandler.Create)
 				ticket.PUT("/:id", ticketHandler.Update)
 				ticket.DELETE("/:id", ticketHandler.Delete)
@@ -204,6 +254,109 @@ func SetupRouter() *gin.Engine {
 				ticket.POST("/:id/cancel", ticketHandler.Cancel)
 			}
 
+			// 工单保存视图
+			ticketView := auth.Group("/ticket-views")
+			ticketView.Use(middleware.CasbinRBACMiddleware())
+			{
+				ticketView.GET("", ticketViewHandler.List)
+				ticketView.GET("/:id", ticketViewHandler.GetByID)
+				ticketView.POST("", ticketViewHandler.Create)
+				ticketView.PUT("/:id", ticketViewHandler.Update)
+				ticketView.DELETE("/:id", ticketViewHandler.Delete)
+			}
+
+			// 周期工单
+			ticketSchedule := auth.Group("/ticket-schedules")
+			ticketSchedule.Use(middleware.CasbinRBACMiddleware())
+			{
+				ticketScheduleHandler := handler.NewTicketScheduleHandler()
+				ticketSchedule.GET("", ticketScheduleHandler.List)
+				ticketSchedule.GET("/:id", ticketScheduleHandler.GetByID)
+				ticketSchedule.POST("", ticketScheduleHandler.Create)
+				ticketSchedule.PUT("/:id", ticketScheduleHandler.Update)
+				ticketSchedule.DELETE("/:id", ticketScheduleHandler.Delete)
+				ticketSchedule.GET("/:id/runs", ticketScheduleHandler.ListRuns)
+				ticketSchedule.POST("/:id/run", ticketScheduleHandler.Run)
+			}
+
+			// 定时报表订阅
+			reportSub := auth.Group("/report-subscriptions")
+			reportSub.Use(middleware.CasbinRBACMiddleware())
+			{
+				reportSubHandler := handler.NewReportSubscriptionHandler()
+				reportSub.GET("", reportSubHandler.List)
+				reportSub.GET("/:id", reportSubHandler.GetByID)
+				reportSub.POST("", reportSubHandler.Create)
+				reportSub.PUT("/:id", reportSubHandler.Update)
+				reportSub.DELETE("/:id", reportSubHandler.Delete)
+				reportSub.POST("/:id/send", reportSubHandler.Send)
+			}
+
+			// Webhook 订阅
+			webhook := auth.Group("/webhooks")
+			webhook.Use(middleware.CasbinRBACMiddleware())
+			{
+				webhookHandler := handler.NewWebhookHandler()
+				webhook.GET("", webhookHandler.List)
+				webhook.GET("/:id", webhookHandler.GetByID)
+				webhook.POST("", webhookHandler.Create)
+				webhook.PUT("/:id", webhookHandler.Update)
+				webhook.DELETE("/:id", webhookHandler.Delete)
+				webhook.POST("/:id/secret/rotate", webhookHandler.RotateSecret)
+				webhook.POST("/:id/ping", webhookHandler.Ping)
+				webhook.GET("/:id/deliveries", webhookHandler.ListDeliveries)
+				webhook.GET("/deliveries/:id", webhookHandler.GetDelivery)
+				webhook.POST("/deliveries/:id/redeliver", webhookHandler.Redeliver)
+			}
+
+			// 通知发件箱
+			outbox := auth.Group("/notification-outbox")
+			outbox.Use(middleware.CasbinRBACMiddleware())
+			{
+				outboxHandler := handler.NewNotificationOutboxHandler()
+				outbox.GET("", outboxHandler.List)
+				outbox.GET("/:id", outboxHandler.GetByID)
+				outbox.POST("/:id/resend", outboxHandler.Resend)
+				outbox.POST("/resend-failed", outboxHandler.ResendFailed)
+			}
+
+			// 个人通知偏好
+			notifyPref := auth.Group("/notification-preferences")
+			notifyPref.Use(middleware.CasbinRBACMiddleware())
+			{
+				notifyPrefHandler := handler.NewNotificationPreferenceHandler()
+				notifyPref.GET("/me", notifyPrefHandler.Get)
+				notifyPref.PUT("/me", notifyPrefHandler.Update)
+			}
+
+			// 通知模板
+			notifyTpl := auth.Group("/notification-templates")
+			notifyTpl.Use(middleware.CasbinRBACMiddleware())
+			{
+				notifyTplHandler := handler.NewNotificationTemplateHandler()
+				notifyTpl.GET("", notifyTplHandler.List)
+				notifyTpl.GET("/builtins", notifyTplHandler.Builtins)
+				notifyTpl.POST("/preview", notifyTplHandler.Preview)
+				notifyTpl.GET("/:id", notifyTplHandler.GetByID)
+				notifyTpl.POST("", notifyTplHandler.Create)
+				notifyTpl.PUT("/:id", notifyTplHandler.Update)
+				notifyTpl.DELETE("/:id", notifyTplHandler.Delete)
+			}
+
+			// 站内通知
+			notificationHandler := handler.NewNotificationHandler()
+			notifications := auth.Group("/notifications")
+			notifications.Use(middleware.CasbinRBACMiddleware())
+			{
+				notifications.GET("", notificationHandler.List)
+				notifications.GET("/unread-count", notificationHandler.UnreadCount)
+				notifications.POST("/:id/read", notificationHandler.MarkRead)
+				notifications.POST("/read-all", notificationHandler.MarkAllRead)
+				notifications.POST("/stream/ticket", notificationHandler.StreamTicket)
+			}
+			// 实时推送（EventSource 无法设置请求头，支持 ticket 查询参数传递推送连接票据）
+			api.GET("/notifications/stream", middleware.StreamAuthMiddleware(), middleware.CasbinRBACMiddleware(), notificationHandler.Stream)
+
 			// 工单评论
 			commentHandler := handler.NewCommentHandler()
 			comments := auth.Group("/comments")
@@ -0,0 +1,258 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/config"
+	"backend/internal/global"
+	"backend/internal/model"
+	"backend/pkg/logger"
+	"backend/pkg/notify"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// approvalActionTTL 审批链接有效期
+const approvalActionTTL = 72 * time.Hour
+
+// ApprovalActionInfo 审批链接对应的操作信息（用于确认页面）
+type ApprovalActionInfo struct {
+	TicketID     uint      `json:"ticket_id"`
+	TicketNumber string    `json:"ticket_number"`
+	TicketTitle  string    `json:"ticket_title"`
+	NodeName     string    `json:"node_name"`
+	Approver     string    `json:"approver"`
+	Action       string    `json:"action"`
+	ActionText   string    `json:"action_text"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// signApprovalAction 使用 JWT 密钥对令牌内容签名
+func signApprovalAction(payload string) string {
+	mac := hmac.New(sha256.New, []byte(config.Get().JWT.Secret))
+	mac.Write([]byte("approval-action:"))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// approvalActionPayload 令牌签名内容：工单.节点.审批人.操作.过期时间.随机串
+func approvalActionPayload(t *model.ApprovalActionToken) string {
+	return fmt.Sprintf("%d.%d.%d.%s.%d.%s", t.TicketID, t.NodeID, t.ApproverID, t.Action, t.ExpiresAt.Unix(), t.Nonce)
+}
+
+// latestNodeEventID 工单最近一次进入审批节点的事件ID，用于区分同一节点的多次审批（如退回后重新提交）
+func latestNodeEventID(ticketID uint) uint {
+	var id uint
+	global.GetDB().Model(&model.TicketEvent{}).
+		Where("ticket_id = ? AND event_type = ?", ticketID, model.TicketEventNodeEntered).
+		Select("COALESCE(MAX(id), 0)").Scan(&id)
+	return id
+}
+
+// issueApprovalActionToken 创建审批令牌，返回令牌字符串
+func issueApprovalActionToken(ticketID, nodeID, approverID uint, action string) (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	t := &model.ApprovalActionToken{
+		Nonce:       hex.EncodeToString(nonce),
+		TicketID:    ticketID,
+		NodeID:      nodeID,
+		ApproverID:  approverID,
+		NodeEventID: latestNodeEventID(ticketID),
+		Action:      action,
+		ExpiresAt:   time.Now().Add(approvalActionTTL).Truncate(time.Second),
+	}
+	if err := global.GetDB().Create(t).Error; err != nil {
+		return "", err
+	}
+	payload := approvalActionPayload(t)
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + signApprovalAction(payload), nil
+}
+
+// approvalActionTokens 为审批人签发工单当前节点的通过/拒绝令牌（操作 -> 令牌），未配置 server.base_url 时返回空
+func approvalActionTokens(ticket *model.Ticket, approverID uint) map[string]string {
+	if publicBaseURL() == "" || ticket.CurrentNodeID == nil {
+		return nil
+	}
+	tokens := make(map[string]string, 2)
+	for _, action := range []string{model.ApprovalActionApprove, model.ApprovalActionReject} {
+		token, err := issueApprovalActionToken(ticket.ID, *ticket.CurrentNodeID, approverID, action)
+		if err != nil {
+			logger.Warn("Failed to issue approval action token", zap.Uint("ticket_id", ticket.ID), zap.Error(err))
+			return nil
+		}
+		tokens[action] = token
+	}
+	return tokens
+}
+
+// approvalActionURL 审批令牌对应的免登录审批链接
+func approvalActionURL(token string) string {
+	return publicBaseURL() + "/api/v1/approval-actions?token=" + url.QueryEscape(token)
+}
+
+// withApprovalLinks 为指定审批人生成带审批链接的通知，卡片按钮同时携带令牌供互动卡片回调使用
+func withApprovalLinks(msg *notifyMessage, ticket *model.Ticket, approverID uint) *notifyMessage {
+	tokens := approvalActionTokens(ticket, approverID)
+	if tokens == nil {
+		return msg
+	}
+	approve, reject := tokens[model.ApprovalActionApprove], tokens[model.ApprovalActionReject]
+	vars := *msg.Vars
+	vars.ApproveLink, vars.RejectLink = approvalActionURL(approve), approvalActionURL(reject)
+	out := *msg
+	out.Vars = &vars
+	out.Actions = []notify.ActionButton{
+		{Title: approvalActionLabels[model.ApprovalActionApprove], URL: vars.ApproveLink, Value: approve},
+		{Title: approvalActionLabels[model.ApprovalActionReject], URL: vars.RejectLink, Value: reject},
+	}
+	return &out
+}
+
+// ApprovalActionService 通知审批链接服务
+type ApprovalActionService struct {
+	ticketSvc *TicketService
+}
+
+// NewApprovalActionService 创建通知审批链接服务
+func NewApprovalActionService() *ApprovalActionService {
+	return &ApprovalActionService{ticketSvc: NewTicketService()}
+}
+
+// verify 校验令牌签名、有效期、使用状态，以及工单是否仍停留在令牌签发时进入的节点
+func (s *ApprovalActionService) verify(token string) (*model.ApprovalActionToken, *model.Ticket, error) {
+	invalid := errors.New("审批链接无效")
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, nil, invalid
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, nil, invalid
+	}
+	payload := string(raw)
+	if !hmac.Equal([]byte(sig), []byte(signApprovalAction(payload))) {
+		return nil, nil, invalid
+	}
+	parts := strings.Split(payload, ".")
+	if len(parts) != 6 {
+		return nil, nil, invalid
+	}
+	expires, err := strconv.ParseInt(parts[4], 10, 64)
+	if err != nil {
+		return nil, nil, invalid
+	}
+	if time.Now().Unix() > expires {
+		return nil, nil, errors.New("审批链接已过期，请登录系统处理")
+	}
+
+	var t model.ApprovalActionToken
+	if err := global.GetDB().Where("nonce = ?", parts[5]).First(&t).Error; err != nil {
+		return nil, nil, invalid
+	}
+	if approvalActionPayload(&t) != payload {
+		return nil, nil, invalid
+	}
+	if t.UsedAt != nil {
+		return nil, nil, errors.New("审批链接已使用")
+	}
+
+	var ticket model.Ticket
+	if err := global.GetDB().First(&ticket, t.TicketID).Error; err != nil {
+		return nil, nil, errors.New("工单不存在")
+	}
+	if (ticket.Status != model.TicketStatusPending && ticket.Status != model.TicketStatusApproving) ||
+		ticket.CurrentNodeID == nil || *ticket.CurrentNodeID != t.NodeID || latestNodeEventID(ticket.ID) != t.NodeEventID {
+		return nil, nil, errors.New("工单已不在该审批节点，链接已失效")
+	}
+	canApprove, err := s.ticketSvc.CanUserApprove(ticket.ID, t.ApproverID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !canApprove {
+		return nil, nil, errors.New("您没有审批此工单的权限")
+	}
+	return &t, &ticket, nil
+}
+
+// actionInfo 组装确认页面展示的信息
+func (s *ApprovalActionService) actionInfo(t *model.ApprovalActionToken, ticket *model.Ticket) *ApprovalActionInfo {
+	info := &ApprovalActionInfo{
+		TicketID:     ticket.ID,
+		TicketNumber: ticketDisplayNumber(ticket),
+		TicketTitle:  ticket.Title,
+		Approver:     newUserNameCache().Get(t.ApproverID),
+		Action:       t.Action,
+		ActionText:   approvalActionLabels[t.Action],
+		ExpiresAt:    t.ExpiresAt,
+	}
+	var node model.FlowNode
+	if global.GetDB().Select("id", "name").First(&node, t.NodeID).Error == nil {
+		info.NodeName = node.Name
+	}
+	return info
+}
+
+// Inspect 校验审批链接并返回操作信息（不执行审批）
+func (s *ApprovalActionService) Inspect(token string) (*ApprovalActionInfo, error) {
+	t, ticket, err := s.verify(token)
+	if err != nil {
+		return nil, err
+	}
+	return s.actionInfo(t, ticket), nil
+}
+
+// Confirm 执行审批链接对应的操作
+func (s *ApprovalActionService) Confirm(token, comment string) (*ApprovalActionInfo, error) {
+	t, ticket, err := s.verify(token)
+	if err != nil {
+		return nil, err
+	}
+	return s.confirm(t, ticket, comment)
+}
+
+// ConfirmFor 以指定用户身份执行审批令牌对应的操作（如钉钉卡片回调），用户必须是令牌绑定的审批人
+func (s *ApprovalActionService) ConfirmFor(token string, userID uint, comment string) (*ApprovalActionInfo, error) {
+	t, ticket, err := s.verify(token)
+	if err != nil {
+		return nil, err
+	}
+	if t.ApproverID != userID {
+		return nil, errors.New("您没有审批此工单的权限")
+	}
+	return s.confirm(t, ticket, comment)
+}
+
+// confirm 一次性抢占审批人在本次节点审批中的全部令牌，保证只执行一次审批，审批失败时释放令牌
+func (s *ApprovalActionService) confirm(t *model.ApprovalActionToken, ticket *model.Ticket, comment string) (*ApprovalActionInfo, error) {
+	siblings := func() *gorm.DB {
+		return global.GetDB().Model(&model.ApprovalActionToken{}).
+			Where("ticket_id = ? AND node_id = ? AND approver_id = ? AND node_event_id = ?", t.TicketID, t.NodeID, t.ApproverID, t.NodeEventID)
+	}
+	res := siblings().Where("used_at IS NULL").Update("used_at", time.Now())
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, errors.New("审批链接已使用")
+	}
+
+	if err := s.ticketSvc.Approve(ticket.ID, t.ApproverID, t.Action == model.ApprovalActionApprove, comment, nil); err != nil {
+		siblings().Update("used_at", nil)
+		return nil, err
+	}
+	return s.actionInfo(t, ticket), nil
+}
@@ -2,9 +2,11 @@ package service
 
 import (
 	"errors"
+	"fmt"
 
 	"backend/internal/model"
 	"backend/internal/global"
+	"backend/pkg/expr"
 )
 
 type ApprovalFlowService struct{}
@@ -83,7 +85,27 @@ func (s *ApprovalFlowService) GetNodesByFlowID(flowID uint) ([]model.FlowNode, e
 	return nodes, nil
 }
 
+// validateFlowNodes 校验条件节点的条件表达式及节点字段权限
+func validateFlowNodes(nodes []model.FlowNode) error {
+	for _, node := range nodes {
+		if _, err := ParseFieldPermissions(node.FieldPermissions); err != nil {
+			return fmt.Errorf("节点 %s: %v", node.Name, err)
+		}
+		if node.NodeType != model.FlowNodeTypeCondition {
+			continue
+		}
+		if _, err := expr.ParseCondition(node.Condition); err != nil {
+			return fmt.Errorf("节点 %s 的条件无效: %v", node.Name, err)
+		}
+	}
+	return nil
+}
+
 func (s *ApprovalFlowService) SaveNodes(flowID uint, nodes []model.FlowNode) error {
+	if err := validateFlowNodes(nodes); err != nil {
+		return err
+	}
+
 	// 开启事务
 	tx := global.GetDB().Begin()
 
@@ -131,6 +153,10 @@ func (s *ApprovalFlowService) SaveNodes(flowID uint, nodes []model.FlowNode) err
 
 // SaveNodesWithConnections 保存节点及连线关系（用于可视化编辑器）
 func (s *ApprovalFlowService) SaveNodesWithConnections(flowID uint, nodes []model.FlowNode, connections []NodeConnection) error {
+	if err := validateFlowNodes(nodes); err != nil {
+		return err
+	}
+
 	tx := global.GetDB().Begin()
 
 	// 删除旧节点
@@ -71,6 +71,7 @@ func (s *AttachmentService) Upload(ctx context.Context, ticketID uint, commentID
 		provider.Delete(ctx, storagePath)
 		return nil, err
 	}
+	reindexTicket(ticketID)
 
 	return attachment, nil
 }
@@ -86,7 +87,11 @@ func (s *AttachmentService) Delete(ctx context.Context, id uint) error {
 		provider.Delete(ctx, attachment.StoragePath)
 	}
 
-	return global.GetDB().Delete(&attachment).Error
+	if err := global.GetDB().Delete(&attachment).Error; err != nil {
+		return err
+	}
+	reindexTicket(attachment.TicketID)
+	return nil
 }
 
 // GetByID 根据ID获取附件
@@ -1,8 +1,12 @@
 package service
 
 import (
+	"errors"
+
 	"backend/internal/model"
 	"backend/internal/global"
+
+	"gorm.io/gorm"
 )
 
 type CommentService struct{}
@@ -13,13 +17,33 @@ func NewCommentService() *CommentService {
 
 // Create 创建评论
 func (s *CommentService) Create(comment *model.TicketComment) error {
-	return global.GetDB().Create(comment).Error
+	if err := global.GetDB().Create(comment).Error; err != nil {
+		return err
+	}
+	reindexTicket(comment.TicketID)
+	enqueueWebhookEvent(comment.TicketID, model.WebhookEventCommented, nil, comment.UserID, map[string]interface{}{
+		"comment_id":   comment.ID,
+		"content":      comment.Content,
+		"comment_type": comment.CommentType,
+	})
+	return nil
 }
 
 // Delete 删除评论
 func (s *CommentService) Delete(id, userID uint) error {
 	// 只能删除自己的评论
-	return global.GetDB().Where("id = ? AND user_id = ?", id, userID).Delete(&model.TicketComment{}).Error
+	var comment model.TicketComment
+	if err := global.GetDB().Where("id = ? AND user_id = ?", id, userID).First(&comment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if err := global.GetDB().Delete(&comment).Error; err != nil {
+		return err
+	}
+	reindexTicket(comment.TicketID)
+	return nil
 }
 
 // GetByTicketID 获取工单的评论列表
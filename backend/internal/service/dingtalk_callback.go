@@ -0,0 +1,185 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/global"
+	"backend/internal/model"
+	"backend/pkg/logger"
+	"backend/pkg/notify"
+
+	"go.uber.org/zap"
+)
+
+// dingTalkCallbackMaxSkew 回调时间戳与服务器时间允许的最大偏差，超出视为重放
+const dingTalkCallbackMaxSkew = 5 * time.Minute
+
+var (
+	// ErrDingTalkCallbackSignature 钉钉回调签名校验失败
+	ErrDingTalkCallbackSignature = errors.New("钉钉回调签名校验失败")
+	// ErrDingTalkCallbackExpired 钉钉回调时间戳无效或已过期
+	ErrDingTalkCallbackExpired = errors.New("钉钉回调时间戳无效或已过期")
+)
+
+// DingTalkCallbackQuery 钉钉回调请求的签名参数
+type DingTalkCallbackQuery struct {
+	Signature string
+	Timestamp string
+	Nonce     string
+}
+
+// dingTalkCallbackEvent 钉钉回调事件（卡片按钮回调的参数可能在 content 或 value 中，均为 JSON 字符串）
+type dingTalkCallbackEvent struct {
+	EventType string `json:"EventType"`
+	UserID    string `json:"userId"`
+	Content   string `json:"content"`
+	Value     string `json:"value"`
+}
+
+// dingTalkCardParams 卡片按钮回调参数
+type dingTalkCardParams struct {
+	Token   string `json:"token"`
+	Comment string `json:"comment"`
+}
+
+// DingTalkCallbackService 钉钉企业内部应用事件回调服务
+type DingTalkCallbackService struct {
+	notifySvc  *NotificationService
+	actionSvc  *ApprovalActionService
+	crypto     func() (*notify.DingTalkCrypto, error)   // 回调加解密器，默认按钉钉应用配置创建
+	cardAction func(event *dingTalkCallbackEvent) error // 卡片按钮回调处理，默认执行审批
+}
+
+// NewDingTalkCallbackService 创建钉钉回调服务
+func NewDingTalkCallbackService() *DingTalkCallbackService {
+	s := &DingTalkCallbackService{
+		notifySvc: NewNotificationService(),
+		actionSvc: NewApprovalActionService(),
+	}
+	s.crypto = s.configuredCrypto
+	s.cardAction = s.handleCardAction
+	return s
+}
+
+// configuredCrypto 根据钉钉应用配置创建回调加解密器
+func (s *DingTalkCallbackService) configuredCrypto() (*notify.DingTalkCrypto, error) {
+	cfg := s.notifySvc.dingTalkConfig()
+	if cfg == nil || cfg.Mode != notify.DingTalkModeApp {
+		return nil, errors.New("钉钉企业内部应用未启用")
+	}
+	return notify.NewDingTalkCrypto(cfg.CallbackToken, cfg.CallbackAESKey, cfg.AppKey)
+}
+
+// Handle 校验签名和时间戳并解密回调事件，返回加密的 success 响应。
+// 签名和解密通过后总是确认接收，业务处理失败只记录日志，避免钉钉反复重试
+func (s *DingTalkCallbackService) Handle(q DingTalkCallbackQuery, encrypt string) (map[string]string, error) {
+	crypto, err := s.crypto()
+	if err != nil {
+		return nil, err
+	}
+	if q.Signature == "" || !crypto.VerifySignature(q.Signature, q.Timestamp, q.Nonce, encrypt) {
+		return nil, ErrDingTalkCallbackSignature
+	}
+	if !dingTalkTimestampFresh(q.Timestamp, time.Now()) {
+		return nil, ErrDingTalkCallbackExpired
+	}
+	plain, err := crypto.Decrypt(encrypt)
+	if err != nil {
+		return nil, err
+	}
+
+	var event dingTalkCallbackEvent
+	if err := json.Unmarshal(plain, &event); err != nil {
+		logger.Warn("Failed to parse DingTalk callback event", zap.Error(err))
+	} else if event.EventType != "check_url" {
+		if err := s.cardAction(&event); err != nil {
+			logger.Warn("DingTalk callback event not handled", zap.String("event_type", event.EventType),
+				zap.String("dingtalk_user_id", event.UserID), zap.Error(err))
+		}
+	}
+	return crypto.EncryptResponse([]byte("success"), q.Timestamp, q.Nonce)
+}
+
+// dingTalkTimestampFresh 回调时间戳（毫秒，兼容秒）是否在允许的偏差内
+func dingTalkTimestampFresh(timestamp string, now time.Time) bool {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	t := time.UnixMilli(ts)
+	if ts < 1e12 {
+		t = time.Unix(ts, 0)
+	}
+	skew := now.Sub(t)
+	if skew < 0 {
+		skew = -skew
+	}
+	return skew <= dingTalkCallbackMaxSkew
+}
+
+// handleCardAction 处理审批卡片按钮回调：按钉钉用户ID找到系统用户，以其身份执行审批令牌对应的操作
+func (s *DingTalkCallbackService) handleCardAction(event *dingTalkCallbackEvent) error {
+	params := parseDingTalkCardParams(event)
+	if params.Token == "" {
+		// 与审批无关的事件直接确认
+		return nil
+	}
+	if event.UserID == "" {
+		return errors.New("钉钉回调缺少用户")
+	}
+	var user model.User
+	if err := global.GetDB().Where("ding_talk_user_id = ?", event.UserID).First(&user).Error; err != nil {
+		return errors.New("钉钉用户未关联系统账号")
+	}
+	info, err := s.actionSvc.ConfirmFor(params.Token, user.ID, params.Comment)
+	if err != nil {
+		return err
+	}
+	logger.Info("DingTalk card action handled", zap.Uint("ticket_id", info.TicketID),
+		zap.Uint("user_id", user.ID), zap.String("action", info.Action))
+	return nil
+}
+
+// parseDingTalkCardParams 从回调中取出按钮参数，兼容 {"cardPrivateData":{"params":{...}}}、{"params":{...}} 和直接的参数对象；
+// 按钮为审批链接时也可从链接中取出令牌
+func parseDingTalkCardParams(event *dingTalkCallbackEvent) dingTalkCardParams {
+	for _, raw := range []string{event.Content, event.Value} {
+		if raw == "" {
+			continue
+		}
+		var wrapper struct {
+			CardPrivateData struct {
+				Params dingTalkCardParams `json:"params"`
+			} `json:"cardPrivateData"`
+			Params dingTalkCardParams `json:"params"`
+			dingTalkCardParams
+		}
+		if json.Unmarshal([]byte(raw), &wrapper) != nil {
+			continue
+		}
+		for _, p := range []dingTalkCardParams{wrapper.CardPrivateData.Params, wrapper.Params, wrapper.dingTalkCardParams} {
+			if p.Token != "" {
+				p.Token = tokenFromActionURL(p.Token)
+				return p
+			}
+		}
+	}
+	return dingTalkCardParams{}
+}
+
+// tokenFromActionURL 参数为审批链接时取出其中的令牌
+func tokenFromActionURL(s string) string {
+	if !strings.Contains(s, "?") {
+		return s
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.Query().Get("token") == "" {
+		return s
+	}
+	return u.Query().Get("token")
+}
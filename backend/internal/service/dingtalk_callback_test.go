@@ -0,0 +1,132 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"backend/pkg/notify"
+	"backend/pkg/notify/dingtalkmock"
+)
+
+func newTestCallbackService(t *testing.T, cardAction func(*dingTalkCallbackEvent) error) (*DingTalkCallbackService, *notify.DingTalkCrypto) {
+	t.Helper()
+	aesKey := base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+	crypto, err := notify.NewDingTalkCrypto("callback-token", aesKey[:43], "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &DingTalkCallbackService{
+		crypto:     func() (*notify.DingTalkCrypto, error) { return crypto, nil },
+		cardAction: cardAction,
+	}
+	return s, crypto
+}
+
+// callbackArgs 从模拟钉钉构造的回调请求中取出签名参数和密文
+func callbackArgs(t *testing.T, crypto *notify.DingTalkCrypto, event interface{}) (DingTalkCallbackQuery, string) {
+	t.Helper()
+	req, err := dingtalkmock.NewCallbackRequest(crypto, "http://localhost/api/v1/dingtalk/callback", event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body struct {
+		Encrypt string `json:"encrypt"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	q := req.URL.Query()
+	return DingTalkCallbackQuery{
+		Signature: q.Get("msg_signature"),
+		Timestamp: q.Get("timestamp"),
+		Nonce:     q.Get("nonce"),
+	}, body.Encrypt
+}
+
+// assertSuccessResponse 校验响应签名并解密出 success
+func assertSuccessResponse(t *testing.T, crypto *notify.DingTalkCrypto, resp map[string]string) {
+	t.Helper()
+	if !crypto.VerifySignature(resp["msg_signature"], resp["timeStamp"], resp["nonce"], resp["encrypt"]) {
+		t.Fatal("response signature invalid")
+	}
+	plain, err := crypto.Decrypt(resp["encrypt"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(plain) != "success" {
+		t.Errorf("response = %q, want success", plain)
+	}
+}
+
+func TestDingTalkCallbackCardActionRoundTrip(t *testing.T) {
+	var handled *dingTalkCallbackEvent
+	s, crypto := newTestCallbackService(t, func(event *dingTalkCallbackEvent) error {
+		handled = event
+		return nil
+	})
+	content, _ := json.Marshal(map[string]interface{}{
+		"cardPrivateData": map[string]interface{}{"params": map[string]string{"token": "approve-token"}},
+	})
+	q, encrypt := callbackArgs(t, crypto, map[string]string{
+		"EventType": "card_callback",
+		"userId":    "ding-user",
+		"content":   string(content),
+	})
+
+	resp, err := s.Handle(q, encrypt)
+	if err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	assertSuccessResponse(t, crypto, resp)
+	if handled == nil {
+		t.Fatal("card action not handled")
+	}
+	if handled.UserID != "ding-user" {
+		t.Errorf("user = %q, want ding-user", handled.UserID)
+	}
+	if params := parseDingTalkCardParams(handled); params.Token != "approve-token" {
+		t.Errorf("token = %q, want approve-token", params.Token)
+	}
+}
+
+func TestDingTalkCallbackAcknowledgesFailedAction(t *testing.T) {
+	s, crypto := newTestCallbackService(t, func(*dingTalkCallbackEvent) error {
+		return errors.New("审批链接已使用")
+	})
+	q, encrypt := callbackArgs(t, crypto, map[string]string{"EventType": "card_callback", "userId": "ding-user"})
+
+	resp, err := s.Handle(q, encrypt)
+	if err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	assertSuccessResponse(t, crypto, resp)
+}
+
+func TestDingTalkCallbackRejectsBadSignatureAndStaleTimestamp(t *testing.T) {
+	called := false
+	s, crypto := newTestCallbackService(t, func(*dingTalkCallbackEvent) error {
+		called = true
+		return nil
+	})
+	q, encrypt := callbackArgs(t, crypto, map[string]string{"EventType": "card_callback"})
+
+	tampered := q
+	tampered.Nonce += "x"
+	if _, err := s.Handle(tampered, encrypt); !errors.Is(err, ErrDingTalkCallbackSignature) {
+		t.Errorf("tampered nonce: err = %v, want %v", err, ErrDingTalkCallbackSignature)
+	}
+
+	stale := q
+	stale.Timestamp = strconv.FormatInt(time.Now().Add(-10*time.Minute).UnixMilli(), 10)
+	stale.Signature = crypto.Signature(stale.Timestamp, stale.Nonce, encrypt)
+	if _, err := s.Handle(stale, encrypt); !errors.Is(err, ErrDingTalkCallbackExpired) {
+		t.Errorf("stale timestamp: err = %v, want %v", err, ErrDingTalkCallbackExpired)
+	}
+	if called {
+		t.Error("card action handled for rejected callback")
+	}
+}
@@ -0,0 +1,410 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"backend/internal/model"
+	"backend/pkg/expr"
+)
+
+// formulaDefaultPrecision 公式结果默认保留的小数位数
+const formulaDefaultPrecision = 2
+
+// TableColumn 表格字段的列定义
+type TableColumn struct {
+	Name      string   `json:"name"`
+	Label     string   `json:"label"`
+	Type      string   `json:"type"` // text/number/money/date/select/formula
+	Required  bool     `json:"required"`
+	Options   []string `json:"options,omitempty"`   // select 列的选项
+	Formula   string   `json:"formula,omitempty"`   // formula 列的行内公式，如 qty * price
+	Precision *int     `json:"precision,omitempty"` // formula 列的小数位数
+}
+
+// tableColumnTypes 表格列支持的类型
+var tableColumnTypes = map[string]bool{
+	model.FormFieldTypeText:    true,
+	model.FormFieldTypeNumber:  true,
+	model.FormFieldTypeMoney:   true,
+	model.FormFieldTypeDate:    true,
+	model.FormFieldTypeSelect:  true,
+	model.FormFieldTypeFormula: true,
+}
+
+// tableConfig 表格字段配置（FormField.Options）
+type tableConfig struct {
+	Columns []TableColumn `json:"columns"`
+}
+
+// formulaConfig 公式字段配置（FormField.Options）
+type formulaConfig struct {
+	Expression string `json:"expression"`
+	Precision  *int   `json:"precision,omitempty"`
+}
+
+// parseTableColumns 解析表格字段的列定义
+func parseTableColumns(field *model.FormField) ([]TableColumn, error) {
+	var cfg tableConfig
+	if err := json.Unmarshal([]byte(field.Options), &cfg); err != nil || len(cfg.Columns) == 0 {
+		return nil, errors.New("表格字段需配置列定义")
+	}
+	seen := make(map[string]bool, len(cfg.Columns))
+	for i := range cfg.Columns {
+		col := &cfg.Columns[i]
+		if col.Name == "" || strings.ContainsAny(col.Name, ". ") {
+			return nil, fmt.Errorf("列标识无效: %q", col.Name)
+		}
+		if seen[col.Name] {
+			return nil, fmt.Errorf("列标识重复: %s", col.Name)
+		}
+		seen[col.Name] = true
+		if col.Label == "" {
+			col.Label = col.Name
+		}
+		if !tableColumnTypes[col.Type] {
+			return nil, fmt.Errorf("列 %s 的类型无效: %s", col.Label, col.Type)
+		}
+		if col.Type == model.FormFieldTypeFormula {
+			f, err := expr.CompileFormula(col.Formula)
+			if err != nil {
+				return nil, fmt.Errorf("列 %s 的公式无效: %v", col.Label, err)
+			}
+			for _, v := range f.Vars() {
+				if !seen[v] && !hasColumn(cfg.Columns, v) {
+					return nil, fmt.Errorf("列 %s 的公式引用了不存在的列: %s", col.Label, v)
+				}
+			}
+		}
+	}
+	return cfg.Columns, nil
+}
+
+func hasColumn(columns []TableColumn, name string) bool {
+	for _, c := range columns {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// parseFormulaConfig 解析公式字段配置并编译公式
+func parseFormulaConfig(field *model.FormField) (*formulaConfig, *expr.Formula, error) {
+	var cfg formulaConfig
+	if err := json.Unmarshal([]byte(field.Options), &cfg); err != nil || strings.TrimSpace(cfg.Expression) == "" {
+		return nil, nil, errors.New("公式字段需配置计算公式")
+	}
+	f, err := expr.CompileFormula(cfg.Expression)
+	if err != nil {
+		return nil, nil, fmt.Errorf("公式无效: %v", err)
+	}
+	return &cfg, f, nil
+}
+
+// formatFormulaResult 按精度格式化计算结果
+func formatFormulaResult(v float64, precision *int) string {
+	digits := formulaDefaultPrecision
+	if precision != nil {
+		digits = *precision
+	}
+	return strconv.FormatFloat(expr.Round(v, digits), 'f', -1, 64)
+}
+
+// parseTableRows 解析表格字段的值（行对象数组）
+func parseTableRows(raw string) ([]map[string]interface{}, error) {
+	raw = unquoteFormValue(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	var rows []map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &rows); err != nil {
+		return nil, errors.New("表格数据格式无效")
+	}
+	return rows, nil
+}
+
+// numericFormValue 将原始值解析为数字，空值或非数字按 0 处理
+func numericFormValue(raw string) float64 {
+	n, _ := strconv.ParseFloat(unquoteFormValue(raw), 64)
+	return n
+}
+
+// ValidateFormulaReferences 校验模板内公式字段引用的字段均存在且没有循环引用
+func ValidateFormulaReferences(fields []model.FormField) error {
+	byName := make(map[string]*model.FormField, len(fields))
+	for i := range fields {
+		byName[fields[i].Name] = &fields[i]
+	}
+	deps := make(map[string][]string)
+	for i := range fields {
+		field := &fields[i]
+		if field.FieldType != model.FormFieldTypeFormula {
+			continue
+		}
+		_, f, err := parseFormulaConfig(field)
+		if err != nil {
+			return fmt.Errorf("%s: %v", field.Label, err)
+		}
+		for _, v := range f.Vars() {
+			name, column, isColumn := strings.Cut(v, ".")
+			ref, ok := byName[name]
+			if !ok {
+				return fmt.Errorf("%s: 公式引用了不存在的字段: %s", field.Label, v)
+			}
+			if isColumn {
+				if ref.FieldType != model.FormFieldTypeTable {
+					return fmt.Errorf("%s: %s 不是表格字段", field.Label, name)
+				}
+				columns, err := parseTableColumns(ref)
+				if err != nil || !hasColumn(columns, column) {
+					return fmt.Errorf("%s: 公式引用了不存在的列: %s", field.Label, v)
+				}
+			}
+			deps[field.Name] = append(deps[field.Name], name)
+		}
+	}
+	state := make(map[string]int) // 1 访问中 2 已完成
+	var visit func(name string) bool
+	visit = func(name string) bool {
+		switch state[name] {
+		case 1:
+			return false
+		case 2:
+			return true
+		}
+		state[name] = 1
+		for _, dep := range deps[name] {
+			if !visit(dep) {
+				return false
+			}
+		}
+		state[name] = 2
+		return true
+	}
+	for name := range deps {
+		if !visit(name) {
+			return fmt.Errorf("%s: 公式存在循环引用", byName[name].Label)
+		}
+	}
+	return nil
+}
+
+// computeFormValues 服务端计算表格行内公式列及公式字段，结果写回 values（覆盖客户端提交的计算值）
+func computeFormValues(fields []model.FormField, values map[string]string) error {
+	byName := make(map[string]*model.FormField, len(fields))
+	tables := make(map[string][]map[string]interface{})
+	var fieldErrors []FieldError
+	for i := range fields {
+		field := &fields[i]
+		byName[field.Name] = field
+		if field.FieldType != model.FormFieldTypeTable {
+			continue
+		}
+		raw, ok := values[field.Name]
+		if !ok {
+			continue
+		}
+		rows, err := parseTableRows(raw)
+		columns, cfgErr := parseTableColumns(field)
+		if err != nil || cfgErr != nil {
+			// 格式错误由校验阶段报告
+			continue
+		}
+		if err := computeTableRows(columns, rows); err != nil {
+			fieldErrors = append(fieldErrors, FieldError{Field: field.Name, Label: field.Label, Message: err.Error()})
+			continue
+		}
+		tables[field.Name] = rows
+		if rows != nil {
+			encoded, _ := json.Marshal(rows)
+			values[field.Name] = string(encoded)
+		}
+	}
+
+	results := make(map[string]float64)
+	visiting := make(map[string]bool)
+	var evalField func(field *model.FormField) (float64, error)
+	env := func(name string) ([]float64, bool, bool) {
+		fieldName, column, isColumn := strings.Cut(name, ".")
+		ref, ok := byName[fieldName]
+		if !ok {
+			return nil, false, false
+		}
+		if isColumn {
+			var list []float64
+			for _, row := range tables[fieldName] {
+				list = append(list, numericFormValue(formValueString(row[column])))
+			}
+			return list, true, true
+		}
+		if ref.FieldType == model.FormFieldTypeTable {
+			// 表格字段本身按行计数，如 count(items)
+			list := make([]float64, len(tables[fieldName]))
+			for i := range list {
+				list[i] = 1
+			}
+			return list, true, true
+		}
+		if ref.FieldType == model.FormFieldTypeFormula {
+			v, err := evalField(ref)
+			if err != nil {
+				return nil, false, false
+			}
+			return []float64{v}, false, true
+		}
+		return []float64{numericFormValue(values[fieldName])}, false, true
+	}
+	evalField = func(field *model.FormField) (float64, error) {
+		if v, ok := results[field.Name]; ok {
+			return v, nil
+		}
+		if visiting[field.Name] {
+			return 0, errors.New("公式存在循环引用")
+		}
+		visiting[field.Name] = true
+		defer delete(visiting, field.Name)
+		cfg, f, err := parseFormulaConfig(field)
+		if err != nil {
+			return 0, err
+		}
+		v, err := f.Eval(env)
+		if err != nil {
+			return 0, err
+		}
+		v, _ = strconv.ParseFloat(formatFormulaResult(v, cfg.Precision), 64)
+		results[field.Name] = v
+		return v, nil
+	}
+
+	for i := range fields {
+		field := &fields[i]
+		if field.FieldType != model.FormFieldTypeFormula {
+			continue
+		}
+		cfg, _, err := parseFormulaConfig(field)
+		if err != nil {
+			fieldErrors = append(fieldErrors, FieldError{Field: field.Name, Label: field.Label, Message: err.Error()})
+			continue
+		}
+		v, err := evalField(field)
+		if err != nil {
+			fieldErrors = append(fieldErrors, FieldError{Field: field.Name, Label: field.Label, Message: "计算失败: " + err.Error()})
+			continue
+		}
+		values[field.Name] = formatFormulaResult(v, cfg.Precision)
+	}
+	if len(fieldErrors) > 0 {
+		return &FormValidationError{Errors: fieldErrors}
+	}
+	return nil
+}
+
+// computeTableRows 计算表格每行的公式列
+func computeTableRows(columns []TableColumn, rows []map[string]interface{}) error {
+	for i, row := range rows {
+		for _, col := range columns {
+			if col.Type != model.FormFieldTypeFormula {
+				continue
+			}
+			f, err := expr.CompileFormula(col.Formula)
+			if err != nil {
+				return err
+			}
+			v, err := f.Eval(func(name string) ([]float64, bool, bool) {
+				if !hasColumn(columns, name) {
+					return nil, false, false
+				}
+				return []float64{numericFormValue(formValueString(row[name]))}, false, true
+			})
+			if err != nil {
+				return fmt.Errorf("第 %d 行 %s 计算失败: %v", i+1, col.Label, err)
+			}
+			row[col.Name] = formatFormulaResult(v, col.Precision)
+		}
+	}
+	return nil
+}
+
+// validateTableRows 校验表格各行数据
+func (v *formValidator) validateTableRows(field *model.FormField, rules *FieldValidation, raw string) {
+	columns, err := parseTableColumns(field)
+	if err != nil {
+		v.add(field, err.Error())
+		return
+	}
+	rows, err := parseTableRows(raw)
+	if err != nil {
+		v.add(field, err.Error())
+		return
+	}
+	for i, row := range rows {
+		for name := range row {
+			if !hasColumn(columns, name) {
+				v.add(field, fmt.Sprintf("第 %d 行包含未知列: %s", i+1, name))
+				return
+			}
+		}
+		for _, col := range columns {
+			cell := unquoteFormValue(formValueString(row[col.Name]))
+			if cell == "" {
+				if col.Required {
+					v.add(field, fmt.Sprintf("第 %d 行 %s: 必填", i+1, col.Label))
+					return
+				}
+				continue
+			}
+			if msg := validateTableCell(&col, cell); msg != "" {
+				v.add(field, fmt.Sprintf("第 %d 行 %s: %s", i+1, col.Label, msg))
+				return
+			}
+		}
+	}
+	v.checkItems(field, rules, len(rows))
+}
+
+// validateTableCell 按列类型校验单元格，返回错误信息
+func validateTableCell(col *TableColumn, cell string) string {
+	switch col.Type {
+	case model.FormFieldTypeNumber, model.FormFieldTypeMoney, model.FormFieldTypeFormula:
+		if _, err := strconv.ParseFloat(cell, 64); err != nil {
+			return "不是有效的数字"
+		}
+	case model.FormFieldTypeDate:
+		if _, ok := parseFormTime(cell); !ok {
+			return "不是有效的日期"
+		}
+	case model.FormFieldTypeSelect:
+		if len(col.Options) == 0 {
+			return ""
+		}
+		for _, opt := range col.Options {
+			if opt == cell {
+				return ""
+			}
+		}
+		return "选项无效: " + cell
+	}
+	return ""
+}
+
+// displayTableValue 表格字段显示文本：每行“列名: 值”，行之间以分号分隔
+func displayTableValue(field *model.FormField, raw string) string {
+	columns, err := parseTableColumns(field)
+	rows, rowErr := parseTableRows(raw)
+	if err != nil || rowErr != nil {
+		return unquoteFormValue(raw)
+	}
+	lines := make([]string, 0, len(rows))
+	for _, row := range rows {
+		cells := make([]string, 0, len(columns))
+		for _, col := range columns {
+			cells = append(cells, col.Label+": "+unquoteFormValue(formValueString(row[col.Name])))
+		}
+		lines = append(lines, strings.Join(cells, ", "))
+	}
+	return strings.Join(lines, "; ")
+}
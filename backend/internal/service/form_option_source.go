@@ -0,0 +1,495 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"backend/internal/global"
+	"backend/internal/model"
+	"backend/internal/model/sso"
+)
+
+// 动态选项来源类型
+const (
+	OptionSourceRoleUsers          = "role_users"          // 角色下的用户
+	OptionSourceSSOGroup           = "sso_group"           // SSO 用户组
+	OptionSourceNavigationCategory = "navigation_category" // 导航分类
+	OptionSourceTicketType         = "ticket_type"         // 指定类型的工单
+	OptionSourceHTTP               = "http"                // HTTP 接口
+)
+
+const (
+	optionSourceDefaultTTL  = 300 // 默认缓存秒数
+	optionSourceHTTPTimeout = 5 * time.Second
+	optionSourceMaxItems    = 1000
+	optionCacheMaxEntries   = 2000 // 缓存条目上限，超出时先清理过期条目，再淘汰最久未使用的条目
+	optionParentMaxLength   = 200  // 无选项的父级字段（如文本）允许的父级值长度
+)
+
+// errOptionSourceAddress HTTP 选项来源不允许访问的地址
+var errOptionSourceAddress = errors.New("选项来源地址不能是本机、内网或链路本地地址")
+
+// blockedOptionSourceIP 本机、内网、链路本地、组播及未指定地址
+func blockedOptionSourceIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified()
+}
+
+// optionSourceHTTPClient HTTP 选项来源客户端：连接时校验解析后的 IP（防止 DNS 重绑定），不使用代理，不跟随重定向
+var optionSourceHTTPClient = &http.Client{
+	Timeout: optionSourceHTTPTimeout,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: optionSourceHTTPTimeout,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				if ip := net.ParseIP(host); ip == nil || blockedOptionSourceIP(ip) {
+					return errOptionSourceAddress
+				}
+				return nil
+			},
+		}).DialContext,
+		TLSHandshakeTimeout:   optionSourceHTTPTimeout,
+		ResponseHeaderTimeout: optionSourceHTTPTimeout,
+		MaxIdleConnsPerHost:   4,
+	},
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return errors.New("选项来源不允许重定向")
+	},
+}
+
+// OptionSource 选项来源配置（FormField.OptionSource JSON）
+//
+// 配置 depends_on 时为级联选项，所依赖字段的值作为父级参数：
+// role_users 为角色ID，sso_group 为租户ID，navigation_category 为父分类ID，
+// ticket_type 为工单类型ID，http 替换 URL 中的 {{parent}}
+type OptionSource struct {
+	Type      string            `json:"type"`
+	RoleID    uint              `json:"role_id,omitempty"`    // role_users
+	TenantID  uint              `json:"tenant_id,omitempty"`  // sso_group
+	ParentID  *uint             `json:"parent_id,omitempty"`  // navigation_category
+	TypeID    uint              `json:"type_id,omitempty"`    // ticket_type
+	Status    string            `json:"status,omitempty"`     // ticket_type 工单状态，默认已完成
+	URL       string            `json:"url,omitempty"`        // http
+	Headers   map[string]string `json:"headers,omitempty"`    // http 请求头
+	ItemsPath string            `json:"items_path,omitempty"` // http 响应中选项数组的路径，如 data.list
+	ValueKey  string            `json:"value_key,omitempty"`  // http 选项值字段，默认 value
+	LabelKey  string            `json:"label_key,omitempty"`  // http 选项名称字段，默认 label
+	DependsOn string            `json:"depends_on,omitempty"` // 级联：依赖的字段标识
+	TTL       int               `json:"ttl,omitempty"`        // 缓存秒数
+}
+
+// ParseOptionSource 解析字段的选项来源，未配置时返回 nil（使用静态选项）
+func ParseOptionSource(field *model.FormField) (*OptionSource, error) {
+	if strings.TrimSpace(field.OptionSource) == "" {
+		return nil, nil
+	}
+	var src OptionSource
+	if err := json.Unmarshal([]byte(field.OptionSource), &src); err != nil {
+		return nil, errors.New("选项来源必须是 JSON 对象")
+	}
+	cascading := src.DependsOn != ""
+	switch src.Type {
+	case OptionSourceRoleUsers:
+		if src.RoleID == 0 && !cascading {
+			return nil, errors.New("请指定角色")
+		}
+	case OptionSourceSSOGroup, OptionSourceNavigationCategory:
+	case OptionSourceTicketType:
+		if src.TypeID == 0 && !cascading {
+			return nil, errors.New("请指定工单类型")
+		}
+	case OptionSourceHTTP:
+		u, err := url.Parse(strings.ReplaceAll(src.URL, "{{parent}}", "x"))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, errors.New("HTTP 选项来源地址无效")
+		}
+		if ip := net.ParseIP(u.Hostname()); (ip != nil && blockedOptionSourceIP(ip)) || strings.EqualFold(u.Hostname(), "localhost") {
+			return nil, errOptionSourceAddress
+		}
+	default:
+		return nil, fmt.Errorf("不支持的选项来源: %s", src.Type)
+	}
+	if src.DependsOn == field.Name && cascading {
+		return nil, errors.New("级联字段不能依赖自身")
+	}
+	return &src, nil
+}
+
+// optionCacheEntry 选项缓存
+type optionCacheEntry struct {
+	options   []FieldOption
+	expiresAt time.Time
+	usedAt    time.Time
+}
+
+// optionCache 动态选项缓存，键为 字段ID:父级值
+var optionCache = struct {
+	sync.Mutex
+	entries map[string]*optionCacheEntry
+}{entries: make(map[string]*optionCacheEntry)}
+
+// putOptionCache 写入缓存，条目数达到上限时先清理过期条目，仍超出则淘汰最久未使用的条目（调用方需持有锁）
+func putOptionCache(key string, entry *optionCacheEntry) {
+	if _, ok := optionCache.entries[key]; !ok && len(optionCache.entries) >= optionCacheMaxEntries {
+		now := time.Now()
+		for k, e := range optionCache.entries {
+			if now.After(e.expiresAt) {
+				delete(optionCache.entries, k)
+			}
+		}
+		for len(optionCache.entries) >= optionCacheMaxEntries {
+			var oldestKey string
+			var oldest time.Time
+			for k, e := range optionCache.entries {
+				if oldestKey == "" || e.usedAt.Before(oldest) {
+					oldestKey, oldest = k, e.usedAt
+				}
+			}
+			delete(optionCache.entries, oldestKey)
+		}
+	}
+	optionCache.entries[key] = entry
+}
+
+// ResolveFieldOptions 获取字段选项：静态选项直接解析，动态来源优先使用缓存，
+// 缓存过期后重新获取，获取失败时退回过期缓存
+func ResolveFieldOptions(field *model.FormField, parent string) ([]FieldOption, error) {
+	src, err := ParseOptionSource(field)
+	if err != nil {
+		return nil, err
+	}
+	if src == nil {
+		return parseFieldOptions(field.Options), nil
+	}
+	parent = unquoteFormValue(parent)
+	if src.DependsOn != "" && parent == "" {
+		return []FieldOption{}, nil
+	}
+
+	key := fmt.Sprintf("%d:%s", field.ID, parent)
+	optionCache.Lock()
+	entry := optionCache.entries[key]
+	if entry != nil {
+		entry.usedAt = time.Now()
+	}
+	optionCache.Unlock()
+	if entry != nil && time.Now().Before(entry.expiresAt) {
+		return entry.options, nil
+	}
+
+	options, err := fetchOptions(src, parent)
+	if err != nil {
+		if entry != nil {
+			return entry.options, nil
+		}
+		return nil, err
+	}
+	ttl := src.TTL
+	if ttl <= 0 {
+		ttl = optionSourceDefaultTTL
+	}
+	now := time.Now()
+	optionCache.Lock()
+	putOptionCache(key, &optionCacheEntry{options: options, expiresAt: now.Add(time.Duration(ttl) * time.Second), usedAt: now})
+	optionCache.Unlock()
+	return options, nil
+}
+
+// RefreshFieldOptions 清除字段的选项缓存
+func RefreshFieldOptions(fieldID uint) {
+	prefix := fmt.Sprintf("%d:", fieldID)
+	optionCache.Lock()
+	defer optionCache.Unlock()
+	for key := range optionCache.entries {
+		if strings.HasPrefix(key, prefix) {
+			delete(optionCache.entries, key)
+		}
+	}
+}
+
+// fetchOptions 从来源获取选项
+func fetchOptions(src *OptionSource, parent string) ([]FieldOption, error) {
+	parentID := func(fallback uint) (uint, error) {
+		if src.DependsOn == "" {
+			return fallback, nil
+		}
+		id, err := strconv.ParseUint(parent, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("级联父级值无效: %s", parent)
+		}
+		return uint(id), nil
+	}
+	options := []FieldOption{}
+	db := global.GetDB()
+
+	switch src.Type {
+	case OptionSourceRoleUsers:
+		roleID, err := parentID(src.RoleID)
+		if err != nil {
+			return nil, err
+		}
+		var users []model.User
+		if err := db.Select("users.id", "users.username").
+			Joins("JOIN user_roles ON users.id = user_roles.user_id").
+			Where("user_roles.role_id = ? AND users.status = ?", roleID, 1).
+			Order("users.username").Limit(optionSourceMaxItems).Find(&users).Error; err != nil {
+			return nil, err
+		}
+		for _, u := range users {
+			options = append(options, FieldOption{Value: strconv.FormatUint(uint64(u.ID), 10), Label: u.Username})
+		}
+	case OptionSourceSSOGroup:
+		tenantID, err := parentID(src.TenantID)
+		if err != nil {
+			return nil, err
+		}
+		query := db.Model(&sso.Group{}).Where("status = ?", 1)
+		if tenantID > 0 {
+			query = query.Where("tenant_id = ?", tenantID)
+		}
+		var groups []sso.Group
+		if err := query.Order("name").Limit(optionSourceMaxItems).Find(&groups).Error; err != nil {
+			return nil, err
+		}
+		for _, g := range groups {
+			options = append(options, FieldOption{Value: strconv.FormatUint(uint64(g.ID), 10), Label: g.Name})
+		}
+	case OptionSourceNavigationCategory:
+		query := db.Model(&model.NavigationCategory{})
+		if src.DependsOn != "" {
+			id, err := parentID(0)
+			if err != nil {
+				return nil, err
+			}
+			query = query.Where("parent_id = ?", id)
+		} else if src.ParentID != nil {
+			query = query.Where("parent_id = ?", *src.ParentID)
+		}
+		var categories []model.NavigationCategory
+		if err := query.Order("sort ASC, id ASC").Limit(optionSourceMaxItems).Find(&categories).Error; err != nil {
+			return nil, err
+		}
+		for _, c := range categories {
+			options = append(options, FieldOption{Value: strconv.FormatUint(uint64(c.ID), 10), Label: c.Name})
+		}
+	case OptionSourceTicketType:
+		typeID, err := parentID(src.TypeID)
+		if err != nil {
+			return nil, err
+		}
+		status := src.Status
+		if status == "" {
+			status = model.TicketStatusCompleted
+		}
+		var tickets []model.Ticket
+		if err := db.Select("id", "number", "title").Where("type_id = ? AND status = ?", typeID, status).
+			Order("id DESC").Limit(optionSourceMaxItems).Find(&tickets).Error; err != nil {
+			return nil, err
+		}
+		for i := range tickets {
+			options = append(options, FieldOption{
+				Value: strconv.FormatUint(uint64(tickets[i].ID), 10),
+				Label: ticketDisplayNumber(&tickets[i]) + " " + tickets[i].Title,
+			})
+		}
+	case OptionSourceHTTP:
+		return fetchHTTPOptions(src, parent)
+	}
+	return options, nil
+}
+
+// fetchHTTPOptions 从 HTTP 接口获取选项，响应为 JSON 数组（或 items_path 指向的数组），
+// 元素可以是字符串或对象
+func fetchHTTPOptions(src *OptionSource, parent string) ([]FieldOption, error) {
+	target := strings.ReplaceAll(src.URL, "{{parent}}", url.QueryEscape(parent))
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	for k, v := range src.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := optionSourceHTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("选项来源请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("选项来源返回状态码 %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, errors.New("选项来源返回的不是 JSON")
+	}
+	if src.ItemsPath != "" {
+		for _, key := range strings.Split(src.ItemsPath, ".") {
+			obj, ok := data.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("选项来源响应中找不到 %s", src.ItemsPath)
+			}
+			data = obj[key]
+		}
+	}
+	items, ok := data.([]interface{})
+	if !ok {
+		return nil, errors.New("选项来源响应不是数组")
+	}
+
+	valueKey, labelKey := src.ValueKey, src.LabelKey
+	if valueKey == "" {
+		valueKey = "value"
+	}
+	if labelKey == "" {
+		labelKey = "label"
+	}
+	options := make([]FieldOption, 0, len(items))
+	for _, item := range items {
+		if len(options) >= optionSourceMaxItems {
+			break
+		}
+		switch v := item.(type) {
+		case map[string]interface{}:
+			value := formValueString(v[valueKey])
+			if value == "" || value == "null" {
+				continue
+			}
+			label := formValueString(v[labelKey])
+			if label == "" || label == "null" {
+				label = value
+			}
+			options = append(options, FieldOption{Value: value, Label: label})
+		default:
+			value := formValueString(v)
+			options = append(options, FieldOption{Value: value, Label: value})
+		}
+	}
+	return options, nil
+}
+
+// ValidateOptionDependencies 校验级联字段依赖的字段存在
+func ValidateOptionDependencies(fields []model.FormField) error {
+	names := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		names[f.Name] = true
+	}
+	for i := range fields {
+		src, err := ParseOptionSource(&fields[i])
+		if err != nil {
+			return fmt.Errorf("%s: %v", fields[i].Label, err)
+		}
+		if src != nil && src.DependsOn != "" && !names[src.DependsOn] {
+			return fmt.Errorf("%s: 级联依赖的字段不存在: %s", fields[i].Label, src.DependsOn)
+		}
+	}
+	return nil
+}
+
+// FieldOptionsFor 按表单当前值获取字段选项（级联字段取所依赖字段的值）
+func FieldOptionsFor(field *model.FormField, values map[string]string) ([]FieldOption, error) {
+	parent := ""
+	if src, err := ParseOptionSource(field); err == nil && src != nil && src.DependsOn != "" {
+		parent = values[src.DependsOn]
+	}
+	return ResolveFieldOptions(field, parent)
+}
+
+// cachedFieldOptions 获取字段选项用于显示：静态选项直接解析，动态来源仅读取缓存，不发起请求
+func cachedFieldOptions(field *model.FormField) []FieldOption {
+	src, err := ParseOptionSource(field)
+	if err != nil || src == nil {
+		return parseFieldOptions(field.Options)
+	}
+	prefix := fmt.Sprintf("%d:", field.ID)
+	var options []FieldOption
+	optionCache.Lock()
+	defer optionCache.Unlock()
+	for key, entry := range optionCache.entries {
+		if strings.HasPrefix(key, prefix) {
+			options = append(options, entry.options...)
+		}
+	}
+	return options
+}
+
+// validateOptionParent 校验级联父级值是所依赖字段的可选值：依赖字段为静态或非级联的动态选项时按其选项校验，
+// 为级联字段时按已缓存的选项校验，无选项的字段（如文本）只限制长度
+func validateOptionParent(field *model.FormField, parent string) error {
+	src, err := ParseOptionSource(field)
+	if err != nil || src == nil || src.DependsOn == "" {
+		return err
+	}
+	parent = unquoteFormValue(parent)
+	if parent == "" {
+		return nil
+	}
+	var parentField model.FormField
+	if err := global.GetDB().Where("template_id = ? AND name = ?", field.TemplateID, src.DependsOn).
+		First(&parentField).Error; err != nil {
+		return errors.New("级联依赖的字段不存在")
+	}
+	parentSrc, err := ParseOptionSource(&parentField)
+	if err != nil {
+		return err
+	}
+	var options []FieldOption
+	switch {
+	case parentSrc == nil:
+		options = parseFieldOptions(parentField.Options)
+		if len(options) == 0 {
+			if len([]rune(parent)) > optionParentMaxLength {
+				return errors.New("级联父级值过长")
+			}
+			return nil
+		}
+	case parentSrc.DependsOn == "":
+		if options, err = ResolveFieldOptions(&parentField, ""); err != nil {
+			return err
+		}
+	default:
+		options = cachedFieldOptions(&parentField)
+	}
+	for _, opt := range options {
+		if opt.Value == parent {
+			return nil
+		}
+	}
+	return fmt.Errorf("级联父级值无效: %s", parent)
+}
+
+// GetFieldOptions 按字段ID获取选项（供前端加载下拉选项），级联字段需传入父级值
+func (s *FormTemplateService) GetFieldOptions(fieldID uint, parent string) ([]FieldOption, error) {
+	var field model.FormField
+	if err := global.GetDB().First(&field, fieldID).Error; err != nil {
+		return nil, errors.New("字段不存在")
+	}
+	if err := validateOptionParent(&field, parent); err != nil {
+		return nil, err
+	}
+	return ResolveFieldOptions(&field, parent)
+}
+
+// RefreshFieldOptions 清除字段选项缓存并重新获取（级联字段需传入父级值）
+func (s *FormTemplateService) RefreshFieldOptions(fieldID uint, parent string) ([]FieldOption, error) {
+	RefreshFieldOptions(fieldID)
+	return s.GetFieldOptions(fieldID, parent)
+}
@@ -0,0 +1,513 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"backend/internal/global"
+	"backend/internal/model"
+)
+
+// jsonSchemaDraft 导出的 JSON Schema 版本
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema 表单模板对应的 JSON Schema（仅包含表单用到的关键字）
+//
+// 无法用标准关键字表达的配置使用 x- 扩展字段，导入时据此还原字段类型、公式、显示条件等
+type JSONSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	ID                   string                 `json:"$id,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 interface{}            `json:"type,omitempty"` // 字符串或字符串数组（如 ["string","null"]）
+	Format               string                 `json:"format,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties *bool                  `json:"additionalProperties,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	Enum                 []interface{}          `json:"enum,omitempty"`
+	Const                interface{}            `json:"const,omitempty"`
+	OneOf                []*JSONSchema          `json:"oneOf,omitempty"`
+	AnyOf                []*JSONSchema          `json:"anyOf,omitempty"`
+	Default              interface{}            `json:"default,omitempty"`
+	ReadOnly             bool                   `json:"readOnly,omitempty"`
+	MinLength            *int                   `json:"minLength,omitempty"`
+	MaxLength            *int                   `json:"maxLength,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+	Minimum              *float64               `json:"minimum,omitempty"`
+	Maximum              *float64               `json:"maximum,omitempty"`
+	MultipleOf           *float64               `json:"multipleOf,omitempty"`
+	MinItems             *int                   `json:"minItems,omitempty"`
+	MaxItems             *int                   `json:"maxItems,omitempty"`
+	UniqueItems          bool                   `json:"uniqueItems,omitempty"`
+
+	FieldType     string          `json:"x-field-type,omitempty"`  // 表单字段类型
+	Order         int             `json:"x-order,omitempty"`       // 字段顺序
+	XRequired     bool            `json:"x-required,omitempty"`    // 字段必填（有显示条件的字段仅在显示时必填，不列入 required）
+	Placeholder   string          `json:"x-placeholder,omitempty"` // 占位提示
+	Formula       string          `json:"x-formula,omitempty"`     // 公式字段/公式列的表达式
+	Precision     *int            `json:"x-precision,omitempty"`   // 公式结果小数位数
+	MinDate       string          `json:"x-min-date,omitempty"`    // 最早日期，支持 today
+	MaxDate       string          `json:"x-max-date,omitempty"`    // 最晚日期，支持 today
+	Message       string          `json:"x-validation-message,omitempty"`
+	ShowCondition json.RawMessage `json:"x-show-condition,omitempty"` // 显示条件，隐藏时不要求必填
+	OptionSource  json.RawMessage `json:"x-option-source,omitempty"`  // 动态选项来源，提交值按来源解析结果校验
+	Version       int             `json:"x-version,omitempty"`        // 模板版本
+}
+
+// FormTemplateSchema 生成表单模板的 JSON Schema
+func FormTemplateSchema(tpl *model.FormTemplate, fields []model.FormField) *JSONSchema {
+	closed := false
+	schema := &JSONSchema{
+		Schema:               jsonSchemaDraft,
+		ID:                   fmt.Sprintf("/api/v1/form-templates/%d/schema", tpl.ID),
+		Title:                tpl.Name,
+		Description:          tpl.Description,
+		Type:                 "object",
+		Properties:           make(map[string]*JSONSchema, len(fields)),
+		AdditionalProperties: &closed,
+		Version:              tpl.Version,
+	}
+	for i := range fields {
+		field := &fields[i]
+		prop := fieldSchema(field)
+		prop.Order = i + 1
+		schema.Properties[field.Name] = prop
+		// 有显示条件的字段隐藏时不校验必填，不列入 required；附件创建后单独上传，公式由服务端计算
+		if field.Required && field.ShowCondition == "" &&
+			field.FieldType != model.FormFieldTypeAttachment && field.FieldType != model.FormFieldTypeFormula {
+			schema.Required = append(schema.Required, field.Name)
+		}
+	}
+	return schema
+}
+
+// fieldSchema 单个字段的 JSON Schema
+func fieldSchema(field *model.FormField) *JSONSchema {
+	rules, err := parseFieldValidation(field)
+	if err != nil {
+		rules = &FieldValidation{}
+	}
+	prop := &JSONSchema{
+		Title:       field.Label,
+		FieldType:   field.FieldType,
+		XRequired:   field.Required,
+		Placeholder: field.Placeholder,
+		Message:     rules.Message,
+	}
+	if field.DefaultValue != "" {
+		prop.Default = field.DefaultValue
+	}
+	if json.Valid([]byte(field.ShowCondition)) {
+		prop.ShowCondition = json.RawMessage(field.ShowCondition)
+	}
+	if json.Valid([]byte(field.OptionSource)) {
+		prop.OptionSource = json.RawMessage(field.OptionSource)
+	}
+
+	switch field.FieldType {
+	case model.FormFieldTypeNumber, model.FormFieldTypeMoney:
+		prop.Type = "number"
+		prop.Minimum, prop.Maximum = rules.Min, rules.Max
+		if field.FieldType == model.FormFieldTypeMoney {
+			cent := 0.01
+			prop.MultipleOf = &cent
+		}
+	case model.FormFieldTypeFormula:
+		prop.Type = "number"
+		prop.ReadOnly = true
+		prop.Minimum, prop.Maximum = rules.Min, rules.Max
+		if cfg, _, err := parseFormulaConfig(field); err == nil {
+			prop.Formula = cfg.Expression
+			prop.Precision = cfg.Precision
+		}
+	case model.FormFieldTypeDate, model.FormFieldTypeDatetime:
+		prop.Type = "string"
+		prop.Format = "date"
+		if field.FieldType == model.FormFieldTypeDatetime {
+			prop.Format = "date-time"
+		}
+		prop.MinDate, prop.MaxDate = rules.MinDate, rules.MaxDate
+	case model.FormFieldTypeSelect:
+		prop.Type = "string"
+		prop.OneOf = optionSchemas(field)
+	case model.FormFieldTypeMultiSelect:
+		prop.Type = "array"
+		prop.Items = &JSONSchema{Type: "string", OneOf: optionSchemas(field)}
+		prop.UniqueItems = true
+		prop.MinItems, prop.MaxItems = rules.MinItems, rules.MaxItems
+	case model.FormFieldTypeUser:
+		one := 1.0
+		prop.AnyOf = []*JSONSchema{
+			{Type: "integer", Minimum: &one},
+			{Type: "array", Items: &JSONSchema{Type: "integer", Minimum: &one}, MinItems: rules.MinItems, MaxItems: rules.MaxItems},
+		}
+		prop.Description = "用户ID，多个用户时为数组"
+	case model.FormFieldTypeAttachment:
+		prop.Type = "array"
+		prop.ReadOnly = true
+		prop.Description = "附件在工单创建后单独上传"
+	case model.FormFieldTypeTable:
+		prop.Type = "array"
+		prop.Items = tableRowSchema(field)
+		prop.MinItems, prop.MaxItems = rules.MinItems, rules.MaxItems
+	default:
+		prop.Type = "string"
+		prop.MinLength, prop.MaxLength = rules.MinLength, rules.MaxLength
+		prop.Pattern = rules.Pattern
+	}
+	return prop
+}
+
+// optionSchemas 静态选项转为 oneOf（const 为选项值，title 为选项名称）；动态来源不列出选项
+func optionSchemas(field *model.FormField) []*JSONSchema {
+	if strings.TrimSpace(field.OptionSource) != "" {
+		return nil
+	}
+	var result []*JSONSchema
+	for _, opt := range parseFieldOptions(field.Options) {
+		result = append(result, &JSONSchema{Const: opt.Value, Title: opt.Label})
+	}
+	return result
+}
+
+// tableRowSchema 表格字段单行的 JSON Schema
+func tableRowSchema(field *model.FormField) *JSONSchema {
+	columns, err := parseTableColumns(field)
+	if err != nil {
+		return &JSONSchema{Type: "object"}
+	}
+	row := &JSONSchema{Type: "object", Properties: make(map[string]*JSONSchema, len(columns))}
+	for i, col := range columns {
+		prop := &JSONSchema{Title: col.Label, FieldType: col.Type, Order: i + 1}
+		switch col.Type {
+		case model.FormFieldTypeNumber, model.FormFieldTypeMoney:
+			prop.Type = "number"
+		case model.FormFieldTypeFormula:
+			prop.Type = "number"
+			prop.ReadOnly = true
+			prop.Formula = col.Formula
+			prop.Precision = col.Precision
+		case model.FormFieldTypeDate:
+			prop.Type = "string"
+			prop.Format = "date"
+		case model.FormFieldTypeSelect:
+			prop.Type = "string"
+			for _, opt := range col.Options {
+				prop.OneOf = append(prop.OneOf, &JSONSchema{Const: opt, Title: opt})
+			}
+		default:
+			prop.Type = "string"
+		}
+		row.Properties[col.Name] = prop
+		if col.Required && col.Type != model.FormFieldTypeFormula {
+			row.Required = append(row.Required, col.Name)
+		}
+	}
+	return row
+}
+
+// ParseFormSchema 将 JSON Schema 转为表单字段。字段顺序取 x-order，未指定时按文档中的顺序
+func ParseFormSchema(doc []byte) (*JSONSchema, []model.FormField, error) {
+	var schema JSONSchema
+	if err := json.Unmarshal(doc, &schema); err != nil {
+		return nil, nil, fmt.Errorf("JSON Schema 解析失败: %v", err)
+	}
+	if schemaType(&schema) != "object" || len(schema.Properties) == 0 {
+		return nil, nil, errors.New("JSON Schema 必须是包含 properties 的 object")
+	}
+	required := make(map[string]bool, len(schema.Required))
+	for _, name := range schema.Required {
+		required[name] = true
+	}
+
+	names := schemaPropertyOrder(doc, schema.Properties)
+	fields := make([]model.FormField, 0, len(names))
+	for _, name := range names {
+		field, err := schemaField(name, schema.Properties[name])
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %v", name, err)
+		}
+		field.Required = schema.Properties[name].XRequired || required[name]
+		fields = append(fields, *field)
+	}
+	return &schema, fields, nil
+}
+
+// schemaPropertyOrder 字段顺序：有 x-order 的按 x-order，其余按文档中出现的顺序排在后面
+func schemaPropertyOrder(doc []byte, properties map[string]*JSONSchema) []string {
+	var raw struct {
+		Properties json.RawMessage `json:"properties"`
+	}
+	_ = json.Unmarshal(doc, &raw)
+	position := make(map[string]int, len(properties))
+	dec := json.NewDecoder(bytes.NewReader(raw.Properties))
+	if tok, err := dec.Token(); err == nil && tok == json.Delim('{') {
+		for i := 0; dec.More(); i++ {
+			tok, err := dec.Token()
+			if err != nil {
+				break
+			}
+			if key, ok := tok.(string); ok {
+				position[key] = i
+			}
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				break
+			}
+		}
+	}
+
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.SliceStable(names, func(i, j int) bool {
+		a, b := properties[names[i]], properties[names[j]]
+		if (a.Order > 0) != (b.Order > 0) {
+			return a.Order > 0
+		}
+		if a.Order != b.Order {
+			return a.Order < b.Order
+		}
+		return position[names[i]] < position[names[j]]
+	})
+	return names
+}
+
+// schemaType 取 type 中第一个非 null 的类型
+func schemaType(s *JSONSchema) string {
+	switch t := s.Type.(type) {
+	case string:
+		return t
+	case []interface{}:
+		for _, item := range t {
+			if name, ok := item.(string); ok && name != "null" {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// schemaOptions 从 oneOf/enum 中提取选项
+func schemaOptions(s *JSONSchema) []FieldOption {
+	var options []FieldOption
+	for _, item := range s.OneOf {
+		if item.Const == nil {
+			continue
+		}
+		value := formValueString(item.Const)
+		label := item.Title
+		if label == "" {
+			label = value
+		}
+		options = append(options, FieldOption{Value: value, Label: label})
+	}
+	for _, item := range s.Enum {
+		if item != nil {
+			value := formValueString(item)
+			options = append(options, FieldOption{Value: value, Label: value})
+		}
+	}
+	return options
+}
+
+// schemaField 单个属性转为表单字段
+func schemaField(name string, prop *JSONSchema) (*model.FormField, error) {
+	if prop == nil {
+		return nil, errors.New("属性定义为空")
+	}
+	field := &model.FormField{Name: name, Label: prop.Title, Placeholder: prop.Placeholder}
+	if field.Label == "" {
+		field.Label = name
+	}
+	if prop.Default != nil {
+		field.DefaultValue = formValueString(prop.Default)
+	}
+	field.ShowCondition = compactSchemaJSON(prop.ShowCondition)
+	field.OptionSource = compactSchemaJSON(prop.OptionSource)
+
+	field.FieldType = prop.FieldType
+	if field.FieldType == "" {
+		field.FieldType = inferFieldType(prop)
+	}
+	if field.FieldType == "" {
+		return nil, fmt.Errorf("无法识别的类型: %v", prop.Type)
+	}
+
+	rules := FieldValidation{
+		Min:       prop.Minimum,
+		Max:       prop.Maximum,
+		MinLength: prop.MinLength,
+		MaxLength: prop.MaxLength,
+		Pattern:   prop.Pattern,
+		MinDate:   prop.MinDate,
+		MaxDate:   prop.MaxDate,
+		MinItems:  prop.MinItems,
+		MaxItems:  prop.MaxItems,
+		Message:   prop.Message,
+	}
+	var options interface{}
+	switch field.FieldType {
+	case model.FormFieldTypeSelect:
+		options = schemaOptions(prop)
+		if schemaType(prop) == "boolean" && len(schemaOptions(prop)) == 0 {
+			options = []FieldOption{{Value: "true", Label: "是"}, {Value: "false", Label: "否"}}
+		}
+	case model.FormFieldTypeMultiSelect:
+		if prop.Items != nil {
+			options = schemaOptions(prop.Items)
+		}
+	case model.FormFieldTypeUser:
+		for _, alt := range prop.AnyOf {
+			if schemaType(alt) == "array" {
+				rules.MinItems, rules.MaxItems = alt.MinItems, alt.MaxItems
+			}
+		}
+	case model.FormFieldTypeFormula:
+		options = formulaConfig{Expression: prop.Formula, Precision: prop.Precision}
+	case model.FormFieldTypeTable:
+		if prop.Items == nil || len(prop.Items.Properties) == 0 {
+			return nil, errors.New("表格字段需定义 items.properties")
+		}
+		options = tableConfig{Columns: schemaTableColumns(prop.Items)}
+	}
+	if options != nil {
+		data, err := json.Marshal(options)
+		if err != nil {
+			return nil, err
+		}
+		if string(data) != "null" {
+			field.Options = string(data)
+		}
+	}
+	if rules != (FieldValidation{}) {
+		data, _ := json.Marshal(rules)
+		field.Validation = string(data)
+	}
+	return field, nil
+}
+
+// compactSchemaJSON 扩展字段中的 JSON 配置压缩为字符串保存
+func compactSchemaJSON(raw json.RawMessage) string {
+	var buf bytes.Buffer
+	if len(raw) == 0 || string(raw) == "null" || json.Compact(&buf, raw) != nil {
+		return ""
+	}
+	return buf.String()
+}
+
+// inferFieldType 未指定 x-field-type 时按标准关键字推断字段类型
+func inferFieldType(prop *JSONSchema) string {
+	switch schemaType(prop) {
+	case "string":
+		switch {
+		case prop.Format == "date":
+			return model.FormFieldTypeDate
+		case prop.Format == "date-time":
+			return model.FormFieldTypeDatetime
+		case len(schemaOptions(prop)) > 0 || len(prop.OptionSource) > 0:
+			return model.FormFieldTypeSelect
+		}
+		return model.FormFieldTypeText
+	case "number", "integer":
+		if prop.ReadOnly && prop.Formula != "" {
+			return model.FormFieldTypeFormula
+		}
+		return model.FormFieldTypeNumber
+	case "boolean":
+		return model.FormFieldTypeSelect
+	case "array":
+		if prop.Items != nil && schemaType(prop.Items) == "object" {
+			return model.FormFieldTypeTable
+		}
+		return model.FormFieldTypeMultiSelect
+	}
+	return ""
+}
+
+// schemaTableColumns 表格行的属性转为列定义
+func schemaTableColumns(row *JSONSchema) []TableColumn {
+	required := make(map[string]bool, len(row.Required))
+	for _, name := range row.Required {
+		required[name] = true
+	}
+	names := schemaPropertyOrder(nil, row.Properties)
+	columns := make([]TableColumn, 0, len(names))
+	for _, name := range names {
+		prop := row.Properties[name]
+		col := TableColumn{Name: name, Label: prop.Title, Type: prop.FieldType, Required: required[name]}
+		if col.Label == "" {
+			col.Label = name
+		}
+		if col.Type == "" {
+			col.Type = inferFieldType(prop)
+		}
+		if col.Type == model.FormFieldTypeFormula {
+			col.Formula = prop.Formula
+			col.Precision = prop.Precision
+		}
+		for _, opt := range schemaOptions(prop) {
+			col.Options = append(col.Options, opt.Value)
+		}
+		columns = append(columns, col)
+	}
+	return columns
+}
+
+// ExportSchema 导出表单模板当前版本的 JSON Schema
+func (s *FormTemplateService) ExportSchema(id uint) (*JSONSchema, error) {
+	tpl, err := s.GetByID(id)
+	if err != nil {
+		return nil, errors.New("模板不存在")
+	}
+	return FormTemplateSchema(tpl, tpl.Fields), nil
+}
+
+// ImportSchema 从 JSON Schema 创建（id 为 0）或更新表单模板字段，更新时生成新版本
+func (s *FormTemplateService) ImportSchema(id uint, doc []byte, operatorID uint) (*model.FormTemplate, error) {
+	schema, fields, err := ParseFormSchema(doc)
+	if err != nil {
+		return nil, err
+	}
+	tpl := &model.FormTemplate{}
+	if id == 0 {
+		if schema.Title == "" {
+			return nil, errors.New("JSON Schema 缺少 title（模板名称）")
+		}
+		tpl.Name = schema.Title
+		tpl.Description = schema.Description
+		tpl.Enabled = true
+		if err := s.Create(tpl); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := global.GetDB().First(tpl, id).Error; err != nil {
+			return nil, errors.New("模板不存在")
+		}
+		updates := map[string]interface{}{}
+		if schema.Title != "" {
+			updates["name"] = schema.Title
+		}
+		if schema.Description != "" {
+			updates["description"] = schema.Description
+		}
+		if len(updates) > 0 {
+			if err := global.GetDB().Model(tpl).Updates(updates).Error; err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := s.SaveFields(tpl.ID, fields, operatorID); err != nil {
+		if id == 0 {
+			global.GetDB().Delete(tpl)
+		}
+		return nil, err
+	}
+	return s.GetByID(tpl.ID)
+}
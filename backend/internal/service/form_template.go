@@ -86,10 +86,56 @@ func (s *FormTemplateService) GetFields(templateID uint) ([]model.FormField, err
 	return fields, nil
 }
 
-// SaveFields 保存模板字段
-func (s *FormTemplateService) SaveFields(templateID uint, fields []model.FormField) error {
+// SaveFields 保存模板字段，生成新的模板版本
+//
+// 旧字段软删除后仍被历史工单数据引用，历史工单按创建时绑定的版本快照渲染和校验
+func (s *FormTemplateService) SaveFields(templateID uint, fields []model.FormField, operatorID uint) error {
+	for i := range fields {
+		if err := ValidateFieldRules(&fields[i]); err != nil {
+			return err
+		}
+	}
+	if err := ValidateFormulaReferences(fields); err != nil {
+		return err
+	}
+	if err := ValidateOptionDependencies(fields); err != nil {
+		return err
+	}
+
 	tx := global.GetDB().Begin()
 
+	var tpl model.FormTemplate
+	if err := tx.First(&tpl, templateID).Error; err != nil {
+		tx.Rollback()
+		return errors.New("模板不存在")
+	}
+	nextVersion := tpl.Version + 1
+	var snapshots int64
+	tx.Model(&model.FormTemplateVersion{}).Where("template_id = ? AND version = ?", templateID, tpl.Version).Count(&snapshots)
+	if snapshots == 0 {
+		// 当前版本尚无快照（升级前的模板或首次保存字段）
+		var current []model.FormField
+		if err := tx.Where("template_id = ?", templateID).Order("sort_order ASC").Find(&current).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+		if len(current) == 0 {
+			nextVersion = tpl.Version
+		} else {
+			// 补录当前字段为当前版本，并将未绑定版本的工单绑定到该版本
+			if err := snapshotFormVersion(tx, templateID, tpl.Version, current, nil); err != nil {
+				tx.Rollback()
+				return err
+			}
+			typeIDs := tx.Model(&model.TicketType{}).Select("id").Where("template_id = ?", templateID)
+			if err := tx.Model(&model.Ticket{}).Where("form_template_id IS NULL AND type_id IN (?)", typeIDs).
+				Updates(map[string]interface{}{"form_template_id": templateID, "form_version": tpl.Version}).Error; err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+	}
+
 	// 删除旧字段
 	if err := tx.Where("template_id = ?", templateID).Delete(&model.FormField{}).Error; err != nil {
 		tx.Rollback()
@@ -107,6 +153,15 @@ func (s *FormTemplateService) SaveFields(templateID uint, fields []model.FormFie
 		}
 	}
 
+	if err := snapshotFormVersion(tx, templateID, nextVersion, fields, &operatorID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Model(&tpl).Update("version", nextVersion).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	return tx.Commit().Error
 }
 
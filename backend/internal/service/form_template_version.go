@@ -0,0 +1,218 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"backend/internal/global"
+	"backend/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// FormVersionDetail 表单模板版本详情
+type FormVersionDetail struct {
+	model.FormTemplateVersion
+	Fields []model.FormField `json:"fields"`
+}
+
+// FormFieldAttrChange 字段属性变更
+type FormFieldAttrChange struct {
+	Attr string `json:"attr"`
+	Old  string `json:"old"`
+	New  string `json:"new"`
+}
+
+// FormFieldChange 字段变更（按字段标识匹配）
+type FormFieldChange struct {
+	Name    string                `json:"name"`
+	Label   string                `json:"label"`
+	Changes []FormFieldAttrChange `json:"changes"`
+}
+
+// FormVersionDiff 两个版本的字段差异
+type FormVersionDiff struct {
+	From    int               `json:"from"`
+	To      int               `json:"to"`
+	Added   []model.FormField `json:"added"`
+	Removed []model.FormField `json:"removed"`
+	Changed []FormFieldChange `json:"changed"`
+}
+
+// snapshotFormVersion 保存模板字段快照为指定版本
+func snapshotFormVersion(tx *gorm.DB, templateID uint, version int, fields []model.FormField, operatorID *uint) error {
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	return tx.Create(&model.FormTemplateVersion{
+		TemplateID:  templateID,
+		Version:     version,
+		Fields:      string(data),
+		FieldCount:  len(fields),
+		CreatedByID: operatorID,
+	}).Error
+}
+
+// loadFormVersionFields 读取版本快照中的字段，版本不存在时返回 gorm.ErrRecordNotFound
+func loadFormVersionFields(db *gorm.DB, templateID uint, version int) ([]model.FormField, error) {
+	var v model.FormTemplateVersion
+	if err := db.Where("template_id = ? AND version = ?", templateID, version).First(&v).Error; err != nil {
+		return nil, err
+	}
+	var fields []model.FormField
+	if err := json.Unmarshal([]byte(v.Fields), &fields); err != nil {
+		return nil, fmt.Errorf("表单版本 %d 快照损坏", version)
+	}
+	return fields, nil
+}
+
+// bindTicketFormVersion 将新建工单绑定到类型关联模板的当前版本
+func bindTicketFormVersion(db *gorm.DB, ticket *model.Ticket) error {
+	var ticketType model.TicketType
+	if err := db.Select("id", "template_id").First(&ticketType, ticket.TypeID).Error; err != nil {
+		return err
+	}
+	if ticketType.TemplateID == nil {
+		return nil
+	}
+	var tpl model.FormTemplate
+	if err := db.Select("id", "version").First(&tpl, *ticketType.TemplateID).Error; err != nil {
+		return err
+	}
+	ticket.FormTemplateID = &tpl.ID
+	ticket.FormVersion = tpl.Version
+	return nil
+}
+
+// loadTicketFormFields 获取工单绑定版本的表单字段；未绑定版本（升级前创建）或快照缺失时退回模板当前字段
+func loadTicketFormFields(db *gorm.DB, ticket *model.Ticket) ([]model.FormField, error) {
+	if ticket.FormTemplateID == nil {
+		return loadTypeFormFields(db, ticket.TypeID)
+	}
+	fields, err := loadFormVersionFields(db, *ticket.FormTemplateID, ticket.FormVersion)
+	if err == nil {
+		return fields, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if err := db.Where("template_id = ?", *ticket.FormTemplateID).Order("sort_order ASC").Find(&fields).Error; err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// applyTicketFormVersion 填充工单绑定版本的表单字段，并用版本中的字段定义渲染表单数据
+func applyTicketFormVersion(ticket *model.Ticket) error {
+	fields, err := loadTicketFormFields(global.GetDB(), ticket)
+	if err != nil {
+		return err
+	}
+	ticket.FormFields = fields
+	byID := make(map[uint]*model.FormField, len(fields))
+	for i := range fields {
+		byID[fields[i].ID] = &fields[i]
+	}
+	for i := range ticket.Data {
+		if f, ok := byID[ticket.Data[i].FieldID]; ok {
+			field := *f
+			ticket.Data[i].Field = &field
+		}
+	}
+	return nil
+}
+
+// ListVersions 表单模板版本列表（新版本在前）
+func (s *FormTemplateService) ListVersions(templateID uint) ([]model.FormTemplateVersion, error) {
+	var versions []model.FormTemplateVersion
+	if err := global.GetDB().Preload("CreatedBy").Where("template_id = ?", templateID).
+		Order("version DESC").Find(&versions).Error; err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// GetVersion 表单模板指定版本的字段
+func (s *FormTemplateService) GetVersion(templateID uint, version int) (*FormVersionDetail, error) {
+	var v model.FormTemplateVersion
+	if err := global.GetDB().Preload("CreatedBy").Where("template_id = ? AND version = ?", templateID, version).
+		First(&v).Error; err != nil {
+		return nil, errors.New("版本不存在")
+	}
+	detail := &FormVersionDetail{FormTemplateVersion: v}
+	if err := json.Unmarshal([]byte(v.Fields), &detail.Fields); err != nil {
+		return nil, fmt.Errorf("表单版本 %d 快照损坏", version)
+	}
+	return detail, nil
+}
+
+// CompareVersions 对比两个版本的字段差异（按字段标识匹配）
+func (s *FormTemplateService) CompareVersions(templateID uint, from, to int) (*FormVersionDiff, error) {
+	fromFields, err := loadFormVersionFields(global.GetDB(), templateID, from)
+	if err != nil {
+		return nil, fmt.Errorf("版本 %d 不存在", from)
+	}
+	toFields, err := loadFormVersionFields(global.GetDB(), templateID, to)
+	if err != nil {
+		return nil, fmt.Errorf("版本 %d 不存在", to)
+	}
+
+	diff := &FormVersionDiff{
+		From:    from,
+		To:      to,
+		Added:   []model.FormField{},
+		Removed: []model.FormField{},
+		Changed: []FormFieldChange{},
+	}
+	old := make(map[string]*model.FormField, len(fromFields))
+	for i := range fromFields {
+		old[fromFields[i].Name] = &fromFields[i]
+	}
+	seen := make(map[string]bool, len(toFields))
+	for i := range toFields {
+		field := &toFields[i]
+		seen[field.Name] = true
+		prev, ok := old[field.Name]
+		if !ok {
+			diff.Added = append(diff.Added, *field)
+			continue
+		}
+		if changes := diffFormField(prev, field); len(changes) > 0 {
+			diff.Changed = append(diff.Changed, FormFieldChange{Name: field.Name, Label: field.Label, Changes: changes})
+		}
+	}
+	for _, field := range fromFields {
+		if !seen[field.Name] {
+			diff.Removed = append(diff.Removed, field)
+		}
+	}
+	return diff, nil
+}
+
+// diffFormField 比较同一字段在两个版本中的属性
+func diffFormField(a, b *model.FormField) []FormFieldAttrChange {
+	attrs := []struct {
+		name     string
+		old, new string
+	}{
+		{"label", a.Label, b.Label},
+		{"field_type", a.FieldType, b.FieldType},
+		{"required", fmt.Sprint(a.Required), fmt.Sprint(b.Required)},
+		{"default_value", a.DefaultValue, b.DefaultValue},
+		{"options", a.Options, b.Options},
+		{"validation", a.Validation, b.Validation},
+		{"placeholder", a.Placeholder, b.Placeholder},
+		{"show_condition", a.ShowCondition, b.ShowCondition},
+		{"option_source", a.OptionSource, b.OptionSource},
+		{"sort_order", fmt.Sprint(a.SortOrder), fmt.Sprint(b.SortOrder)},
+	}
+	var changes []FormFieldAttrChange
+	for _, attr := range attrs {
+		if attr.old != attr.new {
+			changes = append(changes, FormFieldAttrChange{Attr: attr.name, Old: attr.old, New: attr.new})
+		}
+	}
+	return changes
+}
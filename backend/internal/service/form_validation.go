@@ -0,0 +1,414 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"backend/internal/global"
+	"backend/internal/model"
+	"backend/pkg/expr"
+
+	"gorm.io/gorm"
+)
+
+// FieldValidation 表单字段校验规则（FormField.Validation JSON）
+type FieldValidation struct {
+	Min       *float64 `json:"min,omitempty"`        // 数字/金额最小值
+	Max       *float64 `json:"max,omitempty"`        // 数字/金额最大值
+	MinLength *int     `json:"min_length,omitempty"` // 文本最少字符数
+	MaxLength *int     `json:"max_length,omitempty"` // 文本最多字符数
+	Pattern   string   `json:"pattern,omitempty"`    // 文本正则
+	MinDate   string   `json:"min_date,omitempty"`   // 最早日期，支持 today
+	MaxDate   string   `json:"max_date,omitempty"`   // 最晚日期，支持 today
+	MinItems  *int     `json:"min_items,omitempty"`  // 多选/多用户最少选择数
+	MaxItems  *int     `json:"max_items,omitempty"`  // 多选/多用户最多选择数
+	Message   string   `json:"message,omitempty"`    // 自定义错误提示（替代规则校验失败时的默认提示）
+}
+
+// FieldError 单个字段的校验错误
+type FieldError struct {
+	Field   string `json:"field"`
+	Label   string `json:"label"`
+	Message string `json:"message"`
+}
+
+// FormValidationError 表单校验错误，包含字段级错误明细
+type FormValidationError struct {
+	Errors []FieldError
+}
+
+func (e *FormValidationError) Error() string {
+	parts := make([]string, 0, len(e.Errors))
+	for _, fe := range e.Errors {
+		parts = append(parts, fe.Label+": "+fe.Message)
+	}
+	return "表单校验失败: " + strings.Join(parts, "; ")
+}
+
+// formValidateMode 表单校验场景
+type formValidateMode int
+
+const (
+	formValidateCreate formValidateMode = iota // 创建：校验全部字段，拒绝未知字段
+	formValidateUpdate                         // 更新：仅校验提交的字段，拒绝未知字段
+	formValidateSubmit                         // 提交：校验已保存数据，忽略已删除的字段
+)
+
+// parseFieldValidation 解析字段校验规则，未配置时返回空规则
+func parseFieldValidation(field *model.FormField) (*FieldValidation, error) {
+	rules := &FieldValidation{}
+	if strings.TrimSpace(field.Validation) == "" {
+		return rules, nil
+	}
+	if err := json.Unmarshal([]byte(field.Validation), rules); err != nil {
+		return nil, errors.New("校验规则必须是 JSON 对象")
+	}
+	return rules, nil
+}
+
+// ValidateFieldRules 校验字段的校验规则配置本身是否合法（保存表单字段时调用）
+func ValidateFieldRules(field *model.FormField) error {
+	rules, err := parseFieldValidation(field)
+	if err != nil {
+		return fmt.Errorf("%s: %v", field.Label, err)
+	}
+	if _, err := ParseOptionSource(field); err != nil {
+		return fmt.Errorf("%s: %v", field.Label, err)
+	}
+	if _, err := expr.ParseCondition(field.ShowCondition); err != nil {
+		return fmt.Errorf("%s: 显示条件无效: %v", field.Label, err)
+	}
+	switch field.FieldType {
+	case model.FormFieldTypeTable:
+		if _, err := parseTableColumns(field); err != nil {
+			return fmt.Errorf("%s: %v", field.Label, err)
+		}
+	case model.FormFieldTypeFormula:
+		if _, _, err := parseFormulaConfig(field); err != nil {
+			return fmt.Errorf("%s: %v", field.Label, err)
+		}
+	}
+	if rules.Pattern != "" {
+		if _, err := regexp.Compile(rules.Pattern); err != nil {
+			return fmt.Errorf("%s: 正则表达式无效", field.Label)
+		}
+	}
+	for _, d := range []string{rules.MinDate, rules.MaxDate} {
+		if _, ok := resolveRuleDate(d); d != "" && !ok {
+			return fmt.Errorf("%s: 日期范围无效: %s", field.Label, d)
+		}
+	}
+	if rules.Min != nil && rules.Max != nil && *rules.Min > *rules.Max {
+		return fmt.Errorf("%s: 最小值不能大于最大值", field.Label)
+	}
+	if rules.MinLength != nil && rules.MaxLength != nil && *rules.MinLength > *rules.MaxLength {
+		return fmt.Errorf("%s: 最少字符数不能大于最多字符数", field.Label)
+	}
+	return nil
+}
+
+// resolveRuleDate 解析规则中的日期，today 表示当天
+func resolveRuleDate(s string) (time.Time, bool) {
+	if strings.EqualFold(strings.TrimSpace(s), "today") {
+		now := time.Now()
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local), true
+	}
+	return parseFormTime(s)
+}
+
+// formValidator 表单校验器，收集全部字段错误后一并返回
+type formValidator struct {
+	values  map[string]string // 表单全部原始值（级联选项取父级值）
+	errors  []FieldError
+	userIDs map[uint][]*model.FormField // 待校验存在性的用户ID
+}
+
+func (v *formValidator) add(field *model.FormField, message string) {
+	v.errors = append(v.errors, FieldError{Field: field.Name, Label: field.Label, Message: message})
+}
+
+// fail 规则校验失败，有自定义提示时使用自定义提示
+func (v *formValidator) fail(field *model.FormField, rules *FieldValidation, message string) {
+	if rules.Message != "" {
+		message = rules.Message
+	}
+	v.add(field, message)
+}
+
+// hiddenFormFields 按显示条件计算被隐藏的字段。隐藏字段的值视为空，
+// 依赖隐藏字段的条件随之重新计算，直到结果稳定
+func hiddenFormFields(fields []model.FormField, values map[string]string) map[string]bool {
+	hidden := make(map[string]bool)
+	lookup := func(name string) string {
+		if hidden[name] {
+			return ""
+		}
+		return values[name]
+	}
+	for round := 0; round <= len(fields); round++ {
+		changed := false
+		for i := range fields {
+			field := &fields[i]
+			if field.ShowCondition == "" {
+				continue
+			}
+			// 条件无效时按显示处理（与前端一致）
+			isHidden := !expr.Match(field.ShowCondition, lookup, true)
+			if hidden[field.Name] != isHidden {
+				hidden[field.Name] = isHidden
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+	return hidden
+}
+
+// validateFormValues 按字段定义校验表单原始值（values 为字段标识到原始字符串的映射），
+// hidden 中的隐藏字段不做必填及规则校验
+func validateFormValues(fields []model.FormField, values map[string]string, hidden map[string]bool, mode formValidateMode) error {
+	v := &formValidator{values: values, userIDs: make(map[uint][]*model.FormField)}
+	fieldMap := make(map[string]*model.FormField, len(fields))
+	for i := range fields {
+		fieldMap[fields[i].Name] = &fields[i]
+	}
+
+	if mode != formValidateSubmit {
+		for name := range values {
+			if _, ok := fieldMap[name]; !ok {
+				v.errors = append(v.errors, FieldError{Field: name, Label: name, Message: "未知字段"})
+			}
+		}
+	}
+
+	for i := range fields {
+		field := &fields[i]
+		raw, provided := values[field.Name]
+		if hidden[field.Name] || (mode == formValidateUpdate && !provided) {
+			continue
+		}
+		raw = unquoteFormValue(raw)
+		if raw == "" || raw == "[]" || raw == "null" {
+			// 附件在工单创建后单独上传，不校验必填
+			if field.Required && field.FieldType != model.FormFieldTypeAttachment {
+				v.add(field, "必填")
+			}
+			continue
+		}
+		rules, err := parseFieldValidation(field)
+		if err != nil {
+			rules = &FieldValidation{}
+		}
+		v.validateField(field, rules, raw)
+	}
+
+	v.checkUsers()
+	if len(v.errors) > 0 {
+		return &FormValidationError{Errors: v.errors}
+	}
+	return nil
+}
+
+// validateField 按字段类型校验单个非空值
+func (v *formValidator) validateField(field *model.FormField, rules *FieldValidation, raw string) {
+	switch field.FieldType {
+	case model.FormFieldTypeText, model.FormFieldTypeTextarea:
+		length := utf8.RuneCountInString(raw)
+		if rules.MinLength != nil && length < *rules.MinLength {
+			v.fail(field, rules, fmt.Sprintf("不能少于 %d 个字符", *rules.MinLength))
+		} else if rules.MaxLength != nil && length > *rules.MaxLength {
+			v.fail(field, rules, fmt.Sprintf("不能超过 %d 个字符", *rules.MaxLength))
+		} else if rules.Pattern != "" {
+			if re, err := regexp.Compile(rules.Pattern); err == nil && !re.MatchString(raw) {
+				v.fail(field, rules, "格式不正确")
+			}
+		}
+	case model.FormFieldTypeNumber, model.FormFieldTypeMoney, model.FormFieldTypeFormula:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+			v.add(field, "不是有效的数字")
+			return
+		}
+		if field.FieldType == model.FormFieldTypeMoney && math.Abs(n*100-math.Round(n*100)) > 1e-6 {
+			v.add(field, "金额最多保留两位小数")
+			return
+		}
+		if rules.Min != nil && n < *rules.Min {
+			v.fail(field, rules, fmt.Sprintf("不能小于 %s", strconv.FormatFloat(*rules.Min, 'f', -1, 64)))
+		} else if rules.Max != nil && n > *rules.Max {
+			v.fail(field, rules, fmt.Sprintf("不能大于 %s", strconv.FormatFloat(*rules.Max, 'f', -1, 64)))
+		}
+	case model.FormFieldTypeDate, model.FormFieldTypeDatetime:
+		t, ok := parseFormTime(raw)
+		if !ok {
+			v.add(field, "不是有效的日期")
+			return
+		}
+		if field.FieldType == model.FormFieldTypeDate {
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+		}
+		if min, ok := resolveRuleDate(rules.MinDate); ok && t.Before(min) {
+			v.fail(field, rules, "不能早于 "+min.Format("2006-01-02"))
+		} else if max, ok := resolveRuleDate(rules.MaxDate); ok && t.After(endOfRuleDate(rules.MaxDate, max)) {
+			v.fail(field, rules, "不能晚于 "+max.Format("2006-01-02"))
+		}
+	case model.FormFieldTypeSelect:
+		if !v.checkOptions(field, []string{raw}) {
+			return
+		}
+	case model.FormFieldTypeMultiSelect:
+		items := splitFormValues(raw)
+		if !v.checkOptions(field, items) {
+			return
+		}
+		v.checkItems(field, rules, len(items))
+	case model.FormFieldTypeTable:
+		v.validateTableRows(field, rules, raw)
+	case model.FormFieldTypeUser:
+		items := splitFormValues(raw)
+		for _, item := range items {
+			id, err := strconv.ParseUint(item, 10, 64)
+			if err != nil || id == 0 {
+				v.add(field, "用户无效: "+item)
+				return
+			}
+			v.userIDs[uint(id)] = append(v.userIDs[uint(id)], field)
+		}
+		v.checkItems(field, rules, len(items))
+	}
+}
+
+// endOfRuleDate 仅指定日期的最晚日期包含当天全天
+func endOfRuleDate(s string, t time.Time) time.Time {
+	if strings.EqualFold(strings.TrimSpace(s), "today") || len(strings.TrimSpace(s)) == len("2006-01-02") {
+		return t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	return t
+}
+
+// checkOptions 校验值均为字段选项（静态选项或动态来源解析结果；未配置选项时不校验）
+func (v *formValidator) checkOptions(field *model.FormField, items []string) bool {
+	src, _ := ParseOptionSource(field)
+	if src != nil && src.DependsOn != "" && unquoteFormValue(v.values[src.DependsOn]) == "" {
+		v.add(field, "请先选择"+src.DependsOn)
+		return false
+	}
+	options, err := FieldOptionsFor(field, v.values)
+	if err != nil {
+		v.add(field, "选项加载失败: "+err.Error())
+		return false
+	}
+	if src == nil && len(options) == 0 {
+		return true
+	}
+	allowed := make(map[string]bool, len(options))
+	for _, opt := range options {
+		allowed[opt.Value] = true
+	}
+	for _, item := range items {
+		if !allowed[item] {
+			v.add(field, "选项无效: "+item)
+			return false
+		}
+	}
+	return true
+}
+
+// checkItems 校验多值字段的选择数量
+func (v *formValidator) checkItems(field *model.FormField, rules *FieldValidation, n int) {
+	if rules.MinItems != nil && n < *rules.MinItems {
+		v.fail(field, rules, fmt.Sprintf("至少选择 %d 项", *rules.MinItems))
+	} else if rules.MaxItems != nil && n > *rules.MaxItems {
+		v.fail(field, rules, fmt.Sprintf("最多选择 %d 项", *rules.MaxItems))
+	}
+}
+
+// checkUsers 批量校验用户字段中的用户是否存在
+func (v *formValidator) checkUsers() {
+	if len(v.userIDs) == 0 {
+		return
+	}
+	var existing []uint
+	global.GetDB().Model(&model.User{}).Where("id IN ?", mapKeys(v.userIDs)).Pluck("id", &existing)
+	found := make(map[uint]bool, len(existing))
+	for _, id := range existing {
+		found[id] = true
+	}
+	reported := make(map[string]bool)
+	for id, fields := range v.userIDs {
+		if found[id] {
+			continue
+		}
+		for _, field := range fields {
+			if !reported[field.Name] {
+				reported[field.Name] = true
+				v.add(field, fmt.Sprintf("用户不存在: %d", id))
+			}
+		}
+	}
+}
+
+// loadTypeFormFields 获取工单类型关联表单模板的字段
+func loadTypeFormFields(db *gorm.DB, typeID uint) ([]model.FormField, error) {
+	var ticketType model.TicketType
+	if err := db.Select("id", "template_id").First(&ticketType, typeID).Error; err != nil {
+		return nil, err
+	}
+	if ticketType.TemplateID == nil {
+		return nil, nil
+	}
+	var fields []model.FormField
+	if err := db.Where("template_id = ?", *ticketType.TemplateID).Order("sort_order ASC").Find(&fields).Error; err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// formDataStrings 将前端提交的表单值转为原始字符串
+func formDataStrings(formData map[string]interface{}) map[string]string {
+	values := make(map[string]string, len(formData))
+	for name, value := range formData {
+		values[name] = formValueString(value)
+	}
+	return values
+}
+
+// storedFormValues 读取工单已保存的表单数据，按字段标识返回原始值
+func storedFormValues(ticketID uint) (map[string]string, error) {
+	var data []model.TicketData
+	if err := global.GetDB().Where("ticket_id = ?", ticketID).Find(&data).Error; err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(data))
+	if len(data) == 0 {
+		return values, nil
+	}
+	fieldIDs := make([]uint, 0, len(data))
+	for _, d := range data {
+		fieldIDs = append(fieldIDs, d.FieldID)
+	}
+	// 模板字段重新保存后旧字段被软删除，按字段标识匹配
+	var fields []model.FormField
+	if err := global.GetDB().Unscoped().Where("id IN ?", fieldIDs).Find(&fields).Error; err != nil {
+		return nil, err
+	}
+	names := make(map[uint]string, len(fields))
+	for _, f := range fields {
+		names[f.ID] = f.Name
+	}
+	for _, d := range data {
+		if name, ok := names[d.FieldID]; ok {
+			values[name] = d.Value
+		}
+	}
+	return values, nil
+}
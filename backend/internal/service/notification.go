@@ -2,7 +2,11 @@ package service
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
+	"time"
 
 	"backend/internal/model"
 	"backend/internal/global"
@@ -22,113 +26,167 @@ func NewNotificationService() *NotificationService {
 	}
 }
 
-// NotifyTicketCreated 工单创建通知
-func (s *NotificationService) NotifyTicketCreated(ticket *model.Ticket) {
-	var creator model.User
-	global.GetDB().First(&creator, ticket.CreatorID)
+// notifyMessage 一条通知的内容及关联的事件、工单；Vars 不为空时按渠道和语言渲染模板生成标题和内容
+type notifyMessage struct {
+	Event    string
+	TicketID *uint
+	Vars     *NotifyVars
+	Title    string
+	Content  string
+	HTML     bool
+	Actions  []notify.ActionButton // 消息卡片按钮（支持卡片的渠道使用）
+}
 
-	title := fmt.Sprintf("新工单: %s", ticket.Title)
-	content := fmt.Sprintf("工单编号: #%d\n创建人: %s\n优先级: %s\n\n%s",
-		ticket.ID, creator.Username, getPriorityText(ticket.Priority), ticket.Description)
+// ticketMessage 工单相关通知
+func ticketMessage(event string, ticket *model.Ticket, vars *NotifyVars) *notifyMessage {
+	return &notifyMessage{Event: event, TicketID: &ticket.ID, Vars: vars}
+}
 
-	s.sendNotification(title, content)
+// NotifyTicketCreated 工单创建通知
+func (s *NotificationService) NotifyTicketCreated(ticket *model.Ticket) {
+	vars := buildNotifyVars(model.NotificationEventTicketCreated, ticket)
+	s.sendNotification(ticketMessage(model.NotificationEventTicketCreated, ticket, vars))
 }
 
 // NotifyTicketApproved 工单审批通知
-func (s *NotificationService) NotifyTicketApproved(ticket *model.Ticket, approved bool, comment string) {
-	var creator model.User
-	global.GetDB().First(&creator, ticket.CreatorID)
-
-	status := "已通过"
+func (s *NotificationService) NotifyTicketApproved(ticket *model.Ticket, approverID uint, approved bool, comment string) {
+	vars := buildNotifyVars(model.NotificationEventTicketApproved, ticket)
+	vars.Approver = newUserNameCache().Get(approverID)
+	vars.Approved = approved
+	vars.Result = "已通过"
 	if !approved {
-		status = "已拒绝"
+		vars.Result = "已拒绝"
 	}
+	vars.Comment = comment
 
-	title := fmt.Sprintf("工单审批结果: %s", ticket.Title)
-	content := fmt.Sprintf("工单编号: #%d\n审批结果: %s\n审批意见: %s",
-		ticket.ID, status, comment)
-
-	s.sendToUser(creator.ID, title, content)
+	s.sendToUser(ticket.CreatorID, ticketMessage(model.NotificationEventTicketApproved, ticket, vars))
 }
 
 // NotifyTicketCompleted 工单完成通知
 func (s *NotificationService) NotifyTicketCompleted(ticket *model.Ticket) {
-	var creator model.User
-	global.GetDB().First(&creator, ticket.CreatorID)
-
-	title := fmt.Sprintf("工单已完成: %s", ticket.Title)
-	content := fmt.Sprintf("工单编号: #%d\n状态: 已完成", ticket.ID)
-
-	s.sendToUser(creator.ID, title, content)
+	vars := buildNotifyVars(model.NotificationEventTicketCompleted, ticket)
+	s.sendToUser(ticket.CreatorID, ticketMessage(model.NotificationEventTicketCompleted, ticket, vars))
 }
 
-// NotifyPendingApproval 待审批通知
+// NotifyPendingApproval 待审批通知（订阅了待审批摘要的用户不再逐条接收邮件）
 func (s *NotificationService) NotifyPendingApproval(ticket *model.Ticket, approverIDs []uint) {
-	title := fmt.Sprintf("待审批工单: %s", ticket.Title)
-	content := fmt.Sprintf("工单编号: #%d\n请及时处理", ticket.ID)
+	vars := buildNotifyVars(model.NotificationEventPendingApproval, ticket)
+	msg := ticketMessage(model.NotificationEventPendingApproval, ticket, vars)
 
+	digestUsers := pendingDigestSubscribers(approverIDs)
 	for _, userID := range approverIDs {
-		s.sendToUser(userID, title, content)
+		userMsg := withApprovalLinks(approverMessage(msg, ticket, userID), ticket, userID)
+		if digestUsers[userID] {
+			s.sendToUserExceptEmail(userID, userMsg)
+			continue
+		}
+		s.sendToUser(userID, userMsg)
 	}
 }
 
 // NotifyTicketCC 抄送通知
 func (s *NotificationService) NotifyTicketCC(ticket *model.Ticket, ccUserIDs []uint) {
-	var creator model.User
-	global.GetDB().First(&creator, ticket.CreatorID)
-
-	title := fmt.Sprintf("工单抄送: %s", ticket.Title)
-	content := fmt.Sprintf("工单编号: #%d\n创建人: %s\n您已被抄送此工单，请知悉。",
-		ticket.ID, creator.Username)
+	vars := buildNotifyVars(model.NotificationEventTicketCC, ticket)
+	msg := ticketMessage(model.NotificationEventTicketCC, ticket, vars)
 
 	for _, userID := range ccUserIDs {
-		s.sendToUser(userID, title, content)
+		s.sendToUser(userID, msg)
 	}
 }
 
 // NotifyTicketUrge 催办通知
 func (s *NotificationService) NotifyTicketUrge(ticket *model.Ticket, approverIDs []uint) {
-	var creator model.User
-	global.GetDB().First(&creator, ticket.CreatorID)
-
-	title := fmt.Sprintf("工单催办: %s", ticket.Title)
-	content := fmt.Sprintf("工单编号: #%d\n创建人: %s\n请尽快处理此工单！",
-		ticket.ID, creator.Username)
+	vars := buildNotifyVars(model.NotificationEventTicketUrge, ticket)
+	msg := ticketMessage(model.NotificationEventTicketUrge, ticket, vars)
 
 	for _, userID := range approverIDs {
-		s.sendToUser(userID, title, content)
+		s.sendToUser(userID, withApprovalLinks(approverMessage(msg, ticket, userID), ticket, userID))
+	}
+}
+
+// approverMessage 发给当前节点审批人的通知不包含节点上隐藏的字段（创建人除外）
+func approverMessage(msg *notifyMessage, ticket *model.Ticket, approverID uint) *notifyMessage {
+	if approverID == ticket.CreatorID || msg.Vars == nil {
+		return msg
+	}
+	hidden := nodeHiddenFields(ticket)
+	if len(hidden) == 0 {
+		return msg
+	}
+	vars := *msg.Vars
+	vars.Form = make([]NotifyFormValue, 0, len(msg.Vars.Form))
+	for _, f := range msg.Vars.Form {
+		if !hidden[f.Name] {
+			vars.Form = append(vars.Form, f)
+		}
+	}
+	vars.Fields = make(map[string]string, len(msg.Vars.Fields))
+	for name, value := range msg.Vars.Fields {
+		if !hidden[name] {
+			vars.Fields[name] = value
+		}
+	}
+	out := *msg
+	out.Vars = &vars
+	return &out
+}
+
+// sendNotification 发送通知（广播到钉钉群机器人和企业微信全员；钉钉企业内部应用只按用户发送，不广播）
+func (s *NotificationService) sendNotification(msg *notifyMessage) {
+	if cfg := s.dingTalkConfig(); cfg != nil && cfg.Mode != notify.DingTalkModeApp {
+		s.enqueue(model.NotificationChannelDingTalk, "", nil, renderMessage(msg, model.NotificationChannelDingTalk, "", ""), time.Time{})
 	}
+	s.enqueue(model.NotificationChannelWeChat, "@all", nil, renderMessage(msg, model.NotificationChannelWeChat, "", ""), time.Time{})
 }
 
-// sendNotification 发送通知（广播）
-func (s *NotificationService) sendNotification(title, content string) {
-	s.sendByEmail("", title, content)
-	s.sendByDingTalk(title, content)
-	s.sendByWeChat("@all", title, content)
+// sendToUser 按用户通知偏好发送通知给指定用户
+func (s *NotificationService) sendToUser(userID uint, msg *notifyMessage) {
+	s.notifyUser(userID, msg, false)
 }
 
-// sendToUser 发送通知给指定用户
-func (s *NotificationService) sendToUser(userID uint, title, content string) {
+// sendToUserExceptEmail 通过邮件以外的渠道通知指定用户
+func (s *NotificationService) sendToUserExceptEmail(userID uint, msg *notifyMessage) {
+	s.notifyUser(userID, msg, true)
+}
+
+// notifyUser 按用户偏好的渠道发送通知：未映射账号的渠道跳过，免打扰时段内的外部通知延后到时段结束
+func (s *NotificationService) notifyUser(userID uint, msg *notifyMessage, skipEmail bool) {
 	var user model.User
 	if err := global.GetDB().First(&user, userID).Error; err != nil {
 		return
 	}
-
-	if user.Email != "" {
-		s.sendByEmail(user.Email, title, content)
+	pref := loadUserPreference(userID)
+	sendAt := pref.deferUntil(time.Now())
+
+	recipients := map[string]string{
+		model.NotificationChannelEmail:    user.Email,
+		model.NotificationChannelDingTalk: user.DingTalkUserID,
+		model.NotificationChannelWeChat:   user.WeChatUserID,
+		model.NotificationChannelInApp:    user.Username,
+	}
+	for _, channel := range notificationChannels {
+		if skipEmail && channel == model.NotificationChannelEmail {
+			continue
+		}
+		if recipients[channel] == "" || !pref.wants(msg.Event, channel) {
+			continue
+		}
+		if channel == model.NotificationChannelInApp {
+			// 站内通知不打扰用户，直接写入
+			s.deliverInApp(user.ID, renderMessage(msg, channel, pref.locale(), user.Username))
+			continue
+		}
+		s.enqueue(channel, recipients[channel], &user.ID, renderMessage(msg, channel, pref.locale(), user.Username), sendAt)
 	}
-	s.sendByDingTalk(title, content)
-	s.sendByWeChat(user.Username, title, content)
 }
 
-// sendByEmail 发送邮件
-func (s *NotificationService) sendByEmail(to, title, content string) {
+// emailClient 根据系统配置创建邮件客户端，未启用邮件时返回 nil
+func (s *NotificationService) emailClient() *email.EmailClient {
 	cfg, err := s.configSvc.GetEmailConfig()
 	if err != nil || cfg == nil || !cfg.Enabled {
-		return
+		return nil
 	}
-
-	client := email.NewEmailClient(&email.EmailConfig{
+	return email.NewEmailClient(&email.EmailConfig{
 		Host:     cfg.Host,
 		Port:     cfg.Port,
 		Username: cfg.Username,
@@ -138,45 +196,71 @@ func (s *NotificationService) sendByEmail(to, title, content string) {
 		UseTLS:   cfg.UseTLS,
 		UseSSL:   cfg.UseSSL,
 	})
-	msg := &email.EmailMessage{
-		To:      []string{to},
-		Subject: title,
-		Body:    content,
-		IsHTML:  false,
+}
+
+// SendHTMLEmail 将 HTML 邮件写入发件箱，邮件通知未启用时返回错误
+func (s *NotificationService) SendHTMLEmail(to []string, subject, body string) error {
+	if s.emailClient() == nil {
+		return errors.New("邮件通知未启用")
 	}
-	client.Send(msg)
+	msg := &notifyMessage{Event: model.NotificationEventReport, Title: subject, Content: body, HTML: true}
+	return s.enqueueOutbox(model.NotificationChannelEmail, strings.Join(to, ","), nil, msg, time.Time{})
 }
 
-// sendByDingTalk 发送钉钉通知
-func (s *NotificationService) sendByDingTalk(title, content string) {
+// dingTalkConfig 读取钉钉通知配置，未配置或未启用时返回 nil
+func (s *NotificationService) dingTalkConfig() *notify.DingTalkConfig {
 	config, err := s.configSvc.GetByKey("notify.dingtalk")
 	if err != nil || config == nil || config.Value == "" {
-		return
+		return nil
 	}
 
 	var cfg notify.DingTalkConfig
-	if err := json.Unmarshal([]byte(config.Value), &cfg); err != nil {
-		return
+	if err := json.Unmarshal([]byte(config.Value), &cfg); err != nil || !cfg.Enabled {
+		return nil
+	}
+	return &cfg
+}
+
+// dingTalkAppNotifiers 按应用配置缓存的企业内部应用通知，保证 access_token 在多次发送间复用
+var (
+	dingTalkAppNotifiers   = make(map[string]*notify.DingTalkAppNotifier)
+	dingTalkAppNotifiersMu sync.Mutex
+)
+
+// dingTalkNotifier 根据系统配置创建钉钉通知（群机器人或企业内部应用），未配置或未启用时返回 nil
+func (s *NotificationService) dingTalkNotifier() notify.Notifier {
+	cfg := s.dingTalkConfig()
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Mode != notify.DingTalkModeApp {
+		return notify.NewDingTalkNotifier(cfg)
 	}
 
-	notifier := notify.NewDingTalkNotifier(&cfg)
-	notifier.Send("", title, content)
+	// 按完整配置缓存，任一配置变化时重新创建
+	key := fmt.Sprintf("%+v", *cfg)
+	dingTalkAppNotifiersMu.Lock()
+	defer dingTalkAppNotifiersMu.Unlock()
+	if n, ok := dingTalkAppNotifiers[key]; ok {
+		return n
+	}
+	n := notify.NewDingTalkAppNotifier(cfg)
+	dingTalkAppNotifiers[key] = n
+	return n
 }
 
-// sendByWeChat 发送企业微信通知
-func (s *NotificationService) sendByWeChat(to, title, content string) {
+// weChatNotifier 根据系统配置创建企业微信通知，未配置或未启用时返回 nil
+func (s *NotificationService) weChatNotifier() *notify.WeChatNotifier {
 	config, err := s.configSvc.GetByKey("notify.wechat")
 	if err != nil || config == nil || config.Value == "" {
-		return
+		return nil
 	}
 
 	var cfg notify.WeChatConfig
-	if err := json.Unmarshal([]byte(config.Value), &cfg); err != nil {
-		return
+	if err := json.Unmarshal([]byte(config.Value), &cfg); err != nil || !cfg.Enabled {
+		return nil
 	}
-
-	notifier := notify.NewWeChatNotifier(&cfg)
-	notifier.Send(to, title, content)
+	return notify.NewWeChatNotifier(&cfg)
 }
 
 // getPriorityText 获取优先级文本
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"backend/internal/global"
+	"backend/internal/model"
+	"backend/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+const (
+	inboxStreamBuffer    = 16               // 每个推送连接的缓冲消息数，缓冲满时丢弃（客户端可通过列表接口补齐）
+	inboxStreamTicketTTL = 60 * time.Second // 推送连接票据有效期
+)
+
+// notificationHub 站内通知实时推送：按用户维护订阅连接（仅推送到当前实例上的连接）
+type notificationHub struct {
+	mu          sync.RWMutex
+	subscribers map[uint]map[chan *model.UserNotification]struct{}
+}
+
+var inboxHub = &notificationHub{subscribers: make(map[uint]map[chan *model.UserNotification]struct{})}
+
+// subscribe 订阅用户的站内通知
+func (h *notificationHub) subscribe(userID uint) chan *model.UserNotification {
+	ch := make(chan *model.UserNotification, inboxStreamBuffer)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subscribers[userID] == nil {
+		h.subscribers[userID] = make(map[chan *model.UserNotification]struct{})
+	}
+	h.subscribers[userID][ch] = struct{}{}
+	return ch
+}
+
+// unsubscribe 取消订阅并关闭通道
+func (h *notificationHub) unsubscribe(userID uint, ch chan *model.UserNotification) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if subs, ok := h.subscribers[userID]; ok {
+		if _, ok := subs[ch]; ok {
+			delete(subs, ch)
+			close(ch)
+		}
+		if len(subs) == 0 {
+			delete(h.subscribers, userID)
+		}
+	}
+}
+
+// publish 推送通知到用户的所有连接，不阻塞发送方
+func (h *notificationHub) publish(n *model.UserNotification) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for ch := range h.subscribers[n.UserID] {
+		select {
+		case ch <- n:
+		default:
+		}
+	}
+}
+
+// deliverInApp 写入站内通知并实时推送
+func (s *NotificationService) deliverInApp(userID uint, msg *notifyMessage) {
+	title := []rune(msg.Title)
+	if len(title) > 255 {
+		title = title[:255]
+	}
+	n := &model.UserNotification{
+		UserID:   userID,
+		Event:    msg.Event,
+		TicketID: msg.TicketID,
+		Title:    string(title),
+		Content:  msg.Content,
+	}
+	if err := global.GetDB().Create(n).Error; err != nil {
+		logger.Warn("Failed to create in-app notification", zap.Uint("user_id", userID),
+			zap.String("event", msg.Event), zap.Error(err))
+		return
+	}
+	inboxHub.publish(n)
+}
+
+// SubscribeInApp 订阅当前用户的站内通知推送，返回通知通道和取消订阅函数
+func (s *NotificationService) SubscribeInApp(userID uint) (<-chan *model.UserNotification, func()) {
+	ch := inboxHub.subscribe(userID)
+	return ch, func() { inboxHub.unsubscribe(userID, ch) }
+}
+
+// IssueStreamTicket 为当前用户签发推送连接票据（短期有效、仅可使用一次），顺带清理过期票据
+func (s *NotificationService) IssueStreamTicket(userID uint) (*model.NotificationStreamTicket, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	global.GetDB().Where("expires_at < ?", now).Delete(&model.NotificationStreamTicket{})
+	t := &model.NotificationStreamTicket{
+		UserID:    userID,
+		Ticket:    hex.EncodeToString(buf),
+		ExpiresAt: now.Add(inboxStreamTicketTTL),
+	}
+	if err := global.GetDB().Create(t).Error; err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// RedeemStreamTicket 使用推送连接票据，返回签发票据的用户ID
+func (s *NotificationService) RedeemStreamTicket(ticket string) (uint, error) {
+	var t model.NotificationStreamTicket
+	if err := global.GetDB().Where("ticket = ?", ticket).First(&t).Error; err != nil {
+		return 0, errors.New("票据无效")
+	}
+	now := time.Now()
+	res := global.GetDB().Model(&model.NotificationStreamTicket{}).
+		Where("id = ? AND used_at IS NULL AND expires_at > ?", t.ID, now).
+		Update("used_at", now)
+	if res.Error != nil {
+		return 0, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return 0, errors.New("票据已使用或已过期")
+	}
+	return t.UserID, nil
+}
+
+// ListInApp 站内通知列表（新通知在前）
+func (s *NotificationService) ListInApp(userID uint, unreadOnly bool, page, pageSize int) ([]model.UserNotification, int64, error) {
+	var items []model.UserNotification
+	var total int64
+	db := global.GetDB().Model(&model.UserNotification{}).Where("user_id = ?", userID)
+	if unreadOnly {
+		db = db.Where("read_at IS NULL")
+	}
+	db.Count(&total)
+	if err := db.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&items).Error; err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+// UnreadCount 未读站内通知数
+func (s *NotificationService) UnreadCount(userID uint) (int64, error) {
+	var count int64
+	err := global.GetDB().Model(&model.UserNotification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
+
+// MarkRead 将当前用户的站内通知标记为已读
+func (s *NotificationService) MarkRead(userID, id uint) error {
+	var n model.UserNotification
+	if err := global.GetDB().Where("id = ? AND user_id = ?", id, userID).First(&n).Error; err != nil {
+		return errors.New("通知不存在")
+	}
+	if n.ReadAt != nil {
+		return nil
+	}
+	return global.GetDB().Model(&n).Update("read_at", time.Now()).Error
+}
+
+// MarkAllRead 将当前用户的全部站内通知标记为已读，返回标记数量
+func (s *NotificationService) MarkAllRead(userID uint) (int64, error) {
+	res := global.GetDB().Model(&model.UserNotification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).Update("read_at", time.Now())
+	return res.RowsAffected, res.Error
+}
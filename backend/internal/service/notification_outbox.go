@@ -0,0 +1,274 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"backend/internal/global"
+	"backend/internal/model"
+	"backend/pkg/email"
+	"backend/pkg/logger"
+	"backend/pkg/notify"
+
+	"go.uber.org/zap"
+)
+
+const (
+	outboxMaxAttempts    = 6               // 最多发送次数（含首次）
+	outboxBaseBackoff    = time.Minute     // 首次重试间隔，之后每次翻倍
+	outboxMaxBackoff     = time.Hour       // 最大重试间隔
+	outboxBatchSize      = 100             // 每轮最多发送的通知数
+	outboxSendingTimeout = 5 * time.Minute // 发送中状态超过该时长视为中断，重新发送
+)
+
+// outboxPayload 发件箱中保存的消息内容
+type outboxPayload struct {
+	Title   string                `json:"title"`
+	Content string                `json:"content"`
+	HTML    bool                  `json:"html,omitempty"`
+	Actions []notify.ActionButton `json:"actions,omitempty"`
+}
+
+// channelEnabled 渠道是否已启用（未启用的渠道不写入发件箱）
+func (s *NotificationService) channelEnabled(channel string) bool {
+	switch channel {
+	case model.NotificationChannelEmail:
+		return s.emailClient() != nil
+	case model.NotificationChannelDingTalk:
+		return s.dingTalkNotifier() != nil
+	case model.NotificationChannelWeChat:
+		return s.weChatNotifier() != nil
+	}
+	return false
+}
+
+// enqueue 将通知写入发件箱，渠道未启用时跳过（失败只记录日志，不影响业务流程）
+func (s *NotificationService) enqueue(channel, recipient string, userID *uint, msg *notifyMessage, sendAt time.Time) {
+	if !s.channelEnabled(channel) {
+		return
+	}
+	if err := s.enqueueOutbox(channel, recipient, userID, msg, sendAt); err != nil {
+		logger.Warn("Failed to enqueue notification", zap.String("channel", channel),
+			zap.String("event", msg.Event), zap.Error(err))
+	}
+}
+
+// enqueueOutbox 写入发件箱，sendAt 为空时立即发送
+func (s *NotificationService) enqueueOutbox(channel, recipient string, userID *uint, msg *notifyMessage, sendAt time.Time) error {
+	payload, err := json.Marshal(outboxPayload{Title: msg.Title, Content: msg.Content, HTML: msg.HTML, Actions: msg.Actions})
+	if err != nil {
+		return err
+	}
+	subject := []rune(msg.Title)
+	if len(subject) > 255 {
+		subject = subject[:255]
+	}
+	next := time.Now()
+	if sendAt.After(next) {
+		next = sendAt
+	}
+	return global.GetDB().Create(&model.NotificationOutbox{
+		Channel:       channel,
+		Recipient:     recipient,
+		UserID:        userID,
+		Event:         msg.Event,
+		TicketID:      msg.TicketID,
+		Subject:       string(subject),
+		Payload:       string(payload),
+		Status:        model.NotificationStatusPending,
+		NextAttemptAt: &next,
+	}).Error
+}
+
+// outboxBackoff 第 attempts 次失败后的重试间隔
+func outboxBackoff(attempts int) time.Duration {
+	delay := outboxBaseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= outboxMaxBackoff {
+			return outboxMaxBackoff
+		}
+	}
+	return delay
+}
+
+// RunPendingOutbox 发送到期的通知
+func (s *NotificationService) RunPendingOutbox() {
+	now := time.Now()
+	// 发送中断（如进程退出）的通知重新进入队列
+	global.GetDB().Model(&model.NotificationOutbox{}).
+		Where("status = ? AND updated_at < ?", model.NotificationStatusSending, now.Add(-outboxSendingTimeout)).
+		Update("status", model.NotificationStatusPending)
+
+	var items []model.NotificationOutbox
+	if err := global.GetDB().Where("status = ? AND next_attempt_at <= ?", model.NotificationStatusPending, now).
+		Order("id ASC").Limit(outboxBatchSize).Find(&items).Error; err != nil {
+		logger.Error("Failed to load notification outbox", zap.Error(err))
+		return
+	}
+	for i := range items {
+		// 抢占通知，避免多实例重复发送
+		res := global.GetDB().Model(&model.NotificationOutbox{}).
+			Where("id = ? AND status = ?", items[i].ID, model.NotificationStatusPending).
+			Update("status", model.NotificationStatusSending)
+		if res.Error != nil || res.RowsAffected == 0 {
+			continue
+		}
+		s.deliverOutbox(&items[i])
+	}
+}
+
+// deliverOutbox 发送一条通知并记录结果，失败时按指数退避安排重试
+func (s *NotificationService) deliverOutbox(item *model.NotificationOutbox) {
+	attempts := item.Attempts + 1
+	updates := map[string]interface{}{"attempts": attempts}
+	if err := s.dispatch(item); err != nil {
+		updates["last_error"] = err.Error()
+		if attempts >= outboxMaxAttempts {
+			updates["status"] = model.NotificationStatusFailed
+			updates["next_attempt_at"] = nil
+		} else {
+			next := time.Now().Add(outboxBackoff(attempts))
+			updates["status"] = model.NotificationStatusPending
+			updates["next_attempt_at"] = &next
+		}
+		logger.Warn("Notification delivery failed", zap.Uint("outbox_id", item.ID),
+			zap.String("channel", item.Channel), zap.Int("attempts", attempts), zap.Error(err))
+	} else {
+		now := time.Now()
+		updates["status"] = model.NotificationStatusSent
+		updates["last_error"] = ""
+		updates["sent_at"] = &now
+		updates["next_attempt_at"] = nil
+	}
+	global.GetDB().Model(item).Updates(updates)
+}
+
+// dispatch 按渠道发送通知
+func (s *NotificationService) dispatch(item *model.NotificationOutbox) error {
+	var payload outboxPayload
+	if err := json.Unmarshal([]byte(item.Payload), &payload); err != nil {
+		return fmt.Errorf("消息内容解析失败: %w", err)
+	}
+	switch item.Channel {
+	case model.NotificationChannelEmail:
+		client := s.emailClient()
+		if client == nil {
+			return errors.New("邮件通知未启用")
+		}
+		return client.Send(&email.EmailMessage{
+			To:      splitCSV(item.Recipient),
+			Subject: payload.Title,
+			Body:    payload.Content,
+			IsHTML:  payload.HTML,
+		})
+	case model.NotificationChannelDingTalk:
+		notifier := s.dingTalkNotifier()
+		if notifier == nil {
+			return errors.New("钉钉通知未启用")
+		}
+		if sender, ok := notifier.(notify.ActionCardSender); ok && len(payload.Actions) > 0 {
+			return sender.SendActionCard(item.Recipient, payload.Title, payload.Content, payload.Actions)
+		}
+		return notifier.Send(item.Recipient, payload.Title, payload.Content)
+	case model.NotificationChannelWeChat:
+		notifier := s.weChatNotifier()
+		if notifier == nil {
+			return errors.New("企业微信通知未启用")
+		}
+		return notifier.Send(item.Recipient, payload.Title, payload.Content)
+	}
+	return fmt.Errorf("不支持的通知渠道: %s", item.Channel)
+}
+
+// OutboxFilter 发件箱查询条件
+type OutboxFilter struct {
+	Status   string
+	Channel  string
+	Event    string
+	TicketID uint
+	UserID   uint
+}
+
+// ListOutbox 发件箱列表
+func (s *NotificationService) ListOutbox(f OutboxFilter, page, pageSize int) ([]model.NotificationOutbox, int64, error) {
+	var items []model.NotificationOutbox
+	var total int64
+	db := global.GetDB().Model(&model.NotificationOutbox{})
+	if f.Status != "" {
+		db = db.Where("status = ?", f.Status)
+	}
+	if f.Channel != "" {
+		db = db.Where("channel = ?", f.Channel)
+	}
+	if f.Event != "" {
+		db = db.Where("event = ?", f.Event)
+	}
+	if f.TicketID > 0 {
+		db = db.Where("ticket_id = ?", f.TicketID)
+	}
+	if f.UserID > 0 {
+		db = db.Where("user_id = ?", f.UserID)
+	}
+	db.Count(&total)
+	if err := db.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&items).Error; err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+// GetOutbox 发件箱通知详情
+func (s *NotificationService) GetOutbox(id uint) (*model.NotificationOutbox, error) {
+	var item model.NotificationOutbox
+	if err := global.GetDB().First(&item, id).Error; err != nil {
+		return nil, errors.New("通知不存在")
+	}
+	return &item, nil
+}
+
+// Resend 重新发送通知：重置尝试次数后立即进入队列（发送中的通知不可重发）
+func (s *NotificationService) Resend(id uint) error {
+	item, err := s.GetOutbox(id)
+	if err != nil {
+		return err
+	}
+	if item.Status == model.NotificationStatusSending {
+		return errors.New("通知正在发送中")
+	}
+	return global.GetDB().Model(item).Updates(resetOutboxUpdates()).Error
+}
+
+// ResendFailed 重新发送全部失败的通知（可按渠道过滤），返回重发数量
+func (s *NotificationService) ResendFailed(channel string) (int64, error) {
+	db := global.GetDB().Model(&model.NotificationOutbox{}).Where("status = ?", model.NotificationStatusFailed)
+	if channel != "" {
+		db = db.Where("channel = ?", channel)
+	}
+	res := db.Updates(resetOutboxUpdates())
+	return res.RowsAffected, res.Error
+}
+
+func resetOutboxUpdates() map[string]interface{} {
+	now := time.Now()
+	return map[string]interface{}{
+		"status":          model.NotificationStatusPending,
+		"attempts":        0,
+		"next_attempt_at": &now,
+		"last_error":      "",
+	}
+}
+
+// NotificationOutboxJob 发件箱发送定时任务
+type NotificationOutboxJob struct{}
+
+// Name 返回任务名称
+func (j *NotificationOutboxJob) Name() string {
+	return "notification_outbox"
+}
+
+// Run 发送到期的通知
+func (j *NotificationOutboxJob) Run() {
+	NewNotificationService().RunPendingOutbox()
+}
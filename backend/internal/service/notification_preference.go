@@ -0,0 +1,196 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"backend/internal/global"
+	"backend/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// notificationChannels 支持的通知渠道
+var notificationChannels = []string{
+	model.NotificationChannelEmail,
+	model.NotificationChannelDingTalk,
+	model.NotificationChannelWeChat,
+	model.NotificationChannelInApp,
+}
+
+// userNotificationEvents 可按用户配置接收渠道的通知事件
+var userNotificationEvents = []string{
+	model.NotificationEventTicketApproved,
+	model.NotificationEventTicketCompleted,
+	model.NotificationEventPendingApproval,
+	model.NotificationEventTicketCC,
+	model.NotificationEventTicketUrge,
+}
+
+// NotificationPreferenceView 用户通知偏好
+type NotificationPreferenceView struct {
+	EventChannels  map[string][]string `json:"event_channels"` // 各事件接收的渠道
+	QuietStart     string              `json:"quiet_start"`
+	QuietEnd       string              `json:"quiet_end"`
+	Locale         string              `json:"locale"`           // 通知语言，为空时使用默认语言
+	DingTalkUserID string              `json:"dingtalk_user_id"` // 钉钉用户ID（由管理员维护）
+	WeChatUserID   string              `json:"wechat_user_id"`   // 企业微信用户ID（由管理员维护）
+	Events         []string            `json:"events"`           // 可配置的事件
+	Channels       []string            `json:"channels"`         // 可选的渠道
+}
+
+// userPreference 发送时使用的用户通知偏好
+type userPreference struct {
+	eventChannels map[string][]string
+	quietStart    string
+	quietEnd      string
+	userLocale    string
+}
+
+// loadUserPreference 读取用户通知偏好，未配置时返回 nil（接收全部渠道、无免打扰）
+func loadUserPreference(userID uint) *userPreference {
+	var pref model.NotificationPreference
+	if err := global.GetDB().Where("user_id = ?", userID).First(&pref).Error; err != nil {
+		return nil
+	}
+	p := &userPreference{quietStart: pref.QuietStart, quietEnd: pref.QuietEnd, userLocale: pref.Locale}
+	if pref.EventChannels != "" {
+		json.Unmarshal([]byte(pref.EventChannels), &p.eventChannels)
+	}
+	return p
+}
+
+// wants 用户是否通过指定渠道接收该事件
+func (p *userPreference) wants(event, channel string) bool {
+	if p == nil {
+		return true
+	}
+	channels, ok := p.eventChannels[event]
+	if !ok {
+		return true
+	}
+	return containsString(channels, channel)
+}
+
+// locale 用户的通知语言，未设置时为空（使用默认语言）
+func (p *userPreference) locale() string {
+	if p == nil {
+		return ""
+	}
+	return p.userLocale
+}
+
+// deferUntil 当前处于免打扰时段时返回时段结束时间，否则返回零值
+func (p *userPreference) deferUntil(now time.Time) time.Time {
+	if p == nil || p.quietStart == "" || p.quietEnd == "" || p.quietStart == p.quietEnd {
+		return time.Time{}
+	}
+	start, err1 := time.Parse("15:04", p.quietStart)
+	end, err2 := time.Parse("15:04", p.quietEnd)
+	if err1 != nil || err2 != nil {
+		return time.Time{}
+	}
+	minute := now.Hour()*60 + now.Minute()
+	startMin := start.Hour()*60 + start.Minute()
+	endMin := end.Hour()*60 + end.Minute()
+	var quiet bool
+	if startMin < endMin {
+		quiet = minute >= startMin && minute < endMin
+	} else {
+		quiet = minute >= startMin || minute < endMin
+	}
+	if !quiet {
+		return time.Time{}
+	}
+	until := time.Date(now.Year(), now.Month(), now.Day(), end.Hour(), end.Minute(), 0, 0, now.Location())
+	if !until.After(now) {
+		until = until.AddDate(0, 0, 1)
+	}
+	return until
+}
+
+// GetPreference 获取用户通知偏好（未配置时返回默认值）
+func (s *NotificationService) GetPreference(userID uint) (*NotificationPreferenceView, error) {
+	var user model.User
+	if err := global.GetDB().First(&user, userID).Error; err != nil {
+		return nil, errors.New("用户不存在")
+	}
+	view := &NotificationPreferenceView{
+		EventChannels:  make(map[string][]string, len(userNotificationEvents)),
+		DingTalkUserID: user.DingTalkUserID,
+		WeChatUserID:   user.WeChatUserID,
+		Events:         userNotificationEvents,
+		Channels:       notificationChannels,
+	}
+	pref := loadUserPreference(userID)
+	if pref != nil {
+		view.QuietStart = pref.quietStart
+		view.QuietEnd = pref.quietEnd
+		view.Locale = pref.userLocale
+	}
+	for _, event := range userNotificationEvents {
+		channels := []string{}
+		for _, channel := range notificationChannels {
+			if pref.wants(event, channel) {
+				channels = append(channels, channel)
+			}
+		}
+		view.EventChannels[event] = channels
+	}
+	return view, nil
+}
+
+// SavePreference 保存用户通知偏好
+func (s *NotificationService) SavePreference(userID uint, eventChannels map[string][]string, quietStart, quietEnd, locale string) error {
+	for event, channels := range eventChannels {
+		if !containsString(userNotificationEvents, event) {
+			return fmt.Errorf("不支持的通知事件: %s", event)
+		}
+		for _, channel := range channels {
+			if !containsString(notificationChannels, channel) {
+				return fmt.Errorf("不支持的通知渠道: %s", channel)
+			}
+		}
+	}
+	if (quietStart == "") != (quietEnd == "") {
+		return errors.New("免打扰开始和结束时间需同时设置")
+	}
+	for _, t := range []string{quietStart, quietEnd} {
+		if t == "" {
+			continue
+		}
+		if _, err := time.Parse("15:04", t); err != nil || len(t) != 5 {
+			return fmt.Errorf("免打扰时间格式错误: %s（应为 HH:MM）", t)
+		}
+	}
+	if len(locale) > 10 {
+		return fmt.Errorf("不支持的通知语言: %s", locale)
+	}
+	data, err := json.Marshal(eventChannels)
+	if err != nil {
+		return err
+	}
+
+	var pref model.NotificationPreference
+	err = global.GetDB().Where("user_id = ?", userID).First(&pref).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return global.GetDB().Create(&model.NotificationPreference{
+			UserID:        userID,
+			EventChannels: string(data),
+			QuietStart:    quietStart,
+			QuietEnd:      quietEnd,
+			Locale:        locale,
+		}).Error
+	}
+	if err != nil {
+		return err
+	}
+	return global.GetDB().Model(&pref).Updates(map[string]interface{}{
+		"event_channels": string(data),
+		"quiet_start":    quietStart,
+		"quiet_end":      quietEnd,
+		"locale":         locale,
+	}).Error
+}
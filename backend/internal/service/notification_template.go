@@ -0,0 +1,440 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	"text/template"
+	"time"
+
+	"backend/internal/config"
+	"backend/internal/global"
+	"backend/internal/model"
+	"backend/pkg/logger"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// defaultNotificationLocale 默认通知语言
+const defaultNotificationLocale = "zh-CN"
+
+// templateNotificationEvents 可定制模板的通知事件
+var templateNotificationEvents = append([]string{model.NotificationEventTicketCreated}, userNotificationEvents...)
+
+// NotifyTicket 通知模板中的工单信息
+type NotifyTicket struct {
+	ID          uint      `json:"id"`
+	Number      string    `json:"number"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Type        string    `json:"type"`
+	Status      string    `json:"status"`      // 状态标识
+	StatusText  string    `json:"status_text"` // 状态显示名称
+	Priority    string    `json:"priority"`    // 优先级显示名称
+	Creator     string    `json:"creator"`
+	CurrentNode string    `json:"current_node"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// NotifyFormValue 通知模板中的表单字段值
+type NotifyFormValue struct {
+	Name  string `json:"name"`
+	Label string `json:"label"`
+	Value string `json:"value"` // 显示值
+}
+
+// NotifyVars 通知模板变量
+type NotifyVars struct {
+	Event       string            `json:"event"`
+	Ticket      NotifyTicket      `json:"ticket"`
+	Form        []NotifyFormValue `json:"form"`         // 按字段顺序排列的表单数据
+	Fields      map[string]string `json:"fields"`       // 字段标识 -> 显示值
+	Approver    string            `json:"approver"`     // 审批人（审批结果通知）
+	Approved    bool              `json:"approved"`     // 是否通过（审批结果通知）
+	Result      string            `json:"result"`       // 审批结果显示名称
+	Comment     string            `json:"comment"`      // 审批意见
+	Recipient   string            `json:"recipient"`    // 接收人用户名，广播时为空
+	Link        string            `json:"link"`         // 工单详情链接，未配置 server.base_url 时为空
+	ApproveLink string            `json:"approve_link"` // 免登录通过链接（待审批、催办通知，一次性）
+	RejectLink  string            `json:"reject_link"`  // 免登录拒绝链接
+}
+
+// notifyTemplate 通知模板内容
+type notifyTemplate struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+const (
+	notifyLinkLine   = "{{if .Link}}\n\n查看工单: {{.Link}}{{end}}"
+	notifyActionLine = "{{if .ApproveLink}}\n\n通过: {{.ApproveLink}}\n拒绝: {{.RejectLink}}{{end}}"
+)
+
+// builtinNotifyTemplates 内置通知模板（未配置自定义模板时使用，各渠道通用）
+var builtinNotifyTemplates = map[string]notifyTemplate{
+	model.NotificationEventTicketCreated: {
+		Subject: "新工单: {{.Ticket.Title}}",
+		Body:    "工单编号: {{.Ticket.Number}}\n创建人: {{.Ticket.Creator}}\n优先级: {{.Ticket.Priority}}\n\n{{.Ticket.Description}}" + notifyLinkLine,
+	},
+	model.NotificationEventTicketApproved: {
+		Subject: "工单审批结果: {{.Ticket.Title}}",
+		Body:    "工单编号: {{.Ticket.Number}}\n审批结果: {{.Result}}\n审批意见: {{.Comment}}" + notifyLinkLine,
+	},
+	model.NotificationEventTicketCompleted: {
+		Subject: "工单已完成: {{.Ticket.Title}}",
+		Body:    "工单编号: {{.Ticket.Number}}\n状态: 已完成" + notifyLinkLine,
+	},
+	model.NotificationEventPendingApproval: {
+		Subject: "待审批工单: {{.Ticket.Title}}",
+		Body:    "工单编号: {{.Ticket.Number}}\n请及时处理" + notifyLinkLine + notifyActionLine,
+	},
+	model.NotificationEventTicketCC: {
+		Subject: "工单抄送: {{.Ticket.Title}}",
+		Body:    "工单编号: {{.Ticket.Number}}\n创建人: {{.Ticket.Creator}}\n您已被抄送此工单，请知悉。" + notifyLinkLine,
+	},
+	model.NotificationEventTicketUrge: {
+		Subject: "工单催办: {{.Ticket.Title}}",
+		Body:    "工单编号: {{.Ticket.Number}}\n创建人: {{.Ticket.Creator}}\n请尽快处理此工单！" + notifyLinkLine + notifyActionLine,
+	},
+}
+
+// publicBaseURL 对外访问地址，未配置时为空
+func publicBaseURL() string {
+	cfg := config.Get()
+	if cfg == nil {
+		return ""
+	}
+	return strings.TrimRight(cfg.Server.BaseURL, "/")
+}
+
+// ticketLink 工单详情页链接
+func ticketLink(ticketID uint) string {
+	base := publicBaseURL()
+	if base == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/ticket/%d", base, ticketID)
+}
+
+// buildNotifyVars 根据工单构建通知模板变量
+func buildNotifyVars(event string, ticket *model.Ticket) *NotifyVars {
+	db := global.GetDB()
+	users := newUserNameCache()
+	vars := &NotifyVars{
+		Event: event,
+		Ticket: NotifyTicket{
+			ID:          ticket.ID,
+			Number:      ticketDisplayNumber(ticket),
+			Title:       ticket.Title,
+			Description: ticket.Description,
+			Status:      ticket.Status,
+			StatusText:  ticketStatusLabels[ticket.Status],
+			Priority:    getPriorityText(ticket.Priority),
+			Creator:     users.Get(ticket.CreatorID),
+			CreatedAt:   ticket.CreatedAt,
+		},
+		Form:   []NotifyFormValue{},
+		Fields: map[string]string{},
+		Link:   ticketLink(ticket.ID),
+	}
+	var ticketType model.TicketType
+	if db.Select("id", "name").First(&ticketType, ticket.TypeID).Error == nil {
+		vars.Ticket.Type = ticketType.Name
+	}
+	if ticket.CurrentNodeID != nil {
+		var node model.FlowNode
+		if db.Select("id", "name").First(&node, *ticket.CurrentNodeID).Error == nil {
+			vars.Ticket.CurrentNode = node.Name
+		}
+	}
+
+	fields, err := loadTicketFormFields(db, ticket)
+	if err != nil {
+		return vars
+	}
+	stored, err := storedFormValues(ticket.ID)
+	if err != nil {
+		return vars
+	}
+	for i := range fields {
+		raw, ok := stored[fields[i].Name]
+		if !ok {
+			continue
+		}
+		value := displayFormValue(&fields[i], raw, users)
+		vars.Form = append(vars.Form, NotifyFormValue{Name: fields[i].Name, Label: fields[i].Label, Value: value})
+		vars.Fields[fields[i].Name] = value
+	}
+	return vars
+}
+
+// sampleNotifyVars 预览模板使用的示例数据
+func sampleNotifyVars(event string) *NotifyVars {
+	vars := &NotifyVars{
+		Event: event,
+		Ticket: NotifyTicket{
+			ID:          1,
+			Number:      "T20240101-0001",
+			Title:       "申请开通测试环境权限",
+			Description: "因项目联调需要，申请开通测试环境数据库只读权限。",
+			Type:        "权限申请",
+			Status:      model.TicketStatusPending,
+			StatusText:  ticketStatusLabels[model.TicketStatusPending],
+			Priority:    getPriorityText(model.TicketPriorityHigh),
+			Creator:     "zhangsan",
+			CurrentNode: "部门经理审批",
+			CreatedAt:   time.Now(),
+		},
+		Form: []NotifyFormValue{
+			{Name: "env", Label: "环境", Value: "测试环境"},
+			{Name: "reason", Label: "申请原因", Value: "项目联调"},
+		},
+		Fields:    map[string]string{"env": "测试环境", "reason": "项目联调"},
+		Approver:  "lisi",
+		Approved:  true,
+		Result:    "已通过",
+		Comment:   "同意",
+		Recipient: "zhangsan",
+		Link:      ticketLink(1),
+	}
+	if base := publicBaseURL(); base != "" && (event == model.NotificationEventPendingApproval || event == model.NotificationEventTicketUrge) {
+		vars.ApproveLink = base + "/api/v1/approval-actions?token=sample-approve"
+		vars.RejectLink = base + "/api/v1/approval-actions?token=sample-reject"
+	}
+	return vars
+}
+
+// renderNotifyTemplate 渲染通知模板：标题使用 text/template，邮件内容使用 html/template（自动转义），其他渠道使用 text/template
+func renderNotifyTemplate(channel, subject, body string, vars *NotifyVars) (string, string, error) {
+	var subj bytes.Buffer
+	st, err := template.New("subject").Option("missingkey=zero").Parse(subject)
+	if err != nil {
+		return "", "", fmt.Errorf("标题模板错误: %w", err)
+	}
+	if err := st.Execute(&subj, vars); err != nil {
+		return "", "", fmt.Errorf("标题模板错误: %w", err)
+	}
+
+	var content bytes.Buffer
+	if channel == model.NotificationChannelEmail {
+		bt, err := htmltemplate.New("body").Option("missingkey=zero").Parse(body)
+		if err != nil {
+			return "", "", fmt.Errorf("内容模板错误: %w", err)
+		}
+		if err := bt.Execute(&content, vars); err != nil {
+			return "", "", fmt.Errorf("内容模板错误: %w", err)
+		}
+	} else {
+		bt, err := template.New("body").Option("missingkey=zero").Parse(body)
+		if err != nil {
+			return "", "", fmt.Errorf("内容模板错误: %w", err)
+		}
+		if err := bt.Execute(&content, vars); err != nil {
+			return "", "", fmt.Errorf("内容模板错误: %w", err)
+		}
+	}
+	return strings.TrimSpace(subj.String()), content.String(), nil
+}
+
+// findNotifyTemplate 查找启用的自定义模板：优先匹配语言，其次默认语言
+func findNotifyTemplate(event, channel, locale string) *model.NotificationTemplate {
+	locales := []string{defaultNotificationLocale}
+	if locale != "" && locale != defaultNotificationLocale {
+		locales = []string{locale, defaultNotificationLocale}
+	}
+	var templates []model.NotificationTemplate
+	if err := global.GetDB().Where("event = ? AND channel = ? AND locale IN ? AND enabled = ?", event, channel, locales, true).
+		Find(&templates).Error; err != nil {
+		return nil
+	}
+	for _, l := range locales {
+		for i := range templates {
+			if templates[i].Locale == l {
+				return &templates[i]
+			}
+		}
+	}
+	return nil
+}
+
+// renderMessage 按渠道和语言渲染通知内容；自定义模板渲染失败时退回内置模板
+func renderMessage(msg *notifyMessage, channel, locale, recipient string) *notifyMessage {
+	if msg.Vars == nil {
+		return msg
+	}
+	vars := *msg.Vars
+	vars.Recipient = recipient
+	out := *msg
+	if tpl := findNotifyTemplate(msg.Event, channel, locale); tpl != nil {
+		subject, body, err := renderNotifyTemplate(channel, tpl.Subject, tpl.Body, &vars)
+		if err == nil {
+			out.Title, out.Content, out.HTML = subject, body, channel == model.NotificationChannelEmail
+			return &out
+		}
+		logger.Warn("Failed to render notification template", zap.Uint("template_id", tpl.ID), zap.Error(err))
+	}
+	builtin := builtinNotifyTemplates[msg.Event]
+	subject, body, err := renderNotifyTemplate("", builtin.Subject, builtin.Body, &vars)
+	if err != nil {
+		logger.Warn("Failed to render builtin notification template", zap.String("event", msg.Event), zap.Error(err))
+	}
+	out.Title, out.Content, out.HTML = subject, body, false
+	return &out
+}
+
+// NotificationTemplateService 通知模板服务
+type NotificationTemplateService struct{}
+
+// NewNotificationTemplateService 创建通知模板服务
+func NewNotificationTemplateService() *NotificationTemplateService {
+	return &NotificationTemplateService{}
+}
+
+// BuiltinTemplate 内置模板
+type BuiltinTemplate struct {
+	Event   string `json:"event"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// NotificationPreview 模板预览结果
+type NotificationPreview struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+	HTML    bool   `json:"html"`
+}
+
+// List 通知模板列表
+func (s *NotificationTemplateService) List(event, channel, locale string) ([]model.NotificationTemplate, error) {
+	var templates []model.NotificationTemplate
+	db := global.GetDB().Model(&model.NotificationTemplate{})
+	if event != "" {
+		db = db.Where("event = ?", event)
+	}
+	if channel != "" {
+		db = db.Where("channel = ?", channel)
+	}
+	if locale != "" {
+		db = db.Where("locale = ?", locale)
+	}
+	if err := db.Order("event ASC, channel ASC, locale ASC").Find(&templates).Error; err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// Get 通知模板详情
+func (s *NotificationTemplateService) Get(id uint) (*model.NotificationTemplate, error) {
+	var tpl model.NotificationTemplate
+	if err := global.GetDB().First(&tpl, id).Error; err != nil {
+		return nil, errors.New("通知模板不存在")
+	}
+	return &tpl, nil
+}
+
+// Builtins 内置模板列表，可作为自定义模板的起点
+func (s *NotificationTemplateService) Builtins() []BuiltinTemplate {
+	list := make([]BuiltinTemplate, 0, len(templateNotificationEvents))
+	for _, event := range templateNotificationEvents {
+		tpl := builtinNotifyTemplates[event]
+		list = append(list, BuiltinTemplate{Event: event, Subject: tpl.Subject, Body: tpl.Body})
+	}
+	return list
+}
+
+// validate 校验模板配置，并用示例数据试渲染
+func (s *NotificationTemplateService) validate(tpl *model.NotificationTemplate, excludeID uint) error {
+	if !containsString(templateNotificationEvents, tpl.Event) {
+		return fmt.Errorf("不支持的通知事件: %s", tpl.Event)
+	}
+	if !containsString(notificationChannels, tpl.Channel) {
+		return fmt.Errorf("不支持的通知渠道: %s", tpl.Channel)
+	}
+	tpl.Locale = strings.TrimSpace(tpl.Locale)
+	if tpl.Locale == "" {
+		tpl.Locale = defaultNotificationLocale
+	}
+	if strings.TrimSpace(tpl.Subject) == "" || strings.TrimSpace(tpl.Body) == "" {
+		return errors.New("标题和内容模板不能为空")
+	}
+	if _, _, err := renderNotifyTemplate(tpl.Channel, tpl.Subject, tpl.Body, sampleNotifyVars(tpl.Event)); err != nil {
+		return err
+	}
+	var count int64
+	global.GetDB().Model(&model.NotificationTemplate{}).
+		Where("event = ? AND channel = ? AND locale = ? AND id != ?", tpl.Event, tpl.Channel, tpl.Locale, excludeID).
+		Count(&count)
+	if count > 0 {
+		return errors.New("该事件、渠道和语言的模板已存在")
+	}
+	return nil
+}
+
+// Create 创建通知模板
+func (s *NotificationTemplateService) Create(tpl *model.NotificationTemplate) error {
+	if err := s.validate(tpl, 0); err != nil {
+		return err
+	}
+	return global.GetDB().Create(tpl).Error
+}
+
+// Update 更新通知模板
+func (s *NotificationTemplateService) Update(id uint, tpl *model.NotificationTemplate) error {
+	existing, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+	if err := s.validate(tpl, id); err != nil {
+		return err
+	}
+	return global.GetDB().Model(existing).Select("event", "channel", "locale", "subject", "body", "enabled", "updated_by_id").
+		Updates(tpl).Error
+}
+
+// Delete 删除通知模板
+func (s *NotificationTemplateService) Delete(id uint) error {
+	return global.GetDB().Delete(&model.NotificationTemplate{}, id).Error
+}
+
+// Preview 预览模板：指定工单时使用工单数据，否则使用示例数据；标题和内容为空时预览内置模板
+func (s *NotificationTemplateService) Preview(event, channel, subject, body string, ticketID uint) (*NotificationPreview, error) {
+	if !containsString(templateNotificationEvents, event) {
+		return nil, fmt.Errorf("不支持的通知事件: %s", event)
+	}
+	if !containsString(notificationChannels, channel) {
+		return nil, fmt.Errorf("不支持的通知渠道: %s", channel)
+	}
+	html := channel == model.NotificationChannelEmail
+	if strings.TrimSpace(subject) == "" && strings.TrimSpace(body) == "" {
+		builtin := builtinNotifyTemplates[event]
+		subject, body, html = builtin.Subject, builtin.Body, false
+	}
+
+	vars := sampleNotifyVars(event)
+	if ticketID > 0 {
+		var ticket model.Ticket
+		if err := global.GetDB().First(&ticket, ticketID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, errors.New("工单不存在")
+			}
+			return nil, err
+		}
+		sample := vars
+		vars = buildNotifyVars(event, &ticket)
+		vars.Approver, vars.Approved, vars.Result, vars.Comment = sample.Approver, sample.Approved, sample.Result, sample.Comment
+		vars.Recipient = sample.Recipient
+	}
+
+	renderChannel := channel
+	if !html {
+		renderChannel = ""
+	}
+	subj, content, err := renderNotifyTemplate(renderChannel, subject, body, vars)
+	if err != nil {
+		return nil, err
+	}
+	return &NotificationPreview{Subject: subj, Body: content, HTML: html}, nil
+}
@@ -0,0 +1,435 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"backend/internal/global"
+	"backend/internal/model"
+	"backend/pkg/email"
+	"backend/pkg/logger"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 报表中最多列出的工单数
+const reportTicketLimit = 50
+
+// ReportSubscriptionService 定时报表订阅服务
+type ReportSubscriptionService struct {
+	ticketSvc *TicketService
+	notifySvc *NotificationService
+}
+
+// NewReportSubscriptionService 创建定时报表订阅服务
+func NewReportSubscriptionService() *ReportSubscriptionService {
+	return &ReportSubscriptionService{
+		ticketSvc: NewTicketService(),
+		notifySvc: NewNotificationService(),
+	}
+}
+
+// validate 校验订阅配置并规范化收件人
+func (s *ReportSubscriptionService) validate(sub *model.ReportSubscription) error {
+	switch sub.ReportType {
+	case model.ReportTypeTicketSummary, model.ReportTypePendingDigest:
+	default:
+		return fmt.Errorf("不支持的报表类型: %s", sub.ReportType)
+	}
+	switch sub.Frequency {
+	case model.ReportFrequencyDaily, model.ReportFrequencyWeekly:
+	default:
+		return fmt.Errorf("不支持的发送频率: %s", sub.Frequency)
+	}
+	if sub.Weekday < 0 || sub.Weekday > 6 {
+		return errors.New("每周发送日必须在 0-6 之间")
+	}
+	if sub.SendHour < 0 || sub.SendHour > 23 {
+		return errors.New("发送时刻必须在 0-23 之间")
+	}
+	if sub.ReportType == model.ReportTypePendingDigest {
+		// 待审批摘要只发送给订阅人本人
+		sub.Filter = ""
+		sub.Recipients = ""
+	}
+	if _, err := ParseTicketFilter(sub.Filter); err != nil {
+		return err
+	}
+	recipients, err := parseRecipients(sub.Recipients)
+	if err != nil {
+		return err
+	}
+	sub.Recipients = strings.Join(recipients, ",")
+	return nil
+}
+
+// parseRecipients 解析逗号分隔的收件人邮箱
+func parseRecipients(raw string) ([]string, error) {
+	var result []string
+	for _, item := range strings.Split(raw, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		addr, err := mail.ParseAddress(item)
+		if err != nil {
+			return nil, fmt.Errorf("收件人邮箱格式错误: %s", item)
+		}
+		result = append(result, addr.Address)
+	}
+	return result, nil
+}
+
+// nextReportRun 计算 after 之后的下一次发送时间
+func nextReportRun(sub *model.ReportSubscription, after time.Time) time.Time {
+	next := time.Date(after.Year(), after.Month(), after.Day(), sub.SendHour, 0, 0, 0, after.Location())
+	for !next.After(after) || (sub.Frequency == model.ReportFrequencyWeekly && int(next.Weekday()) != sub.Weekday) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// Create 创建订阅
+func (s *ReportSubscriptionService) Create(sub *model.ReportSubscription) error {
+	if err := s.validate(sub); err != nil {
+		return err
+	}
+	next := nextReportRun(sub, time.Now())
+	sub.NextRunAt = &next
+	return global.GetDB().Create(sub).Error
+}
+
+// Update 更新订阅，仅所有者或管理员可操作
+func (s *ReportSubscriptionService) Update(id, userID uint, isAdmin bool, sub *model.ReportSubscription) error {
+	existing, err := s.getOwned(id, userID, isAdmin)
+	if err != nil {
+		return err
+	}
+	if err := s.validate(sub); err != nil {
+		return err
+	}
+	next := nextReportRun(sub, time.Now())
+	sub.NextRunAt = &next
+	return global.GetDB().Model(existing).
+		Select("Name", "ReportType", "Frequency", "Weekday", "SendHour", "Filter", "Recipients", "Enabled", "NextRunAt").
+		Updates(sub).Error
+}
+
+// Delete 删除订阅，仅所有者或管理员可操作
+func (s *ReportSubscriptionService) Delete(id, userID uint, isAdmin bool) error {
+	sub, err := s.getOwned(id, userID, isAdmin)
+	if err != nil {
+		return err
+	}
+	return global.GetDB().Delete(sub).Error
+}
+
+// List 列出用户的订阅
+func (s *ReportSubscriptionService) List(userID uint) ([]model.ReportSubscription, error) {
+	var subs []model.ReportSubscription
+	if err := global.GetDB().Where("owner_id = ?", userID).Order("created_at DESC").Find(&subs).Error; err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+// Get 获取订阅详情，仅所有者或管理员可查看
+func (s *ReportSubscriptionService) Get(id, userID uint, isAdmin bool) (*model.ReportSubscription, error) {
+	return s.getOwned(id, userID, isAdmin)
+}
+
+func (s *ReportSubscriptionService) getOwned(id, userID uint, isAdmin bool) (*model.ReportSubscription, error) {
+	var sub model.ReportSubscription
+	if err := global.GetDB().First(&sub, id).Error; err != nil {
+		return nil, errors.New("订阅不存在")
+	}
+	if !isAdmin && sub.OwnerID != userID {
+		return nil, errors.New("只能操作自己的订阅")
+	}
+	return &sub, nil
+}
+
+// SendNow 立即发送一次报表（不影响下次定时发送时间）
+func (s *ReportSubscriptionService) SendNow(id, userID uint, isAdmin bool) error {
+	sub, err := s.getOwned(id, userID, isAdmin)
+	if err != nil {
+		return err
+	}
+	return s.send(sub, time.Now())
+}
+
+// RunDueSubscriptions 发送所有到期的报表
+func (s *ReportSubscriptionService) RunDueSubscriptions() {
+	now := time.Now()
+	var subs []model.ReportSubscription
+	if err := global.GetDB().Where("enabled = ? AND next_run_at <= ?", true, now).Find(&subs).Error; err != nil {
+		logger.Error("Failed to load due report subscriptions", zap.Error(err))
+		return
+	}
+	for i := range subs {
+		sub := &subs[i]
+		lastError := ""
+		if err := s.send(sub, now); err != nil {
+			lastError = err.Error()
+			logger.Warn("Report subscription failed", zap.Uint("subscription_id", sub.ID), zap.Error(err))
+		}
+		next := nextReportRun(sub, now)
+		global.GetDB().Model(sub).Updates(map[string]interface{}{
+			"last_run_at": now,
+			"next_run_at": next,
+			"last_error":  lastError,
+		})
+	}
+}
+
+// reportPeriod 报表统计周期的起始时间
+func reportPeriod(sub *model.ReportSubscription, now time.Time) time.Time {
+	if sub.Frequency == model.ReportFrequencyWeekly {
+		return now.AddDate(0, 0, -7)
+	}
+	return now.AddDate(0, 0, -1)
+}
+
+// send 生成并发送报表
+func (s *ReportSubscriptionService) send(sub *model.ReportSubscription, now time.Time) error {
+	var owner model.User
+	if err := global.GetDB().First(&owner, sub.OwnerID).Error; err != nil {
+		return errors.New("订阅人不存在")
+	}
+	recipients, _ := parseRecipients(sub.Recipients)
+	if len(recipients) == 0 {
+		if owner.Email == "" {
+			return errors.New("订阅人未设置邮箱")
+		}
+		recipients = []string{owner.Email}
+	}
+
+	var subject, body string
+	var err error
+	switch sub.ReportType {
+	case model.ReportTypePendingDigest:
+		subject, body, err = s.renderPendingDigest(sub, &owner, now)
+	default:
+		subject, body, err = s.renderTicketSummary(sub, &owner, now)
+	}
+	if err != nil {
+		return err
+	}
+	if body == "" {
+		// 没有需要发送的内容
+		return nil
+	}
+	return s.notifySvc.SendHTMLEmail(recipients, subject, body)
+}
+
+// reportTicketRow 报表中的工单行
+type reportTicketRow struct {
+	Number   string
+	Title    string
+	Type     string
+	Status   string
+	Priority string
+	Creator  string
+	Node     string
+	Created  string
+}
+
+func toReportTicketRows(tickets []model.Ticket) []reportTicketRow {
+	rows := make([]reportTicketRow, 0, len(tickets))
+	for _, t := range tickets {
+		row := reportTicketRow{
+			Number:   ticketDisplayNumber(&t),
+			Title:    t.Title,
+			Status:   ticketStatusLabels[t.Status],
+			Priority: getPriorityText(t.Priority),
+			Type:     t.Type.Name,
+			Creator:  t.Creator.Username,
+			Created:  t.CreatedAt.Format("2006-01-02 15:04"),
+		}
+		if t.CurrentNode != nil {
+			row.Node = t.CurrentNode.Name
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// renderPendingDigest 渲染待审批摘要，没有待审批工单时不发送
+func (s *ReportSubscriptionService) renderPendingDigest(sub *model.ReportSubscription, owner *model.User, now time.Time) (string, string, error) {
+	tickets, total, err := s.ticketSvc.GetPendingApprovals(owner.ID, 1, reportTicketLimit)
+	if err != nil {
+		return "", "", err
+	}
+	if total == 0 {
+		return "", "", nil
+	}
+	subject := fmt.Sprintf("待审批工单摘要（%d 条）", total)
+	body, err := email.RenderHTML(pendingDigestTemplate, map[string]interface{}{
+		"Name":    sub.Name,
+		"User":    owner.Username,
+		"Date":    now.Format("2006-01-02"),
+		"Total":   total,
+		"Tickets": toReportTicketRows(tickets),
+		"More":    total > int64(len(tickets)),
+	})
+	return subject, body, err
+}
+
+// renderTicketSummary 渲染工单汇总：统计周期内新建、完成数量及当前未完结工单
+func (s *ReportSubscriptionService) renderTicketSummary(sub *model.ReportSubscription, owner *model.User, now time.Time) (string, string, error) {
+	filter, err := ParseTicketFilter(sub.Filter)
+	if err != nil {
+		return "", "", err
+	}
+	filterScope, err := ScopeTicketFilter(filter, owner.ID)
+	if err != nil {
+		return "", "", err
+	}
+	isAdmin := hasAdminRole(owner.ID)
+	base := func() *gorm.DB {
+		db := global.GetDB().Model(&model.Ticket{}).Scopes(filterScope)
+		if !isAdmin {
+			db = db.Scopes(s.ticketSvc.ScopeVisibleTo(owner.ID))
+		}
+		return db
+	}
+
+	since := reportPeriod(sub, now)
+	var created, completed, open int64
+	if err := base().Where("tickets.created_at >= ? AND tickets.created_at < ?", since, now).Count(&created).Error; err != nil {
+		return "", "", err
+	}
+	if err := base().Where("tickets.completed_at >= ? AND tickets.completed_at < ?", since, now).Count(&completed).Error; err != nil {
+		return "", "", err
+	}
+	if err := base().Where("tickets.status IN ?", openTicketStatuses).Count(&open).Error; err != nil {
+		return "", "", err
+	}
+
+	var statusCounts []struct {
+		Status string
+		Count  int64
+	}
+	if err := base().Select("tickets.status, count(*) as count").Group("tickets.status").Scan(&statusCounts).Error; err != nil {
+		return "", "", err
+	}
+	type statusRow struct {
+		Status string
+		Count  int64
+	}
+	byStatus := make([]statusRow, 0, len(statusCounts))
+	for _, sc := range statusCounts {
+		label := ticketStatusLabels[sc.Status]
+		if label == "" {
+			label = sc.Status
+		}
+		byStatus = append(byStatus, statusRow{Status: label, Count: sc.Count})
+	}
+
+	var openTickets []model.Ticket
+	if err := base().Preload("Type").Preload("Creator").Preload("CurrentNode").
+		Where("tickets.status IN ?", openTicketStatuses).
+		Order("tickets.created_at ASC").Limit(reportTicketLimit).Find(&openTickets).Error; err != nil {
+		return "", "", err
+	}
+
+	period := "日报"
+	if sub.Frequency == model.ReportFrequencyWeekly {
+		period = "周报"
+	}
+	subject := fmt.Sprintf("工单%s: %s（%s）", period, sub.Name, now.Format("2006-01-02"))
+	body, err := email.RenderHTML(ticketSummaryTemplate, map[string]interface{}{
+		"Name":      sub.Name,
+		"Period":    period,
+		"Since":     since.Format("2006-01-02 15:04"),
+		"Until":     now.Format("2006-01-02 15:04"),
+		"Created":   created,
+		"Completed": completed,
+		"Open":      open,
+		"ByStatus":  byStatus,
+		"Tickets":   toReportTicketRows(openTickets),
+		"More":      open > int64(len(openTickets)),
+	})
+	return subject, body, err
+}
+
+// hasAdminRole 判断用户是否拥有管理员角色
+func hasAdminRole(userID uint) bool {
+	var count int64
+	global.GetDB().Table("user_roles").
+		Joins("JOIN roles ON roles.id = user_roles.role_id").
+		Where("user_roles.user_id = ? AND roles.name = ?", userID, "admin").
+		Count(&count)
+	return count > 0
+}
+
+// pendingDigestSubscribers 返回已启用待审批摘要订阅的用户
+func pendingDigestSubscribers(userIDs []uint) map[uint]bool {
+	result := make(map[uint]bool)
+	if len(userIDs) == 0 {
+		return result
+	}
+	var ownerIDs []uint
+	global.GetDB().Model(&model.ReportSubscription{}).
+		Where("owner_id IN ? AND report_type = ? AND enabled = ?", userIDs, model.ReportTypePendingDigest, true).
+		Distinct().Pluck("owner_id", &ownerIDs)
+	for _, id := range ownerIDs {
+		result[id] = true
+	}
+	return result
+}
+
+// ReportSubscriptionJob 定时报表发送任务
+type ReportSubscriptionJob struct{}
+
+// Name 返回任务名称
+func (j *ReportSubscriptionJob) Name() string {
+	return "report_subscription"
+}
+
+// Run 发送到期的报表
+func (j *ReportSubscriptionJob) Run() {
+	NewReportSubscriptionService().RunDueSubscriptions()
+}
+
+const reportStyle = `<style>
+body{font-family:-apple-system,"Segoe UI","PingFang SC","Microsoft YaHei",sans-serif;color:#333;font-size:14px}
+table{border-collapse:collapse;margin:8px 0}
+th,td{border:1px solid #e5e5e5;padding:6px 10px;text-align:left}
+th{background:#fafafa}
+.metric{display:inline-block;margin-right:24px}
+.metric b{font-size:20px}
+</style>`
+
+const reportTicketTable = `<table>
+<tr><th>编号</th><th>标题</th><th>类型</th><th>状态</th><th>优先级</th><th>当前节点</th><th>创建人</th><th>创建时间</th></tr>
+{{range .Tickets}}<tr><td>{{.Number}}</td><td>{{.Title}}</td><td>{{.Type}}</td><td>{{.Status}}</td><td>{{.Priority}}</td><td>{{.Node}}</td><td>{{.Creator}}</td><td>{{.Created}}</td></tr>
+{{end}}</table>`
+
+const pendingDigestTemplate = `<html><head>` + reportStyle + `</head><body>
+<p>{{.User}}，您好：</p>
+<p>截至 {{.Date}}，共有 <b>{{.Total}}</b> 个工单等待您审批。</p>
+` + reportTicketTable + `
+{{if .More}}<p>仅列出前 {{len .Tickets}} 条，请登录系统查看全部待审批工单。</p>{{end}}
+</body></html>`
+
+const ticketSummaryTemplate = `<html><head>` + reportStyle + `</head><body>
+<h3>{{.Name}} · 工单{{.Period}}</h3>
+<p>统计周期：{{.Since}} ~ {{.Until}}</p>
+<p>
+<span class="metric">新建 <b>{{.Created}}</b></span>
+<span class="metric">完成 <b>{{.Completed}}</b></span>
+<span class="metric">未完结 <b>{{.Open}}</b></span>
+</p>
+{{if .ByStatus}}<h4>状态分布</h4>
+<table><tr><th>状态</th><th>数量</th></tr>
+{{range .ByStatus}}<tr><td>{{.Status}}</td><td>{{.Count}}</td></tr>
+{{end}}</table>{{end}}
+{{if .Tickets}}<h4>未完结工单</h4>
+` + reportTicketTable + `
+{{if .More}}<p>仅列出最早创建的 {{len .Tickets}} 条。</p>{{end}}{{end}}
+</body></html>`
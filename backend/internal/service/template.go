@@ -1,40 +1,133 @@
 package service
 
 import (
-	"backend/internal/model"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
 	"backend/internal/global"
+	"backend/internal/model"
+
+	"gorm.io/gorm"
 )
 
-type TemplateService struct{}
+type TemplateService struct {
+	ticketSvc *TicketService
+}
 
 func NewTemplateService() *TemplateService {
-	return &TemplateService{}
+	return &TemplateService{ticketSvc: NewTicketService()}
+}
+
+// templatePlaceholder 预设值占位符，如 {{today}}、{{user.department}}
+var templatePlaceholder = regexp.MustCompile(`\{\{\s*([a-z_.]+)\s*\}\}`)
+
+// templatePlaceholders 支持的占位符
+var templatePlaceholders = map[string]bool{
+	"today":           true, // 当天日期 2006-01-02
+	"now":             true, // 当前时间 2006-01-02T15:04
+	"user.id":         true, // 当前用户ID
+	"user.username":   true, // 当前用户名
+	"user.email":      true, // 当前用户邮箱
+	"user.phone":      true, // 当前用户手机号
+	"user.department": true, // 当前用户部门
 }
 
+// validate 校验模板：工单类型存在，预设值为 JSON 对象且占位符合法
+func (s *TemplateService) validate(t *model.TicketTemplate) error {
+	var count int64
+	global.GetDB().Model(&model.TicketType{}).Where("id = ?", t.TypeID).Count(&count)
+	if count == 0 {
+		return errors.New("工单类型不存在")
+	}
+	if t.Priority < 0 || t.Priority > model.TicketPriorityUrgent {
+		return errors.New("优先级无效")
+	}
+	if t.PresetValues != "" {
+		var presets map[string]interface{}
+		if err := json.Unmarshal([]byte(t.PresetValues), &presets); err != nil {
+			return errors.New("预设值必须是 JSON 对象")
+		}
+	}
+	for _, text := range []string{t.Title, t.PresetValues} {
+		for _, m := range templatePlaceholder.FindAllStringSubmatch(text, -1) {
+			if !templatePlaceholders[m[1]] {
+				return fmt.Errorf("不支持的占位符: {{%s}}", m[1])
+			}
+		}
+	}
+	return nil
+}
+
+// Create 创建模板（OwnerID 为空表示全局模板）
 func (s *TemplateService) Create(t *model.TicketTemplate) error {
+	if err := s.validate(t); err != nil {
+		return err
+	}
 	return global.GetDB().Create(t).Error
 }
 
-func (s *TemplateService) Update(id uint, t *model.TicketTemplate) error {
-	return global.GetDB().Model(&model.TicketTemplate{}).Where("id = ?", id).Updates(t).Error
+// Update 更新模板：个人模板仅所有者可修改，全局模板仅管理员可修改
+func (s *TemplateService) Update(id, userID uint, isAdmin bool, t *model.TicketTemplate) error {
+	existing, err := s.getEditable(id, userID, isAdmin)
+	if err != nil {
+		return err
+	}
+	if err := s.validate(t); err != nil {
+		return err
+	}
+	return global.GetDB().Model(existing).
+		Select("Name", "Description", "TypeID", "Title", "Priority", "PresetValues", "Enabled", "SortOrder").
+		Updates(t).Error
 }
 
-func (s *TemplateService) Delete(id uint) error {
-	return global.GetDB().Delete(&model.TicketTemplate{}, id).Error
+// Delete 删除模板：个人模板仅所有者可删除，全局模板仅管理员可删除
+func (s *TemplateService) Delete(id, userID uint, isAdmin bool) error {
+	existing, err := s.getEditable(id, userID, isAdmin)
+	if err != nil {
+		return err
+	}
+	return global.GetDB().Delete(existing).Error
 }
 
-func (s *TemplateService) GetByID(id uint) (*model.TicketTemplate, error) {
+func (s *TemplateService) getEditable(id, userID uint, isAdmin bool) (*model.TicketTemplate, error) {
 	var t model.TicketTemplate
 	if err := global.GetDB().First(&t, id).Error; err != nil {
+		return nil, errors.New("模板不存在")
+	}
+	if t.OwnerID == nil {
+		if !isAdmin {
+			return nil, errors.New("只有管理员可以修改全局模板")
+		}
+	} else if *t.OwnerID != userID {
+		return nil, errors.New("只能修改自己的个人模板")
+	}
+	return &t, nil
+}
+
+// scopeVisible 用户可使用的模板：全局模板及自己的个人模板
+func (s *TemplateService) scopeVisible(userID uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("(owner_id IS NULL OR owner_id = ?)", userID)
+	}
+}
+
+func (s *TemplateService) GetByID(id, userID uint) (*model.TicketTemplate, error) {
+	var t model.TicketTemplate
+	if err := global.GetDB().Scopes(s.scopeVisible(userID)).Preload("Type").First(&t, id).Error; err != nil {
 		return nil, err
 	}
 	return &t, nil
 }
 
-func (s *TemplateService) List(page, pageSize int, keyword string, typeID uint) ([]model.TicketTemplate, int64, error) {
+// List 模板列表（全局模板及自己的个人模板）
+func (s *TemplateService) List(page, pageSize int, keyword string, typeID, userID uint) ([]model.TicketTemplate, int64, error) {
 	var templates []model.TicketTemplate
 	var total int64
-	db := global.GetDB().Model(&model.TicketTemplate{})
+	db := global.GetDB().Model(&model.TicketTemplate{}).Scopes(s.scopeVisible(userID))
 
 	if keyword != "" {
 		db = db.Where("name LIKE ?", "%"+keyword+"%")
@@ -45,15 +138,15 @@ func (s *TemplateService) List(page, pageSize int, keyword string, typeID uint)
 
 	db.Count(&total)
 	offset := (page - 1) * pageSize
-	if err := db.Order("sort_order ASC, created_at DESC").Offset(offset).Limit(pageSize).Find(&templates).Error; err != nil {
+	if err := db.Preload("Type").Order("sort_order ASC, created_at DESC").Offset(offset).Limit(pageSize).Find(&templates).Error; err != nil {
 		return nil, 0, err
 	}
 	return templates, total, nil
 }
 
-func (s *TemplateService) ListEnabled(typeID uint) ([]model.TicketTemplate, error) {
+func (s *TemplateService) ListEnabled(typeID, userID uint) ([]model.TicketTemplate, error) {
 	var templates []model.TicketTemplate
-	db := global.GetDB().Where("enabled = ?", true)
+	db := global.GetDB().Scopes(s.scopeVisible(userID)).Where("enabled = ?", true)
 	if typeID > 0 {
 		db = db.Where("type_id = ?", typeID)
 	}
@@ -62,3 +155,142 @@ func (s *TemplateService) ListEnabled(typeID uint) ([]model.TicketTemplate, erro
 	}
 	return templates, nil
 }
+
+// ResolvedTemplate 替换占位符后的模板预设
+type ResolvedTemplate struct {
+	TemplateID  uint                   `json:"template_id"`
+	TypeID      uint                   `json:"type_id"`
+	Title       string                 `json:"title"`
+	Description string                 `json:"description"`
+	Priority    int                    `json:"priority"`
+	FormData    map[string]interface{} `json:"form_data"`
+}
+
+// Resolve 以指定用户的身份替换模板预设中的占位符
+func (s *TemplateService) Resolve(t *model.TicketTemplate, userID uint) (*ResolvedTemplate, error) {
+	var user model.User
+	if err := global.GetDB().First(&user, userID).Error; err != nil {
+		return nil, errors.New("用户不存在")
+	}
+	now := time.Now()
+	values := map[string]string{
+		"today":           now.Format("2006-01-02"),
+		"now":             now.Format("2006-01-02T15:04"),
+		"user.id":         strconv.FormatUint(uint64(user.ID), 10),
+		"user.username":   user.Username,
+		"user.email":      user.Email,
+		"user.phone":      user.Phone,
+		"user.department": user.Department,
+	}
+	replace := func(text string) string {
+		return templatePlaceholder.ReplaceAllStringFunc(text, func(token string) string {
+			m := templatePlaceholder.FindStringSubmatch(token)
+			if v, ok := values[m[1]]; ok {
+				return v
+			}
+			return token
+		})
+	}
+
+	resolved := &ResolvedTemplate{
+		TemplateID:  t.ID,
+		TypeID:      t.TypeID,
+		Title:       replace(t.Title),
+		Description: replace(t.Description),
+		Priority:    t.Priority,
+		FormData:    map[string]interface{}{},
+	}
+	if t.PresetValues != "" {
+		if err := json.Unmarshal([]byte(t.PresetValues), &resolved.FormData); err != nil {
+			return nil, errors.New("模板预设值格式错误")
+		}
+	}
+	for name, value := range resolved.FormData {
+		resolved.FormData[name] = resolvePresetValue(value, replace)
+	}
+	return resolved, nil
+}
+
+// resolvePresetValue 递归替换预设值（含数组）中字符串的占位符
+func resolvePresetValue(value interface{}, replace func(string) string) interface{} {
+	switch v := value.(type) {
+	case string:
+		return replace(v)
+	case []interface{}:
+		for i := range v {
+			v[i] = resolvePresetValue(v[i], replace)
+		}
+		return v
+	default:
+		return value
+	}
+}
+
+// GetResolved 获取替换占位符后的模板预设（用于前端预填）
+func (s *TemplateService) GetResolved(id, userID uint) (*ResolvedTemplate, error) {
+	t, err := s.GetByID(id, userID)
+	if err != nil {
+		return nil, errors.New("模板不存在")
+	}
+	return s.Resolve(t, userID)
+}
+
+// TemplateTicketInput 基于模板创建工单时用户填写的内容，非空项覆盖模板预设
+type TemplateTicketInput struct {
+	Title       string
+	Description string
+	Priority    int
+	FormData    map[string]interface{}
+	Submit      bool
+}
+
+// CreateTicket 基于模板创建工单：模板预设与用户输入合并，用户输入优先
+func (s *TemplateService) CreateTicket(templateID, userID uint, input *TemplateTicketInput) (*model.Ticket, error) {
+	t, err := s.GetByID(templateID, userID)
+	if err != nil || !t.Enabled {
+		return nil, errors.New("模板不存在或未启用")
+	}
+	resolved, err := s.Resolve(t, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	ticket := model.Ticket{
+		Title:       resolved.Title,
+		Description: resolved.Description,
+		TypeID:      resolved.TypeID,
+		Priority:    resolved.Priority,
+		CreatorID:   userID,
+		Status:      model.TicketStatusDraft,
+	}
+	if input.Title != "" {
+		ticket.Title = input.Title
+	}
+	if ticket.Title == "" {
+		ticket.Title = t.Name
+	}
+	if input.Description != "" {
+		ticket.Description = input.Description
+	}
+	if input.Priority > 0 {
+		ticket.Priority = input.Priority
+	}
+	if ticket.Priority == 0 {
+		ticket.Priority = model.TicketPriorityMedium
+	}
+	formData := resolved.FormData
+	for name, value := range input.FormData {
+		formData[name] = value
+	}
+
+	if err := s.ticketSvc.CreateWithFormData(&ticket, formData); err != nil {
+		return nil, err
+	}
+	if input.Submit {
+		if err := s.ticketSvc.Submit(ticket.ID); err != nil {
+			return &ticket, fmt.Errorf("工单已创建，但提交失败: %w", err)
+		}
+		global.GetDB().First(&ticket, ticket.ID)
+	}
+	return &ticket, nil
+}
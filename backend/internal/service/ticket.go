@@ -1,7 +1,6 @@
 package service
 
 import (
-	"encoding/json"
 	"errors"
 	"strconv"
 	"strings"
@@ -9,6 +8,9 @@ import (
 
 	"backend/internal/model"
 	"backend/internal/global"
+	"backend/pkg/expr"
+
+	"gorm.io/gorm"
 )
 
 type TicketService struct {
@@ -36,68 +38,154 @@ func (s *TicketService) checkTicketStatus(id uint, allowedStatus ...string) (*mo
 }
 
 func (s *TicketService) Create(ticket *model.Ticket) error {
-	return global.GetDB().Create(ticket).Error
+	return withTicketNumberRetry(ticket, func() error {
+		return global.GetDB().Transaction(func(tx *gorm.DB) error {
+			if err := assignTicketNumber(tx, ticket, model.TicketNumberOnCreate); err != nil {
+				return err
+			}
+			return tx.Create(ticket).Error
+		})
+	})
 }
 
 // CreateWithFormData 创建工单并保存动态表单数据
+//
+// 表单数据按类型关联的表单字段校验，校验失败时返回 *FormValidationError
 func (s *TicketService) CreateWithFormData(ticket *model.Ticket, formData map[string]interface{}) error {
 	db := global.GetDB()
-	tx := db.Begin()
-
-	// 创建工单
-	if err := tx.Create(ticket).Error; err != nil {
-		tx.Rollback()
+	fields, err := loadTypeFormFields(db, ticket.TypeID)
+	if err != nil {
+		return errors.New("工单类型不存在")
+	}
+	values := formDataStrings(formData)
+	if err := computeFormValues(fields, values); err != nil {
+		return err
+	}
+	hidden := hiddenFormFields(fields, values)
+	if err := validateFormValues(fields, values, hidden, formValidateCreate); err != nil {
+		return err
+	}
+	// 绑定当前表单模板版本，模板字段后续修改不影响该工单
+	if err := bindTicketFormVersion(db, ticket); err != nil {
 		return err
 	}
 
-	// 如果有表单数据，获取类型对应的模板字段并保存
-	if len(formData) > 0 && ticket.TypeID > 0 {
-		var ticketType model.TicketType
-		if err := tx.Preload("Template").Preload("Template.Fields").First(&ticketType, ticket.TypeID).Error; err == nil {
-			if ticketType.Template != nil && len(ticketType.Template.Fields) > 0 {
-				// 创建字段名到ID的映射
-				fieldMap := make(map[string]uint)
-				for _, field := range ticketType.Template.Fields {
-					fieldMap[field.Name] = field.ID
-				}
+	if err := withTicketNumberRetry(ticket, func() error {
+		return db.Transaction(func(tx *gorm.DB) error {
+			// 分配工单编号（与工单写入同一事务，保证编号连续）
+			if err := assignTicketNumber(tx, ticket, model.TicketNumberOnCreate); err != nil {
+				return err
+			}
+
+			// 创建工单
+			if err := tx.Create(ticket).Error; err != nil {
+				return err
+			}
 
-				// 保存表单数据
-				for fieldName, value := range formData {
-					if fieldID, ok := fieldMap[fieldName]; ok {
-						var valueStr string
-						switch v := value.(type) {
-						case string:
-							valueStr = v
-						case []interface{}:
-							// 多选字段，转为 JSON
-							jsonBytes, _ := json.Marshal(v)
-							valueStr = string(jsonBytes)
-						default:
-							// 其他类型转为字符串
-							jsonBytes, _ := json.Marshal(v)
-							valueStr = string(jsonBytes)
-						}
-
-						ticketData := model.TicketData{
-							TicketID: ticket.ID,
-							FieldID:  fieldID,
-							Value:    valueStr,
-						}
-						if err := tx.Create(&ticketData).Error; err != nil {
-							tx.Rollback()
-							return err
-						}
-					}
+			// 保存表单数据（隐藏字段的值丢弃）
+			for _, field := range fields {
+				value, ok := values[field.Name]
+				if !ok || hidden[field.Name] {
+					continue
+				}
+				ticketData := model.TicketData{
+					TicketID: ticket.ID,
+					FieldID:  field.ID,
+					Value:    value,
+				}
+				ProjectTicketData(&field, &ticketData)
+				if err := tx.Create(&ticketData).Error; err != nil {
+					return err
 				}
 			}
-		}
+			return nil
+		})
+	}); err != nil {
+		return err
 	}
+	recordTicketEvent(ticket.ID, model.TicketEventCreated, "", ticket.Status, nil, ticket.CreatorID)
+	reindexTicket(ticket.ID)
+	return nil
+}
 
-	return tx.Commit().Error
+// Update 修改工单基本信息及表单数据：仅创建者或管理员可操作，且工单须为草稿（含退回）或已撤回状态；
+// 审批中的表单修改只能通过审批提交
+func (s *TicketService) Update(id, userID uint, isAdmin bool, ticket *model.Ticket, formData map[string]interface{}) error {
+	var existing model.Ticket
+	if err := global.GetDB().First(&existing, id).Error; err != nil {
+		return errors.New("工单不存在")
+	}
+	if !isAdmin && existing.CreatorID != userID {
+		return errors.New("只有创建者可以修改工单")
+	}
+	if existing.Status != model.TicketStatusDraft && existing.Status != model.TicketStatusWithdrawn {
+		return errors.New("只有草稿、被退回或已撤回的工单可以修改")
+	}
+	return s.update(&existing, ticket, formData)
 }
 
-func (s *TicketService) Update(id uint, ticket *model.Ticket) error {
-	return global.GetDB().Model(&model.Ticket{}).Where("id = ?", id).Updates(ticket).Error
+// update 更新工单基本信息及表单数据，仅校验并更新提交的字段
+func (s *TicketService) update(existing *model.Ticket, ticket *model.Ticket, formData map[string]interface{}) error {
+	id := existing.ID
+	fields, err := loadTicketFormFields(global.GetDB(), existing)
+	if err != nil {
+		return err
+	}
+	values := formDataStrings(formData)
+	hidden := map[string]bool{}
+	if len(values) > 0 {
+		// 显示条件及公式按修改后的完整表单数据计算
+		merged, err := storedFormValues(id)
+		if err != nil {
+			return err
+		}
+		for name, value := range values {
+			merged[name] = value
+		}
+		if err := computeFormValues(fields, merged); err != nil {
+			return err
+		}
+		// 计算结果（公式字段、表格行内公式）随修改一并保存
+		for _, field := range fields {
+			if _, provided := values[field.Name]; provided || field.FieldType == model.FormFieldTypeFormula {
+				values[field.Name] = merged[field.Name]
+			}
+		}
+		hidden = hiddenFormFields(fields, merged)
+		if err := validateFormValues(fields, values, hidden, formValidateUpdate); err != nil {
+			return err
+		}
+	}
+
+	if err := global.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(existing).Updates(ticket).Error; err != nil {
+			return err
+		}
+		for i := range fields {
+			field := &fields[i]
+			value, ok := values[field.Name]
+			if !ok && !hidden[field.Name] {
+				continue
+			}
+			// 修改的字段及因修改而隐藏的字段先清除旧值
+			if err := tx.Where("ticket_id = ? AND field_id = ?", id, field.ID).Delete(&model.TicketData{}).Error; err != nil {
+				return err
+			}
+			if hidden[field.Name] || unquoteFormValue(value) == "" {
+				continue
+			}
+			ticketData := model.TicketData{TicketID: id, FieldID: field.ID, Value: value}
+			ProjectTicketData(field, &ticketData)
+			if err := tx.Create(&ticketData).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	reindexTicket(id)
+	return nil
 }
 
 func (s *TicketService) Delete(id, userID uint, isAdmin bool) error {
@@ -118,7 +206,11 @@ func (s *TicketService) Delete(id, userID uint, isAdmin bool) error {
 		}
 	}
 
-	return global.GetDB().Delete(&model.Ticket{}, id).Error
+	if err := global.GetDB().Delete(&model.Ticket{}, id).Error; err != nil {
+		return err
+	}
+	reindexTicket(id)
+	return nil
 }
 
 func (s *TicketService) GetByID(id uint) (*model.Ticket, error) {
@@ -136,21 +228,35 @@ func (s *TicketService) GetByIDWithDetails(id uint) (*model.Ticket, error) {
 	var ticket model.Ticket
 	if err := global.GetDB().Preload("Type").Preload("Type.Template").Preload("Type.Template.Fields").
 		Preload("Creator").Preload("Assignee").Preload("CurrentNode").
-		Preload("Data").Preload("Data.Field").
+		Preload("Data").Preload("Data.Field", func(db *gorm.DB) *gorm.DB {
+			// 模板字段修改后旧字段被软删除，历史数据仍按原字段显示
+			return db.Unscoped()
+		}).
 		First(&ticket, id).Error; err != nil {
 		return nil, err
 	}
+	if err := applyTicketFormVersion(&ticket); err != nil {
+		return nil, err
+	}
 	return &ticket, nil
 }
 
+// scopeTicketKeyword 按关键词匹配工单标题或编号（工单列表与导出共用），关键词为空时不过滤
+func scopeTicketKeyword(keyword string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if keyword == "" {
+			return db
+		}
+		return db.Where("(title LIKE ? OR number LIKE ?)", "%"+keyword+"%", "%"+keyword+"%")
+	}
+}
+
 func (s *TicketService) List(page, pageSize int, keyword, status string, typeID, creatorID uint) ([]model.Ticket, int64, error) {
 	var tickets []model.Ticket
 	var total int64
 	db := global.GetDB().Model(&model.Ticket{})
 
-	if keyword != "" {
-		db = db.Where("title LIKE ?", "%"+keyword+"%")
-	}
+	db = db.Scopes(scopeTicketKeyword(keyword))
 	if status != "" {
 		db = db.Where("status = ?", status)
 	}
@@ -179,9 +285,7 @@ func (s *TicketService) ListForUser(userID uint, page, pageSize int, keyword, st
 	// 只查询用户创建的工单
 	db = db.Where("creator_id = ?", userID)
 
-	if keyword != "" {
-		db = db.Where("title LIKE ?", "%"+keyword+"%")
-	}
+	db = db.Scopes(scopeTicketKeyword(keyword))
 	if status != "" {
 		db = db.Where("status = ?", status)
 	}
@@ -198,6 +302,47 @@ func (s *TicketService) ListForUser(userID uint, page, pageSize int, keyword, st
 	return tickets, total, nil
 }
 
+// ScopeVisibleTo 限定用户可见的工单：创建人、处理人、参与过审批（含转审/加签/抄送）、当前节点的审批人或关注人
+func (s *TicketService) ScopeVisibleTo(userID uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		conditions := []string{
+			"tickets.creator_id = ?",
+			"tickets.assignee_id = ?",
+			"tickets.id IN (?)",
+			"tickets.current_node_id IN (?)",
+			"tickets.id IN (?)",
+		}
+		args := []interface{}{
+			userID,
+			userID,
+			global.GetDB().Model(&model.ApprovalRecord{}).Select("ticket_id").
+				Where("approver_id = ? OR delegate_to_id = ?", userID, userID),
+			s.approverNodeQuery(userID),
+			global.GetDB().Model(&model.TicketWatcher{}).Select("ticket_id").Where("user_id = ?", userID),
+		}
+		return db.Where("("+strings.Join(conditions, " OR ")+")", args...)
+	}
+}
+
+// approverNodeQuery 用户作为审批人（所属角色或指定用户）的流程节点ID子查询
+func (s *TicketService) approverNodeQuery(userID uint) *gorm.DB {
+	var roleIDs []uint
+	global.GetDB().Table("user_roles").Where("user_id = ?", userID).Pluck("role_id", &roleIDs)
+
+	conditions := []string{"(approver_type = ? AND FIND_IN_SET(?, approver_value))"}
+	args := []interface{}{model.ApproverTypeUser, strconv.FormatUint(uint64(userID), 10)}
+	if len(roleIDs) > 0 {
+		roleIDStrs := make([]string, len(roleIDs))
+		for i, rid := range roleIDs {
+			roleIDStrs[i] = strconv.FormatUint(uint64(rid), 10)
+		}
+		conditions = append(conditions, "(approver_type = ? AND approver_value IN ?)")
+		args = append(args, model.ApproverTypeRole, roleIDStrs)
+	}
+	return global.GetDB().Model(&model.FlowNode{}).Select("id").
+		Where("("+strings.Join(conditions, " OR ")+")", args...)
+}
+
 // Submit 提交工单（从草稿变为待审批）
 func (s *TicketService) Submit(id uint) error {
 	var ticket model.Ticket
@@ -207,28 +352,45 @@ func (s *TicketService) Submit(id uint) error {
 	if ticket.Status != model.TicketStatusDraft {
 		return errors.New("只有草稿状态的工单可以提交")
 	}
+	if err := s.validateStoredFormData(&ticket); err != nil {
+		return err
+	}
+	if ticket.Number == "" {
+		if err := withTicketNumberRetry(&ticket, func() error {
+			return global.GetDB().Transaction(func(tx *gorm.DB) error {
+				return assignTicketNumber(tx, &ticket, model.TicketNumberOnSubmit)
+			})
+		}); err != nil {
+			return err
+		}
+		if ticket.Number != "" {
+			reindexTicket(ticket.ID)
+		}
+	}
 
 	// 通过工单类型获取关联的审批流程
 	var flow model.ApprovalFlow
 	if ticket.Type.FlowID == nil {
 		// 没有审批流程，直接进入处理中
-		if err := global.GetDB().Model(&ticket).Updates(map[string]interface{}{
+		if err := submitTransition(&ticket, map[string]interface{}{
 			"status": model.TicketStatusProcessing,
-		}).Error; err != nil {
+		}); err != nil {
 			return err
 		}
-		go s.notifySvc.NotifyTicketCreated(&ticket)
+		recordTicketEvent(ticket.ID, model.TicketEventSubmitted, model.TicketStatusDraft, model.TicketStatusProcessing, nil, ticket.CreatorID)
+		s.notifySvc.NotifyTicketCreated(&ticket)
 		return nil
 	}
 
 	if err := global.GetDB().First(&flow, *ticket.Type.FlowID).Error; err != nil || !flow.Enabled {
 		// 流程不存在或未启用，直接进入处理中
-		if err := global.GetDB().Model(&ticket).Updates(map[string]interface{}{
+		if err := submitTransition(&ticket, map[string]interface{}{
 			"status": model.TicketStatusProcessing,
-		}).Error; err != nil {
+		}); err != nil {
 			return err
 		}
-		go s.notifySvc.NotifyTicketCreated(&ticket)
+		recordTicketEvent(ticket.ID, model.TicketEventSubmitted, model.TicketStatusDraft, model.TicketStatusProcessing, nil, ticket.CreatorID)
+		s.notifySvc.NotifyTicketCreated(&ticket)
 		return nil
 	}
 
@@ -236,32 +398,47 @@ func (s *TicketService) Submit(id uint) error {
 	var firstNode model.FlowNode
 	if err := global.GetDB().Where("flow_id = ? AND node_type != ?", flow.ID, model.FlowNodeTypeCC).
 		Order("sort_order ASC").First(&firstNode).Error; err != nil {
-		if err := global.GetDB().Model(&ticket).Updates(map[string]interface{}{
+		if err := submitTransition(&ticket, map[string]interface{}{
 			"status": model.TicketStatusProcessing,
-		}).Error; err != nil {
+		}); err != nil {
 			return err
 		}
-		go s.notifySvc.NotifyTicketCreated(&ticket)
+		recordTicketEvent(ticket.ID, model.TicketEventSubmitted, model.TicketStatusDraft, model.TicketStatusProcessing, nil, ticket.CreatorID)
+		s.notifySvc.NotifyTicketCreated(&ticket)
 		return nil
 	}
 
-	// 处理可能的抄送节点
-	s.processCCNodes(flow.ID, &ticket)
-
-	if err := global.GetDB().Model(&ticket).Updates(map[string]interface{}{
+	if err := submitTransition(&ticket, map[string]interface{}{
 		"status":          model.TicketStatusPending,
 		"flow_id":         flow.ID,
 		"flow_version":    flow.Version,
 		"current_node_id": firstNode.ID,
-	}).Error; err != nil {
+	}); err != nil {
 		return err
 	}
-	go s.notifySvc.NotifyTicketCreated(&ticket)
+	// 处理可能的抄送节点
+	s.processCCNodes(flow.ID, &ticket)
+	recordTicketEvent(ticket.ID, model.TicketEventSubmitted, model.TicketStatusDraft, model.TicketStatusPending, nil, ticket.CreatorID)
+	recordTicketEvent(ticket.ID, model.TicketEventNodeEntered, model.TicketStatusDraft, model.TicketStatusPending, &firstNode.ID, 0)
+	s.notifySvc.NotifyTicketCreated(&ticket)
+	go s.notifyNodeApprovers(ticket.ID, firstNode.ID)
 	return nil
 }
 
-// Approve 审批工单
-func (s *TicketService) Approve(id, approverID uint, approved bool, comment string) error {
+// submitTransition 提交时的状态变更，仅在工单仍为草稿时生效，重复提交时返回错误
+func submitTransition(ticket *model.Ticket, updates map[string]interface{}) error {
+	res := global.GetDB().Model(ticket).Where("status = ?", model.TicketStatusDraft).Updates(updates)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errors.New("只有草稿状态的工单可以提交")
+	}
+	return nil
+}
+
+// Approve 审批工单，formData 为审批人在当前节点修改的表单字段（受节点字段权限约束）
+func (s *TicketService) Approve(id, approverID uint, approved bool, comment string, formData map[string]interface{}) error {
 	var ticket model.Ticket
 	if err := global.GetDB().Preload("Data").First(&ticket, id).Error; err != nil {
 		return err
@@ -278,91 +455,99 @@ func (s *TicketService) Approve(id, approverID uint, approved bool, comment stri
 	if err := global.GetDB().First(&currentNode, *ticket.CurrentNodeID).Error; err != nil {
 		return err
 	}
+	fromStatus := ticket.Status
 
-	// 创建审批记录
 	action := model.ApprovalActionApprove
 	result := "approved"
 	if !approved {
 		action = model.ApprovalActionReject
 		result = "rejected"
 	}
-	record := model.ApprovalRecord{
-		TicketID:   id,
-		NodeID:     *ticket.CurrentNodeID,
-		ApproverID: approverID,
-		Action:     action,
-		Result:     result,
-		Comment:    comment,
-	}
-	if err := global.GetDB().Create(&record).Error; err != nil {
-		return err
-	}
 
-	// 如果拒绝，直接结束流程
-	if !approved {
-		if err := global.GetDB().Model(&ticket).Updates(map[string]any{
-			"status":          model.TicketStatusRejected,
-			"current_node_id": nil,
-		}).Error; err != nil {
+	// 表单修改、审批记录与节点流转在同一事务中完成，任一步失败整体回滚
+	var (
+		toStatus  string
+		eventType string
+		nextNode  *model.FlowNode
+		ccNodes   []*model.FlowNode
+	)
+	if err := global.GetDB().Transaction(func(tx *gorm.DB) error {
+		// 保存审批人填写的字段
+		if err := s.applyApproverFormData(tx, &ticket, &currentNode, approverID, approved, formData); err != nil {
 			return err
 		}
-		go s.notifySvc.NotifyTicketApproved(&ticket, approved, comment)
-		return nil
-	}
-
-	// 根据节点类型判断是否完成当前节点
-	nodeComplete := s.isNodeComplete(&currentNode, &ticket, id)
-
-	if !nodeComplete {
-		// 会签未完成，保持当前节点，更新状态为审批中
-		global.GetDB().Model(&ticket).Update("status", model.TicketStatusApproving)
-		go s.notifySvc.NotifyTicketApproved(&ticket, approved, comment)
-		return nil
-	}
 
-	// 查找下一个节点
-	nextNode := s.getNextNode(&currentNode, &ticket)
-	if nextNode == nil {
-		// 没有下一个节点，审批完成
-		if err := global.GetDB().Model(&ticket).Updates(map[string]any{
-			"status":          model.TicketStatusProcessing,
-			"current_node_id": nil,
-		}).Error; err != nil {
+		// 创建审批记录
+		record := model.ApprovalRecord{
+			TicketID:   id,
+			NodeID:     *ticket.CurrentNodeID,
+			ApproverID: approverID,
+			Action:     action,
+			Result:     result,
+			Comment:    comment,
+		}
+		if err := tx.Create(&record).Error; err != nil {
 			return err
 		}
-		go s.notifySvc.NotifyTicketApproved(&ticket, approved, comment)
-		return nil
-	}
 
-	// 如果是抄送节点，跳过并处理
-	for nextNode != nil && nextNode.NodeType == model.FlowNodeTypeCC {
-		s.processOneCCNode(nextNode, &ticket)
-		nextNode = s.getNextNode(nextNode, &ticket)
-	}
+		// 如果拒绝，直接结束流程
+		if !approved {
+			toStatus, eventType = model.TicketStatusRejected, model.TicketEventRejected
+			return tx.Model(&ticket).Updates(map[string]any{
+				"status":          model.TicketStatusRejected,
+				"current_node_id": nil,
+			}).Error
+		}
 
-	if nextNode == nil {
-		// 没有下一个节点，审批完成
-		if err := global.GetDB().Model(&ticket).Updates(map[string]any{
-			"status":          model.TicketStatusProcessing,
-			"current_node_id": nil,
-		}).Error; err != nil {
-			return err
+		// 根据节点类型判断是否完成当前节点；会签未完成时保持当前节点，更新状态为审批中
+		if !s.isNodeComplete(tx, &currentNode, &ticket, id) {
+			return tx.Model(&ticket).Update("status", model.TicketStatusApproving).Error
+		}
+
+		// 查找下一个节点，抄送节点在提交后处理
+		nextNode = s.getNextNode(&currentNode, &ticket)
+		for nextNode != nil && nextNode.NodeType == model.FlowNodeTypeCC {
+			ccNodes = append(ccNodes, nextNode)
+			nextNode = s.getNextNode(nextNode, &ticket)
+		}
+		if nextNode == nil {
+			// 没有下一个节点，审批完成
+			toStatus, eventType = model.TicketStatusProcessing, model.TicketEventApproved
+			return tx.Model(&ticket).Updates(map[string]any{
+				"status":          model.TicketStatusProcessing,
+				"current_node_id": nil,
+			}).Error
 		}
-	} else {
-		if err := global.GetDB().Model(&ticket).Updates(map[string]any{
+		toStatus, eventType = model.TicketStatusPending, model.TicketEventNodeEntered
+		return tx.Model(&ticket).Updates(map[string]any{
 			"status":          model.TicketStatusPending,
 			"current_node_id": nextNode.ID,
-		}).Error; err != nil {
-			return err
-		}
+		}).Error
+	}); err != nil {
+		return err
+	}
+	if len(formData) > 0 {
+		reindexTicket(id)
 	}
 
-	go s.notifySvc.NotifyTicketApproved(&ticket, approved, comment)
+	for _, ccNode := range ccNodes {
+		s.processOneCCNode(ccNode, &ticket)
+	}
+	switch eventType {
+	case model.TicketEventNodeEntered:
+		recordTicketEvent(id, eventType, fromStatus, toStatus, &nextNode.ID, approverID)
+		go s.notifyNodeApprovers(id, nextNode.ID)
+	case "":
+		// 会签未完成，节点未流转
+	default:
+		recordTicketEvent(id, eventType, fromStatus, toStatus, &currentNode.ID, approverID)
+	}
+	s.notifySvc.NotifyTicketApproved(&ticket, approverID, approved, comment)
 	return nil
 }
 
 // isNodeComplete 检查当前节点是否完成
-func (s *TicketService) isNodeComplete(node *model.FlowNode, ticket *model.Ticket, ticketID uint) bool {
+func (s *TicketService) isNodeComplete(db *gorm.DB, node *model.FlowNode, ticket *model.Ticket, ticketID uint) bool {
 	switch node.NodeType {
 	case model.FlowNodeTypeApprove, model.FlowNodeTypeOr:
 		// 审批节点或或签节点：任一人通过即完成
@@ -371,7 +556,7 @@ func (s *TicketService) isNodeComplete(node *model.FlowNode, ticket *model.Ticke
 		// 会签节点：需要所有审批人都通过
 		approverIDs := s.getApproverIDs(node, ticket)
 		var approvedCount int64
-		global.GetDB().Model(&model.ApprovalRecord{}).
+		db.Model(&model.ApprovalRecord{}).
 			Where("ticket_id = ? AND node_id = ? AND result = ?", ticketID, node.ID, "approved").
 			Count(&approvedCount)
 		return int(approvedCount) >= len(approverIDs)
@@ -423,6 +608,21 @@ func (s *TicketService) getApproverIDs(node *model.FlowNode, ticket *model.Ticke
 	return approverIDs
 }
 
+// notifyNodeApprovers 通知节点审批人有新的待审批工单
+func (s *TicketService) notifyNodeApprovers(ticketID, nodeID uint) {
+	var ticket model.Ticket
+	if err := global.GetDB().Preload("Data").Preload("Data.Field").First(&ticket, ticketID).Error; err != nil {
+		return
+	}
+	var node model.FlowNode
+	if err := global.GetDB().First(&node, nodeID).Error; err != nil {
+		return
+	}
+	if approverIDs := s.getApproverIDs(&node, &ticket); len(approverIDs) > 0 {
+		s.notifySvc.NotifyPendingApproval(&ticket, approverIDs)
+	}
+}
+
 // getNextNode 获取下一个节点
 func (s *TicketService) getNextNode(currentNode *model.FlowNode, ticket *model.Ticket) *model.FlowNode {
 	// 条件节点：根据条件判断走哪个分支
@@ -454,59 +654,17 @@ func (s *TicketService) getNextNode(currentNode *model.FlowNode, ticket *model.T
 	return &nextNode
 }
 
-// evaluateCondition 评估条件节点
+// evaluateCondition 评估条件节点（与表单显示条件使用同一求值器）
 func (s *TicketService) evaluateCondition(node *model.FlowNode, ticket *model.Ticket) *uint {
 	if node.Condition == "" {
 		return node.TrueBranchID
 	}
-
-	// 解析条件配置
-	var condition struct {
-		Field    string `json:"field"`
-		Operator string `json:"operator"`
-		Value    string `json:"value"`
-	}
-	if err := json.Unmarshal([]byte(node.Condition), &condition); err != nil {
+	values, err := storedFormValues(ticket.ID)
+	if err != nil {
 		return node.TrueBranchID
 	}
-
-	// 从工单数据中获取字段值
-	var fieldValue string
-	for _, data := range ticket.Data {
-		if data.Field != nil && data.Field.Name == condition.Field {
-			fieldValue = data.Value
-			break
-		}
-	}
-
-	// 评估条件
-	match := false
-	switch condition.Operator {
-	case "eq", "==":
-		match = fieldValue == condition.Value
-	case "ne", "!=":
-		match = fieldValue != condition.Value
-	case "gt", ">":
-		fv, _ := strconv.ParseFloat(fieldValue, 64)
-		cv, _ := strconv.ParseFloat(condition.Value, 64)
-		match = fv > cv
-	case "gte", ">=":
-		fv, _ := strconv.ParseFloat(fieldValue, 64)
-		cv, _ := strconv.ParseFloat(condition.Value, 64)
-		match = fv >= cv
-	case "lt", "<":
-		fv, _ := strconv.ParseFloat(fieldValue, 64)
-		cv, _ := strconv.ParseFloat(condition.Value, 64)
-		match = fv < cv
-	case "lte", "<=":
-		fv, _ := strconv.ParseFloat(fieldValue, 64)
-		cv, _ := strconv.ParseFloat(condition.Value, 64)
-		match = fv <= cv
-	case "contains":
-		match = strings.Contains(fieldValue, condition.Value)
-	}
-
-	if match {
+	// 条件无效时走真分支；未填写的字段按空值处理
+	if expr.Match(node.Condition, func(field string) string { return values[field] }, true) {
 		return node.TrueBranchID
 	}
 	return node.FalseBranchID
@@ -536,7 +694,7 @@ func (s *TicketService) processOneCCNode(node *model.FlowNode, ticket *model.Tic
 		global.GetDB().Create(&record)
 	}
 	// 发送抄送通知
-	go s.notifySvc.NotifyTicketCC(ticket, ccUserIDs)
+	s.notifySvc.NotifyTicketCC(ticket, ccUserIDs)
 }
 
 // Withdraw 撤回工单
@@ -565,10 +723,15 @@ func (s *TicketService) Withdraw(id, userID uint) error {
 		return errors.New("工单已有审批记录，无法撤回")
 	}
 
-	return global.GetDB().Model(&ticket).Updates(map[string]interface{}{
+	fromStatus := ticket.Status
+	if err := global.GetDB().Model(&ticket).Updates(map[string]interface{}{
 		"status":          model.TicketStatusWithdrawn,
 		"current_node_id": nil,
-	}).Error
+	}).Error; err != nil {
+		return err
+	}
+	recordTicketEvent(id, model.TicketEventWithdrawn, fromStatus, model.TicketStatusWithdrawn, nil, userID)
+	return nil
 }
 
 // Urge 催办工单
@@ -599,7 +762,7 @@ func (s *TicketService) Urge(id, userID uint) error {
 
 	// 发送催办通知
 	approverIDs := s.getApproverIDs(ticket.CurrentNode, &ticket)
-	go s.notifySvc.NotifyTicketUrge(&ticket, approverIDs)
+	s.notifySvc.NotifyTicketUrge(&ticket, approverIDs)
 
 	return nil
 }
@@ -616,7 +779,11 @@ func (s *TicketService) Transfer(id, userID, targetUserID uint) error {
 		return errors.New("只有处理中的工单可以转交")
 	}
 
-	return global.GetDB().Model(&ticket).Update("assignee_id", targetUserID).Error
+	if err := global.GetDB().Model(&ticket).Update("assignee_id", targetUserID).Error; err != nil {
+		return err
+	}
+	recordTicketEvent(id, model.TicketEventReassigned, ticket.Status, ticket.Status, nil, userID)
+	return nil
 }
 
 // Return 退回工单
@@ -647,12 +814,10 @@ func (s *TicketService) Return(id, approverID uint, comment string, toCreator bo
 		return err
 	}
 
+	fromNodeID := *ticket.CurrentNodeID
 	if toCreator {
 		// 退回给发起人修改
-		return global.GetDB().Model(&ticket).Updates(map[string]interface{}{
-			"status":          model.TicketStatusDraft,
-			"current_node_id": nil,
-		}).Error
+		return s.returnToCreator(&ticket, fromNodeID, approverID)
 	}
 
 	// 退回到上一节点
@@ -666,13 +831,29 @@ func (s *TicketService) Return(id, approverID uint, comment string, toCreator bo
 		currentNode.FlowID, currentNode.SortOrder, []string{model.FlowNodeTypeCC, model.FlowNodeTypeCondition}).
 		Order("sort_order DESC").First(&prevNode).Error; err != nil {
 		// 没有上一节点，退回给发起人
-		return global.GetDB().Model(&ticket).Updates(map[string]interface{}{
-			"status":          model.TicketStatusDraft,
-			"current_node_id": nil,
-		}).Error
+		return s.returnToCreator(&ticket, fromNodeID, approverID)
 	}
 
-	return global.GetDB().Model(&ticket).Update("current_node_id", prevNode.ID).Error
+	if err := global.GetDB().Model(&ticket).Update("current_node_id", prevNode.ID).Error; err != nil {
+		return err
+	}
+	recordTicketEvent(id, model.TicketEventReturned, ticket.Status, ticket.Status, &fromNodeID, approverID)
+	recordTicketEvent(id, model.TicketEventNodeEntered, ticket.Status, ticket.Status, &prevNode.ID, approverID)
+	go s.notifyNodeApprovers(id, prevNode.ID)
+	return nil
+}
+
+// returnToCreator 退回给发起人修改（回到草稿状态）
+func (s *TicketService) returnToCreator(ticket *model.Ticket, fromNodeID, approverID uint) error {
+	fromStatus := ticket.Status
+	if err := global.GetDB().Model(ticket).Updates(map[string]interface{}{
+		"status":          model.TicketStatusDraft,
+		"current_node_id": nil,
+	}).Error; err != nil {
+		return err
+	}
+	recordTicketEvent(ticket.ID, model.TicketEventReturned, fromStatus, model.TicketStatusDraft, &fromNodeID, approverID)
+	return nil
 }
 
 // Delegate 转审工单
@@ -738,16 +919,25 @@ func (s *TicketService) Complete(id uint) error {
 	}).Error; err != nil {
 		return err
 	}
+	recordTicketEvent(id, model.TicketEventCompleted, model.TicketStatusProcessing, model.TicketStatusCompleted, nil, 0)
 	var ticket model.Ticket
 	if global.GetDB().First(&ticket, id).Error == nil {
-		go s.notifySvc.NotifyTicketCompleted(&ticket)
+		s.notifySvc.NotifyTicketCompleted(&ticket)
 	}
 	return nil
 }
 
 // Cancel 取消工单
 func (s *TicketService) Cancel(id uint) error {
-	return global.GetDB().Model(&model.Ticket{}).Where("id = ?", id).Update("status", model.TicketStatusCancelled).Error
+	var ticket model.Ticket
+	if err := global.GetDB().First(&ticket, id).Error; err != nil {
+		return err
+	}
+	if err := global.GetDB().Model(&ticket).Update("status", model.TicketStatusCancelled).Error; err != nil {
+		return err
+	}
+	recordTicketEvent(id, model.TicketEventCancelled, ticket.Status, model.TicketStatusCancelled, ticket.CurrentNodeID, 0)
+	return nil
 }
 
 // GetMyTickets 获取我创建的工单
@@ -925,10 +1115,49 @@ func (s *TicketService) CanUserApprove(ticketID, userID uint) (bool, error) {
 	return false, nil
 }
 
+// validateStoredFormData 提交前按工单绑定版本的表单字段校验已保存的表单数据，并清除隐藏字段的值
+func (s *TicketService) validateStoredFormData(ticket *model.Ticket) error {
+	fields, err := loadTicketFormFields(global.GetDB(), ticket)
+	if err != nil || len(fields) == 0 {
+		return nil
+	}
+	values, err := storedFormValues(ticket.ID)
+	if err != nil {
+		return err
+	}
+	if err := computeFormValues(fields, values); err != nil {
+		return err
+	}
+	hidden := hiddenFormFields(fields, values)
+	if err := validateFormValues(fields, values, hidden, formValidateSubmit); err != nil {
+		return err
+	}
+	// 丢弃隐藏字段的历史值
+	var hiddenIDs []uint
+	for _, field := range fields {
+		if hidden[field.Name] {
+			hiddenIDs = append(hiddenIDs, field.ID)
+		}
+	}
+	if len(hiddenIDs) > 0 {
+		return global.GetDB().Where("ticket_id = ? AND field_id IN ?", ticket.ID, hiddenIDs).Delete(&model.TicketData{}).Error
+	}
+	return nil
+}
+
 // SaveTicketData 保存工单表单数据
 func (s *TicketService) SaveTicketData(ticketID uint, data []model.TicketData) error {
+	if err := saveTicketData(global.GetDB(), ticketID, data); err != nil {
+		return err
+	}
+	reindexTicket(ticketID)
+	return nil
+}
+
+// saveTicketData 整体替换工单表单数据
+func saveTicketData(db *gorm.DB, ticketID uint, data []model.TicketData) error {
 	// 删除旧数据
-	if err := global.GetDB().Where("ticket_id = ?", ticketID).Delete(&model.TicketData{}).Error; err != nil {
+	if err := db.Where("ticket_id = ?", ticketID).Delete(&model.TicketData{}).Error; err != nil {
 		return err
 	}
 
@@ -936,8 +1165,13 @@ func (s *TicketService) SaveTicketData(ticketID uint, data []model.TicketData) e
 	for i := range data {
 		data[i].TicketID = ticketID
 	}
+	if err := projectTicketDataList(data); err != nil {
+		return err
+	}
 	if len(data) > 0 {
-		return global.GetDB().Create(&data).Error
+		if err := db.Create(&data).Error; err != nil {
+			return err
+		}
 	}
 	return nil
 }
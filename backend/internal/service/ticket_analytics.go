@@ -0,0 +1,566 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"backend/internal/global"
+	"backend/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// 趋势统计粒度
+const (
+	AnalyticsIntervalDay  = "day"
+	AnalyticsIntervalWeek = "week"
+)
+
+// defaultAnalyticsRange 未指定时间范围时默认统计最近 30 天
+const defaultAnalyticsRange = 30 * 24 * time.Hour
+
+// AnalyticsFilter 统计过滤条件
+type AnalyticsFilter struct {
+	Start    time.Time
+	End      time.Time
+	TypeID   uint
+	Interval string
+	Limit    int
+}
+
+// normalize 补齐默认时间范围、粒度和条数
+func (f *AnalyticsFilter) normalize() error {
+	if f.End.IsZero() {
+		f.End = time.Now()
+	}
+	if f.Start.IsZero() {
+		f.Start = f.End.Add(-defaultAnalyticsRange)
+	}
+	if !f.Start.Before(f.End) {
+		return errors.New("开始时间必须早于结束时间")
+	}
+	switch f.Interval {
+	case "":
+		f.Interval = AnalyticsIntervalDay
+	case AnalyticsIntervalDay, AnalyticsIntervalWeek:
+	default:
+		return errors.New("统计粒度仅支持 day 或 week")
+	}
+	if f.Limit <= 0 || f.Limit > 100 {
+		f.Limit = 20
+	}
+	return nil
+}
+
+// scopeType 按工单类型过滤（column 为工单 type_id 列）
+func (f *AnalyticsFilter) scopeType(db *gorm.DB, column string) *gorm.DB {
+	if f.TypeID > 0 {
+		db = db.Where(column+" = ?", f.TypeID)
+	}
+	return db
+}
+
+// TrendPoint 趋势数据点
+type TrendPoint struct {
+	Date      string `json:"date"`
+	Created   int64  `json:"created"`
+	Completed int64  `json:"completed"`
+}
+
+// DurationStats 耗时统计（单位：秒）
+type DurationStats struct {
+	Count int64   `json:"count"`
+	Avg   float64 `json:"avg"`
+	P50   float64 `json:"p50"`
+	P90   float64 `json:"p90"`
+	P95   float64 `json:"p95"`
+	Max   float64 `json:"max"`
+}
+
+// NodeDurationStat 审批节点耗时
+type NodeDurationStat struct {
+	NodeID   uint   `json:"node_id"`
+	NodeName string `json:"node_name"`
+	DurationStats
+}
+
+// ApproverDurationStat 审批人耗时
+type ApproverDurationStat struct {
+	ApproverID   uint   `json:"approver_id"`
+	ApproverName string `json:"approver_name"`
+	DurationStats
+}
+
+// AssigneeThroughput 处理人吞吐量
+type AssigneeThroughput struct {
+	AssigneeID   uint    `json:"assignee_id"`
+	AssigneeName string  `json:"assignee_name"`
+	Completed    int64   `json:"completed"`
+	Open         int64   `json:"open"`
+	AvgSeconds   float64 `json:"avg_seconds"` // 已完成工单从创建到完成的平均耗时
+}
+
+// NodeRejectionStat 节点拒绝/退回率
+type NodeRejectionStat struct {
+	NodeID        uint    `json:"node_id"`
+	NodeName      string  `json:"node_name"`
+	Total         int64   `json:"total"`
+	Approved      int64   `json:"approved"`
+	Rejected      int64   `json:"rejected"`
+	Returned      int64   `json:"returned"`
+	RejectionRate float64 `json:"rejection_rate"`
+	ReturnRate    float64 `json:"return_rate"`
+}
+
+// SlowTicket 滞留时间最长的未完结工单
+type SlowTicket struct {
+	ID              uint      `json:"id"`
+	Title           string    `json:"title"`
+	Status          string    `json:"status"`
+	TypeID          uint      `json:"type_id"`
+	CurrentNodeID   *uint     `json:"current_node_id"`
+	CurrentNodeName string    `json:"current_node_name"`
+	CreatedAt       time.Time `json:"created_at"`
+	AgeSeconds      float64   `json:"age_seconds"`  // 自创建起的时长
+	NodeSeconds     float64   `json:"node_seconds"` // 在当前节点/状态停留的时长
+}
+
+// TicketAnalyticsService 工单统计分析服务
+type TicketAnalyticsService struct{}
+
+// NewTicketAnalyticsService 创建工单统计分析服务
+func NewTicketAnalyticsService() *TicketAnalyticsService {
+	return &TicketAnalyticsService{}
+}
+
+// bucketStart 计算时间所属统计区间的起点（按周统计时以周一为起点）
+func bucketStart(t time.Time, interval string) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	if interval == AnalyticsIntervalWeek {
+		offset := (int(day.Weekday()) + 6) % 7
+		day = day.AddDate(0, 0, -offset)
+	}
+	return day
+}
+
+// Trends 按天/周统计新建与完成工单数量（空区间补零）
+func (s *TicketAnalyticsService) Trends(f AnalyticsFilter) ([]TrendPoint, error) {
+	if err := f.normalize(); err != nil {
+		return nil, err
+	}
+	var created []time.Time
+	if err := f.scopeType(global.GetDB().Model(&model.Ticket{}), "type_id").
+		Where("created_at >= ? AND created_at < ?", f.Start, f.End).
+		Pluck("created_at", &created).Error; err != nil {
+		return nil, err
+	}
+	var completed []time.Time
+	if err := f.scopeType(global.GetDB().Model(&model.Ticket{}), "type_id").
+		Where("completed_at >= ? AND completed_at < ?", f.Start, f.End).
+		Pluck("completed_at", &completed).Error; err != nil {
+		return nil, err
+	}
+
+	var points []TrendPoint
+	index := make(map[string]int)
+	for t := bucketStart(f.Start, f.Interval); t.Before(f.End); {
+		key := t.Format("2006-01-02")
+		index[key] = len(points)
+		points = append(points, TrendPoint{Date: key})
+		if f.Interval == AnalyticsIntervalWeek {
+			t = t.AddDate(0, 0, 7)
+		} else {
+			t = t.AddDate(0, 0, 1)
+		}
+	}
+	for _, t := range created {
+		if i, ok := index[bucketStart(t.In(f.Start.Location()), f.Interval).Format("2006-01-02")]; ok {
+			points[i].Created++
+		}
+	}
+	for _, t := range completed {
+		if i, ok := index[bucketStart(t.In(f.Start.Location()), f.Interval).Format("2006-01-02")]; ok {
+			points[i].Completed++
+		}
+	}
+	return points, nil
+}
+
+// approvalSpan 单次审批的耗时
+type approvalSpan struct {
+	NodeID     uint
+	ApproverID uint
+	Seconds    float64
+}
+
+// approvalSpans 计算时间范围内每条审批记录的耗时：
+// 从工单进入该节点（最近一次 node_entered 事件，缺失时取上一条审批记录或工单创建时间）到审批完成
+func (s *TicketAnalyticsService) approvalSpans(f AnalyticsFilter) ([]approvalSpan, error) {
+	var records []model.ApprovalRecord
+	db := global.GetDB().Model(&model.ApprovalRecord{}).
+		Joins("JOIN tickets ON tickets.id = approval_records.ticket_id AND tickets.deleted_at IS NULL").
+		Where("approval_records.created_at >= ? AND approval_records.created_at < ?", f.Start, f.End).
+		Where("approval_records.action IN ?", []string{model.ApprovalActionApprove, model.ApprovalActionReject, model.ApprovalActionReturn})
+	if err := f.scopeType(db, "tickets.type_id").Order("approval_records.created_at").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	ticketIDs := make([]uint, 0, len(records))
+	for _, r := range records {
+		ticketIDs = append(ticketIDs, r.TicketID)
+	}
+	ticketIDs = uniqueUints(ticketIDs)
+
+	var tickets []model.Ticket
+	if err := global.GetDB().Select("id", "created_at").Where("id IN ?", ticketIDs).Find(&tickets).Error; err != nil {
+		return nil, err
+	}
+	createdAt := make(map[uint]time.Time, len(tickets))
+	for _, t := range tickets {
+		createdAt[t.ID] = t.CreatedAt
+	}
+
+	var events []model.TicketEvent
+	if err := global.GetDB().Where("ticket_id IN ? AND event_type = ?", ticketIDs, model.TicketEventNodeEntered).
+		Order("created_at").Find(&events).Error; err != nil {
+		return nil, err
+	}
+	entered := make(map[uint][]model.TicketEvent)
+	for _, e := range events {
+		entered[e.TicketID] = append(entered[e.TicketID], e)
+	}
+
+	// 同一工单上一条审批记录时间（包括统计范围之前的记录）
+	var allRecords []model.ApprovalRecord
+	if err := global.GetDB().Select("id", "ticket_id", "created_at").
+		Where("ticket_id IN ? AND action IN ?", ticketIDs,
+			[]string{model.ApprovalActionApprove, model.ApprovalActionReject, model.ApprovalActionReturn}).
+		Order("created_at").Find(&allRecords).Error; err != nil {
+		return nil, err
+	}
+	previous := make(map[uint]time.Time, len(allRecords))
+	last := make(map[uint]time.Time)
+	for _, r := range allRecords {
+		if t, ok := last[r.TicketID]; ok {
+			previous[r.ID] = t
+		}
+		last[r.TicketID] = r.CreatedAt
+	}
+
+	spans := make([]approvalSpan, 0, len(records))
+	for _, r := range records {
+		start, ok := time.Time{}, false
+		for _, e := range entered[r.TicketID] {
+			if e.NodeID != nil && *e.NodeID == r.NodeID && !e.CreatedAt.After(r.CreatedAt) {
+				start, ok = e.CreatedAt, true
+			}
+		}
+		if !ok {
+			if t, found := previous[r.ID]; found {
+				start = t
+			} else {
+				start = createdAt[r.TicketID]
+			}
+		}
+		seconds := r.CreatedAt.Sub(start).Seconds()
+		if seconds < 0 {
+			seconds = 0
+		}
+		spans = append(spans, approvalSpan{NodeID: r.NodeID, ApproverID: r.ApproverID, Seconds: seconds})
+	}
+	return spans, nil
+}
+
+// summarizeDurations 计算平均值与分位数
+func summarizeDurations(values []float64) DurationStats {
+	if len(values) == 0 {
+		return DurationStats{}
+	}
+	sort.Float64s(values)
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return DurationStats{
+		Count: int64(len(values)),
+		Avg:   math.Round(sum/float64(len(values))*100) / 100,
+		P50:   percentile(values, 0.50),
+		P90:   percentile(values, 0.90),
+		P95:   percentile(values, 0.95),
+		Max:   values[len(values)-1],
+	}
+}
+
+// percentile 对已排序的数据按最近秩法取分位数
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+// NodeDurations 各审批节点的停留耗时
+func (s *TicketAnalyticsService) NodeDurations(f AnalyticsFilter) ([]NodeDurationStat, error) {
+	if err := f.normalize(); err != nil {
+		return nil, err
+	}
+	spans, err := s.approvalSpans(f)
+	if err != nil {
+		return nil, err
+	}
+	grouped := make(map[uint][]float64)
+	for _, sp := range spans {
+		grouped[sp.NodeID] = append(grouped[sp.NodeID], sp.Seconds)
+	}
+	names := nodeNames(mapKeys(grouped))
+	result := make([]NodeDurationStat, 0, len(grouped))
+	for nodeID, values := range grouped {
+		result = append(result, NodeDurationStat{NodeID: nodeID, NodeName: names[nodeID], DurationStats: summarizeDurations(values)})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Avg > result[j].Avg })
+	return result, nil
+}
+
+// ApproverDurations 各审批人的处理耗时
+func (s *TicketAnalyticsService) ApproverDurations(f AnalyticsFilter) ([]ApproverDurationStat, error) {
+	if err := f.normalize(); err != nil {
+		return nil, err
+	}
+	spans, err := s.approvalSpans(f)
+	if err != nil {
+		return nil, err
+	}
+	grouped := make(map[uint][]float64)
+	for _, sp := range spans {
+		grouped[sp.ApproverID] = append(grouped[sp.ApproverID], sp.Seconds)
+	}
+	names := userNames(mapKeys(grouped))
+	result := make([]ApproverDurationStat, 0, len(grouped))
+	for userID, values := range grouped {
+		result = append(result, ApproverDurationStat{ApproverID: userID, ApproverName: names[userID], DurationStats: summarizeDurations(values)})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Avg > result[j].Avg })
+	return result, nil
+}
+
+// AssigneeThroughput 各处理人在时间范围内完成的工单数、当前未完结数及平均完成耗时
+func (s *TicketAnalyticsService) AssigneeThroughput(f AnalyticsFilter) ([]AssigneeThroughput, error) {
+	if err := f.normalize(); err != nil {
+		return nil, err
+	}
+	var completed []model.Ticket
+	if err := f.scopeType(global.GetDB().Model(&model.Ticket{}), "type_id").
+		Select("id", "assignee_id", "created_at", "completed_at").
+		Where("assignee_id IS NOT NULL AND completed_at >= ? AND completed_at < ?", f.Start, f.End).
+		Find(&completed).Error; err != nil {
+		return nil, err
+	}
+	var open []struct {
+		AssigneeID uint
+		Count      int64
+	}
+	if err := f.scopeType(global.GetDB().Model(&model.Ticket{}), "type_id").
+		Select("assignee_id, count(*) as count").
+		Where("assignee_id IS NOT NULL AND status IN ?", openTicketStatuses).
+		Group("assignee_id").Scan(&open).Error; err != nil {
+		return nil, err
+	}
+
+	stats := make(map[uint]*AssigneeThroughput)
+	get := func(id uint) *AssigneeThroughput {
+		if stats[id] == nil {
+			stats[id] = &AssigneeThroughput{AssigneeID: id}
+		}
+		return stats[id]
+	}
+	totals := make(map[uint]float64)
+	for _, t := range completed {
+		item := get(*t.AssigneeID)
+		item.Completed++
+		totals[*t.AssigneeID] += t.CompletedAt.Sub(t.CreatedAt).Seconds()
+	}
+	for _, o := range open {
+		get(o.AssigneeID).Open = o.Count
+	}
+
+	ids := make([]uint, 0, len(stats))
+	for id := range stats {
+		ids = append(ids, id)
+	}
+	names := userNames(ids)
+	result := make([]AssigneeThroughput, 0, len(stats))
+	for id, item := range stats {
+		item.AssigneeName = names[id]
+		if item.Completed > 0 {
+			item.AvgSeconds = math.Round(totals[id]/float64(item.Completed)*100) / 100
+		}
+		result = append(result, *item)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Completed != result[j].Completed {
+			return result[i].Completed > result[j].Completed
+		}
+		return result[i].AssigneeID < result[j].AssigneeID
+	})
+	return result, nil
+}
+
+// NodeRejections 各审批节点的通过/拒绝/退回次数及比例
+func (s *TicketAnalyticsService) NodeRejections(f AnalyticsFilter) ([]NodeRejectionStat, error) {
+	if err := f.normalize(); err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		NodeID uint
+		Action string
+		Count  int64
+	}
+	db := global.GetDB().Model(&model.ApprovalRecord{}).
+		Select("approval_records.node_id, approval_records.action, count(*) as count").
+		Joins("JOIN tickets ON tickets.id = approval_records.ticket_id AND tickets.deleted_at IS NULL").
+		Where("approval_records.created_at >= ? AND approval_records.created_at < ?", f.Start, f.End).
+		Where("approval_records.action IN ?", []string{model.ApprovalActionApprove, model.ApprovalActionReject, model.ApprovalActionReturn})
+	if err := f.scopeType(db, "tickets.type_id").
+		Group("approval_records.node_id, approval_records.action").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	stats := make(map[uint]*NodeRejectionStat)
+	for _, r := range rows {
+		item := stats[r.NodeID]
+		if item == nil {
+			item = &NodeRejectionStat{NodeID: r.NodeID}
+			stats[r.NodeID] = item
+		}
+		switch r.Action {
+		case model.ApprovalActionApprove:
+			item.Approved += r.Count
+		case model.ApprovalActionReject:
+			item.Rejected += r.Count
+		case model.ApprovalActionReturn:
+			item.Returned += r.Count
+		}
+		item.Total += r.Count
+	}
+
+	names := nodeNames(mapKeys(stats))
+	result := make([]NodeRejectionStat, 0, len(stats))
+	for id, item := range stats {
+		item.NodeName = names[id]
+		if item.Total > 0 {
+			item.RejectionRate = math.Round(float64(item.Rejected)/float64(item.Total)*10000) / 10000
+			item.ReturnRate = math.Round(float64(item.Returned)/float64(item.Total)*10000) / 10000
+		}
+		result = append(result, *item)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].RejectionRate > result[j].RejectionRate })
+	return result, nil
+}
+
+// openTicketStatuses 未完结的工单状态
+var openTicketStatuses = []string{model.TicketStatusPending, model.TicketStatusApproving, model.TicketStatusProcessing}
+
+// SlowestOpen 停留时间最长的未完结工单（按在当前节点/状态的停留时长排序）
+func (s *TicketAnalyticsService) SlowestOpen(f AnalyticsFilter) ([]SlowTicket, error) {
+	if err := f.normalize(); err != nil {
+		return nil, err
+	}
+	var tickets []model.Ticket
+	if err := f.scopeType(global.GetDB().Model(&model.Ticket{}), "type_id").
+		Preload("CurrentNode").
+		Where("status IN ?", openTicketStatuses).
+		Find(&tickets).Error; err != nil {
+		return nil, err
+	}
+	if len(tickets) == 0 {
+		return []SlowTicket{}, nil
+	}
+
+	ids := make([]uint, 0, len(tickets))
+	for _, t := range tickets {
+		ids = append(ids, t.ID)
+	}
+	// 最近一次进入节点或状态变更的时间
+	var events []model.TicketEvent
+	if err := global.GetDB().Select("ticket_id", "event_type", "node_id", "created_at").
+		Where("ticket_id IN ? AND event_type IN ?", ids, []string{
+			model.TicketEventSubmitted, model.TicketEventNodeEntered, model.TicketEventApproved, model.TicketEventReturned,
+		}).Order("created_at").Find(&events).Error; err != nil {
+		return nil, err
+	}
+	since := make(map[uint]time.Time, len(events))
+	for _, e := range events {
+		since[e.TicketID] = e.CreatedAt
+	}
+
+	now := time.Now()
+	result := make([]SlowTicket, 0, len(tickets))
+	for _, t := range tickets {
+		item := SlowTicket{
+			ID:            t.ID,
+			Title:         t.Title,
+			Status:        t.Status,
+			TypeID:        t.TypeID,
+			CurrentNodeID: t.CurrentNodeID,
+			CreatedAt:     t.CreatedAt,
+			AgeSeconds:    math.Round(now.Sub(t.CreatedAt).Seconds()),
+		}
+		if t.CurrentNode != nil {
+			item.CurrentNodeName = t.CurrentNode.Name
+		}
+		enteredAt, ok := since[t.ID]
+		if !ok {
+			enteredAt = t.CreatedAt
+		}
+		item.NodeSeconds = math.Round(now.Sub(enteredAt).Seconds())
+		result = append(result, item)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].NodeSeconds > result[j].NodeSeconds })
+	if len(result) > f.Limit {
+		result = result[:f.Limit]
+	}
+	return result, nil
+}
+
+// mapKeys 返回 map 的全部键
+func mapKeys[V any](m map[uint]V) []uint {
+	keys := make([]uint, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// nodeNames 批量查询节点名称
+func nodeNames(ids []uint) map[uint]string {
+	names := make(map[uint]string, len(ids))
+	if len(ids) == 0 {
+		return names
+	}
+	var nodes []model.FlowNode
+	global.GetDB().Unscoped().Select("id", "name").Where("id IN ?", ids).Find(&nodes)
+	for _, n := range nodes {
+		names[n.ID] = n.Name
+	}
+	return names
+}
+
+// userNames 批量查询用户名
+func userNames(ids []uint) map[uint]string {
+	names := make(map[uint]string, len(ids))
+	if len(ids) == 0 {
+		return names
+	}
+	var users []model.User
+	global.GetDB().Unscoped().Select("id", "username").Where("id IN ?", ids).Find(&users)
+	for _, u := range users {
+		names[u.ID] = u.Username
+	}
+	return names
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"errors"
+
+	"backend/internal/global"
+	"backend/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// BatchItemResult 批量操作中单个工单的执行结果
+type BatchItemResult struct {
+	TicketID uint   `json:"ticket_id"`
+	Success  bool   `json:"success"`
+	Error    string `json:"error,omitempty"`
+}
+
+// BatchResult 批量操作结果
+type BatchResult struct {
+	Total     int               `json:"total"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+	Items     []BatchItemResult `json:"items"`
+}
+
+// runBatch 逐个执行批量操作，单个失败不影响其他工单
+func runBatch(ticketIDs []uint, fn func(ticketID uint) error) *BatchResult {
+	result := &BatchResult{Items: make([]BatchItemResult, 0, len(ticketIDs))}
+	seen := make(map[uint]bool, len(ticketIDs))
+	for _, id := range ticketIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		item := BatchItemResult{TicketID: id, Success: true}
+		if err := fn(id); err != nil {
+			item.Success = false
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				item.Error = "工单不存在"
+			} else {
+				item.Error = err.Error()
+			}
+			result.Failed++
+		} else {
+			result.Succeeded++
+		}
+		result.Items = append(result.Items, item)
+	}
+	result.Total = len(result.Items)
+	return result
+}
+
+// canView 检查用户是否可以查看工单
+func (s *TicketService) canView(ticketID, userID uint) bool {
+	var count int64
+	global.GetDB().Model(&model.Ticket{}).Where("tickets.id = ?", ticketID).
+		Scopes(s.ScopeVisibleTo(userID)).Count(&count)
+	return count > 0
+}
+
+// BatchApprove 批量审批（通过或拒绝），使用同一审批意见
+func (s *TicketService) BatchApprove(userID uint, isAdmin bool, ticketIDs []uint, approved bool, comment string) *BatchResult {
+	return runBatch(ticketIDs, func(id uint) error {
+		if !isAdmin {
+			canApprove, err := s.CanUserApprove(id, userID)
+			if err != nil {
+				return err
+			}
+			if !canApprove {
+				return errors.New("您没有审批此工单的权限")
+			}
+		}
+		return s.Approve(id, userID, approved, comment, nil)
+	})
+}
+
+// BatchCancel 批量取消工单，仅创建人或管理员可操作
+func (s *TicketService) BatchCancel(userID uint, isAdmin bool, ticketIDs []uint) *BatchResult {
+	return runBatch(ticketIDs, func(id uint) error {
+		ticket, err := s.GetByID(id)
+		if err != nil {
+			return err
+		}
+		if !isAdmin && ticket.CreatorID != userID {
+			return errors.New("只有创建者可以取消工单")
+		}
+		if ticket.Status == model.TicketStatusCompleted || ticket.Status == model.TicketStatusCancelled {
+			return errors.New("工单已结束，无法取消")
+		}
+		return s.Cancel(id)
+	})
+}
+
+// BatchReassign 批量转交处理人，仅当前处理人或管理员可操作
+func (s *TicketService) BatchReassign(userID uint, isAdmin bool, ticketIDs []uint, targetUserID uint) *BatchResult {
+	return runBatch(ticketIDs, func(id uint) error {
+		ticket, err := s.GetByID(id)
+		if err != nil {
+			return err
+		}
+		if !isAdmin && (ticket.AssigneeID == nil || *ticket.AssigneeID != userID) {
+			return errors.New("只有当前处理人可以转交工单")
+		}
+		return s.Transfer(id, userID, targetUserID)
+	})
+}
+
+// BatchSetPriority 批量修改优先级，仅创建人或管理员可操作
+func (s *TicketService) BatchSetPriority(userID uint, isAdmin bool, ticketIDs []uint, priority int) *BatchResult {
+	return runBatch(ticketIDs, func(id uint) error {
+		ticket, err := s.GetByID(id)
+		if err != nil {
+			return err
+		}
+		if !isAdmin && ticket.CreatorID != userID {
+			return errors.New("只有创建者可以修改优先级")
+		}
+		// 优先级不属于表单内容，流转中的工单也可修改
+		return s.update(ticket, &model.Ticket{Priority: priority}, nil)
+	})
+}
+
+// BatchAddWatchers 批量添加关注人，操作人需能查看该工单
+func (s *TicketService) BatchAddWatchers(userID uint, isAdmin bool, ticketIDs []uint, watcherIDs []uint) *BatchResult {
+	return runBatch(ticketIDs, func(id uint) error {
+		if _, err := s.GetByID(id); err != nil {
+			return err
+		}
+		if !isAdmin && !s.canView(id, userID) {
+			return errors.New("您没有查看此工单的权限")
+		}
+		return s.AddWatchers(id, userID, watcherIDs)
+	})
+}
+
+// AddWatchers 添加工单关注人，已关注的用户忽略
+func (s *TicketService) AddWatchers(ticketID, addedByID uint, watcherIDs []uint) error {
+	var count int64
+	global.GetDB().Model(&model.User{}).Where("id IN ?", watcherIDs).Count(&count)
+	if int(count) != len(uniqueUints(watcherIDs)) {
+		return errors.New("关注人不存在")
+	}
+
+	return global.GetDB().Transaction(func(tx *gorm.DB) error {
+		for _, uid := range uniqueUints(watcherIDs) {
+			var existing model.TicketWatcher
+			err := tx.Unscoped().Where("ticket_id = ? AND user_id = ?", ticketID, uid).First(&existing).Error
+			if err == nil {
+				// 已移除的关注人重新恢复
+				if existing.DeletedAt.Valid {
+					if err := tx.Unscoped().Model(&existing).Update("deleted_at", nil).Error; err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			if err := tx.Create(&model.TicketWatcher{TicketID: ticketID, UserID: uid, AddedByID: addedByID}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetWatchers 获取工单关注人
+func (s *TicketService) GetWatchers(ticketID uint) ([]model.TicketWatcher, error) {
+	var watchers []model.TicketWatcher
+	if err := global.GetDB().Preload("User").Where("ticket_id = ?", ticketID).
+		Order("created_at ASC").Find(&watchers).Error; err != nil {
+		return nil, err
+	}
+	return watchers, nil
+}
+
+func uniqueUints(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
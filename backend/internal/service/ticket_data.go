@@ -0,0 +1,338 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"backend/internal/global"
+	"backend/internal/model"
+)
+
+// ticketDataStrMaxRunes 字符串投影的最大长度（与 str_value 列宽一致）
+const ticketDataStrMaxRunes = 255
+
+// ticketDataBackfillBatch 历史数据回填每批处理的行数
+const ticketDataBackfillBatch = 500
+
+// formTimeLayouts 表单日期/时间值支持的格式
+var formTimeLayouts = []string{
+	"2006-01-02",
+	"2006-01-02T15:04",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02 15:04:05",
+}
+
+// formValueString 将前端提交的表单值转为存储用的原始字符串
+func formValueString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	default:
+		// 多选、数字等其他类型转为 JSON
+		jsonBytes, _ := json.Marshal(v)
+		return string(jsonBytes)
+	}
+}
+
+// unquoteFormValue 去掉历史数据中 JSON 序列化产生的引号，如 "\"12.5\"" -> "12.5"
+func unquoteFormValue(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if len(raw) >= 2 && raw[0] == '"' && raw[len(raw)-1] == '"' {
+		var s string
+		if err := json.Unmarshal([]byte(raw), &s); err == nil {
+			return strings.TrimSpace(s)
+		}
+	}
+	return raw
+}
+
+// parseFormTime 解析表单日期/时间值
+func parseFormTime(s string) (time.Time, bool) {
+	for _, layout := range formTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, true
+		}
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+// ProjectTicketData 按字段类型解析原始值，填充类型化投影列
+func ProjectTicketData(field *model.FormField, d *model.TicketData) {
+	d.ValueType = model.TicketDataValueNone
+	d.NumValue, d.TimeValue, d.UserValue, d.StrValue = nil, nil, nil, ""
+
+	raw := unquoteFormValue(d.Value)
+	if raw == "" || field == nil {
+		return
+	}
+
+	switch field.FieldType {
+	case model.FormFieldTypeNumber, model.FormFieldTypeMoney, model.FormFieldTypeFormula:
+		if n, err := strconv.ParseFloat(raw, 64); err == nil {
+			d.NumValue = &n
+			d.ValueType = model.TicketDataValueNumber
+		}
+	case model.FormFieldTypeDate, model.FormFieldTypeDatetime:
+		if t, ok := parseFormTime(raw); ok {
+			d.TimeValue = &t
+			d.ValueType = model.TicketDataValueTime
+		}
+	case model.FormFieldTypeUser:
+		// 仅单个用户可投影，多个用户保留原始值
+		if id, err := strconv.ParseUint(raw, 10, 64); err == nil && id > 0 {
+			uid := uint(id)
+			d.UserValue = &uid
+			d.ValueType = model.TicketDataValueUser
+		}
+	case model.FormFieldTypeText, model.FormFieldTypeTextarea, model.FormFieldTypeSelect:
+		if utf8.RuneCountInString(raw) > ticketDataStrMaxRunes {
+			raw = string([]rune(raw)[:ticketDataStrMaxRunes])
+		}
+		d.StrValue = raw
+		d.ValueType = model.TicketDataValueString
+	}
+}
+
+// projectTicketDataList 为一组表单数据加载字段定义并填充投影
+func projectTicketDataList(data []model.TicketData) error {
+	if len(data) == 0 {
+		return nil
+	}
+	fieldIDs := make([]uint, 0, len(data))
+	for _, d := range data {
+		fieldIDs = append(fieldIDs, d.FieldID)
+	}
+	var fields []model.FormField
+	if err := global.GetDB().Unscoped().Where("id IN ?", fieldIDs).Find(&fields).Error; err != nil {
+		return err
+	}
+	fieldMap := make(map[uint]*model.FormField, len(fields))
+	for i := range fields {
+		fieldMap[fields[i].ID] = &fields[i]
+	}
+	for i := range data {
+		ProjectTicketData(fieldMap[data[i].FieldID], &data[i])
+	}
+	return nil
+}
+
+// BackfillTicketDataValues 回填历史表单数据的类型化投影，返回处理的行数
+func BackfillTicketDataValues() (int, error) {
+	count := 0
+	var lastID uint
+	for {
+		var rows []model.TicketData
+		if err := global.GetDB().Where("id > ? AND (value_type = '' OR value_type IS NULL)", lastID).
+			Order("id ASC").Limit(ticketDataBackfillBatch).Find(&rows).Error; err != nil {
+			return count, err
+		}
+		if len(rows) == 0 {
+			break
+		}
+		if err := projectTicketDataList(rows); err != nil {
+			return count, err
+		}
+		for _, row := range rows {
+			if err := global.GetDB().Model(&model.TicketData{}).Where("id = ?", row.ID).Updates(map[string]interface{}{
+				"value_type": row.ValueType,
+				"num_value":  row.NumValue,
+				"time_value": row.TimeValue,
+				"str_value":  row.StrValue,
+				"user_value": row.UserValue,
+			}).Error; err != nil {
+				return count, err
+			}
+			count++
+		}
+		lastID = rows[len(rows)-1].ID
+	}
+	return count, nil
+}
+
+// FormFieldAggregate 表单数值/时间字段的统计结果
+type FormFieldAggregate struct {
+	Field   string     `json:"field"`
+	Count   int64      `json:"count"`
+	Sum     *float64   `json:"sum,omitempty"`
+	Avg     *float64   `json:"avg,omitempty"`
+	Min     *float64   `json:"min,omitempty"`
+	Max     *float64   `json:"max,omitempty"`
+	MinTime *time.Time `json:"min_time,omitempty"`
+	MaxTime *time.Time `json:"max_time,omitempty"`
+}
+
+// AggregateFormField 统计表单字段的类型化值（可按工单类型、状态过滤）
+func (s *TicketService) AggregateFormField(fieldName string, typeID uint, status string) (*FormFieldAggregate, error) {
+	if fieldName == "" {
+		return nil, errors.New("字段标识不能为空")
+	}
+	db := global.GetDB().Table("ticket_data td").
+		Joins("JOIN form_fields ff ON ff.id = td.field_id").
+		Joins("JOIN tickets t ON t.id = td.ticket_id AND t.deleted_at IS NULL").
+		Where("td.deleted_at IS NULL AND ff.name = ? AND td.value_type IN ?", fieldName,
+			[]string{model.TicketDataValueNumber, model.TicketDataValueTime})
+	if typeID > 0 {
+		db = db.Where("t.type_id = ?", typeID)
+	}
+	if status != "" {
+		db = db.Where("t.status = ?", status)
+	}
+
+	var row struct {
+		Count   int64
+		Sum     *float64
+		Avg     *float64
+		Min     *float64
+		Max     *float64
+		MinTime *time.Time
+		MaxTime *time.Time
+	}
+	if err := db.Select("COUNT(*) AS count, SUM(td.num_value) AS sum, AVG(td.num_value) AS avg, " +
+		"MIN(td.num_value) AS min, MAX(td.num_value) AS max, " +
+		"MIN(td.time_value) AS min_time, MAX(td.time_value) AS max_time").Scan(&row).Error; err != nil {
+		return nil, err
+	}
+	return &FormFieldAggregate{
+		Field:   fieldName,
+		Count:   row.Count,
+		Sum:     row.Sum,
+		Avg:     row.Avg,
+		Min:     row.Min,
+		Max:     row.Max,
+		MinTime: row.MinTime,
+		MaxTime: row.MaxTime,
+	}, nil
+}
+
+// FieldOption 表单选项
+type FieldOption struct {
+	Value string `json:"value"`
+	Label string `json:"label"`
+}
+
+// parseFieldOptions 解析字段选项配置：支持每行一个选项的文本、字符串数组 JSON 或 {value,label} 数组 JSON
+func parseFieldOptions(options string) []FieldOption {
+	options = strings.TrimSpace(options)
+	if options == "" {
+		return nil
+	}
+	if strings.HasPrefix(options, "[") {
+		var objects []FieldOption
+		if err := json.Unmarshal([]byte(options), &objects); err == nil {
+			for i := range objects {
+				if objects[i].Label == "" {
+					objects[i].Label = objects[i].Value
+				}
+			}
+			return objects
+		}
+		var values []string
+		if err := json.Unmarshal([]byte(options), &values); err == nil {
+			result := make([]FieldOption, 0, len(values))
+			for _, v := range values {
+				result = append(result, FieldOption{Value: v, Label: v})
+			}
+			return result
+		}
+	}
+	var result []FieldOption
+	for _, line := range strings.Split(options, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			result = append(result, FieldOption{Value: line, Label: line})
+		}
+	}
+	return result
+}
+
+// userNameCache 导出等批量场景下缓存用户ID到用户名的映射
+type userNameCache struct {
+	names map[uint]string
+}
+
+func newUserNameCache() *userNameCache {
+	return &userNameCache{names: make(map[uint]string)}
+}
+
+// Get 获取用户名，未知用户返回ID本身
+func (c *userNameCache) Get(id uint) string {
+	if name, ok := c.names[id]; ok {
+		return name
+	}
+	var user model.User
+	name := strconv.FormatUint(uint64(id), 10)
+	if err := global.GetDB().Select("id", "username").First(&user, id).Error; err == nil {
+		name = user.Username
+	}
+	c.names[id] = name
+	return name
+}
+
+// splitFormValues 将多值字段（JSON 数组或逗号分隔）拆分为单个值
+func splitFormValues(raw string) []string {
+	raw = strings.TrimSpace(raw)
+	if strings.HasPrefix(raw, "[") {
+		var items []interface{}
+		if err := json.Unmarshal([]byte(raw), &items); err == nil {
+			result := make([]string, 0, len(items))
+			for _, item := range items {
+				result = append(result, strings.TrimSpace(fmt.Sprint(item)))
+			}
+			return result
+		}
+	}
+	var result []string
+	for _, part := range strings.Split(unquoteFormValue(raw), ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			result = append(result, part)
+		}
+	}
+	return result
+}
+
+// displayFormValue 将表单原始值转为显示文本：用户ID解析为用户名，选项值解析为选项名称
+func displayFormValue(field *model.FormField, raw string, users *userNameCache) string {
+	if strings.TrimSpace(raw) == "" {
+		return ""
+	}
+	switch field.FieldType {
+	case model.FormFieldTypeUser:
+		var names []string
+		for _, v := range splitFormValues(raw) {
+			if id, err := strconv.ParseUint(v, 10, 64); err == nil {
+				names = append(names, users.Get(uint(id)))
+			} else {
+				names = append(names, v)
+			}
+		}
+		return strings.Join(names, ", ")
+	case model.FormFieldTypeSelect, model.FormFieldTypeMultiSelect:
+		labels := make(map[string]string)
+		for _, opt := range cachedFieldOptions(field) {
+			labels[opt.Value] = opt.Label
+		}
+		var result []string
+		for _, v := range splitFormValues(raw) {
+			if label, ok := labels[v]; ok {
+				result = append(result, label)
+			} else {
+				result = append(result, v)
+			}
+		}
+		return strings.Join(result, ", ")
+	case model.FormFieldTypeTable:
+		return displayTableValue(field, raw)
+	default:
+		return unquoteFormValue(raw)
+	}
+}
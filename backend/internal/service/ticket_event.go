@@ -0,0 +1,28 @@
+package service
+
+import (
+	"backend/internal/global"
+	"backend/internal/model"
+	"backend/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// recordTicketEvent 记录工单生命周期事件并推送 Webhook（失败只记录日志，不影响业务流程）
+func recordTicketEvent(ticketID uint, eventType, fromStatus, toStatus string, nodeID *uint, actorID uint) {
+	event := model.TicketEvent{
+		TicketID:   ticketID,
+		EventType:  eventType,
+		NodeID:     nodeID,
+		FromStatus: fromStatus,
+		ToStatus:   toStatus,
+	}
+	if actorID > 0 {
+		event.ActorID = &actorID
+	}
+	if err := global.GetDB().Create(&event).Error; err != nil {
+		logger.Warn("Failed to record ticket event", zap.Uint("ticket_id", ticketID),
+			zap.String("event", eventType), zap.Error(err))
+	}
+	enqueueWebhookEvent(ticketID, eventType, nodeID, actorID, nil)
+}
@@ -0,0 +1,483 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/global"
+	"backend/internal/model"
+	"backend/pkg/logger"
+	"backend/pkg/tabular"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// exportSyncLimit 超过该行数的导出转为后台任务
+	exportSyncLimit = 2000
+	// exportBatchSize 导出时每批加载的工单数
+	exportBatchSize = 200
+	// exportURLExpiry 导出文件下载链接有效期
+	exportURLExpiry = 30 * time.Minute
+	// exportHeartbeatInterval 执行中任务更新心跳的间隔
+	exportHeartbeatInterval = time.Minute
+	// exportHeartbeatTimeout 执行中任务超过该时长未更新心跳视为中断（如实例退出），重新排队
+	exportHeartbeatTimeout = 5 * time.Minute
+)
+
+// ticketStatusLabels 工单状态显示名称
+var ticketStatusLabels = map[string]string{
+	model.TicketStatusDraft:      "草稿",
+	model.TicketStatusPending:    "待审批",
+	model.TicketStatusApproving:  "审批中",
+	model.TicketStatusApproved:   "已通过",
+	model.TicketStatusRejected:   "已拒绝",
+	model.TicketStatusWithdrawn:  "已撤回",
+	model.TicketStatusProcessing: "处理中",
+	model.TicketStatusCompleted:  "已完成",
+	model.TicketStatusCancelled:  "已取消",
+}
+
+// ticketPriorityLabels 工单优先级显示名称
+var ticketPriorityLabels = map[int]string{
+	model.TicketPriorityLow:    "低",
+	model.TicketPriorityMedium: "中",
+	model.TicketPriorityHigh:   "高",
+	model.TicketPriorityUrgent: "紧急",
+}
+
+// approvalActionLabels 审批操作显示名称
+var approvalActionLabels = map[string]string{
+	model.ApprovalActionApprove:  "通过",
+	model.ApprovalActionReject:   "拒绝",
+	model.ApprovalActionReturn:   "退回",
+	model.ApprovalActionDelegate: "转审",
+	model.ApprovalActionAddSign:  "加签",
+}
+
+// TicketExportFilter 导出过滤条件（与工单列表一致）
+type TicketExportFilter struct {
+	Keyword   string `json:"keyword"`
+	Status    string `json:"status"`
+	TypeID    uint   `json:"type_id"`
+	CreatorID uint   `json:"creator_id"`
+	// 导出人及其权限，后台任务执行时按导出人的可见范围过滤
+	UserID  uint `json:"user_id"`
+	IsAdmin bool `json:"is_admin"`
+}
+
+// TicketExportService 工单导出服务
+type TicketExportService struct {
+	attachmentSvc *AttachmentService
+}
+
+// NewTicketExportService 创建工单导出服务
+func NewTicketExportService() *TicketExportService {
+	return &TicketExportService{attachmentSvc: NewAttachmentService()}
+}
+
+// scope 与工单列表相同的过滤条件：管理员可按创建人过滤，普通用户只能导出自己创建的工单
+func (s *TicketExportService) scope(f *TicketExportFilter) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if !f.IsAdmin {
+			db = db.Where("creator_id = ?", f.UserID)
+		} else if f.CreatorID > 0 {
+			db = db.Where("creator_id = ?", f.CreatorID)
+		}
+		db = db.Scopes(scopeTicketKeyword(f.Keyword))
+		if f.Status != "" {
+			db = db.Where("status = ?", f.Status)
+		}
+		if f.TypeID > 0 {
+			db = db.Where("type_id = ?", f.TypeID)
+		}
+		return db
+	}
+}
+
+// Count 统计待导出的工单数量
+func (s *TicketExportService) Count(f *TicketExportFilter) (int64, error) {
+	var count int64
+	err := global.GetDB().Model(&model.Ticket{}).Scopes(s.scope(f)).Count(&count).Error
+	return count, err
+}
+
+// ShouldRunAsync 判断导出是否需要转为后台任务
+func (s *TicketExportService) ShouldRunAsync(f *TicketExportFilter) (bool, error) {
+	count, err := s.Count(f)
+	if err != nil {
+		return false, err
+	}
+	return count > exportSyncLimit, nil
+}
+
+// FileName 生成导出文件名
+func (s *TicketExportService) FileName(format string) string {
+	return fmt.Sprintf("tickets_%s.%s", time.Now().Format("20060102150405"), format)
+}
+
+// Export 将工单写入表格，返回导出的行数
+func (s *TicketExportService) Export(f *TicketExportFilter, format string, w io.Writer) (int, error) {
+	writer, err := tabular.NewWriter(format, w)
+	if err != nil {
+		return 0, err
+	}
+
+	fields, err := s.exportFields(f.TypeID)
+	if err != nil {
+		return 0, err
+	}
+
+	header := []string{"工单ID", "工单编号", "标题", "类型", "状态", "优先级", "创建人", "处理人", "当前节点", "创建时间", "审批完成时间", "完成时间"}
+	for _, field := range fields {
+		header = append(header, field.Label)
+	}
+	header = append(header, "审批记录")
+	if err := writer.Write(header); err != nil {
+		return 0, err
+	}
+
+	users := newUserNameCache()
+	count := 0
+	var lastID uint
+	for {
+		var tickets []model.Ticket
+		if err := global.GetDB().Model(&model.Ticket{}).Scopes(s.scope(f)).
+			Preload("Type").Preload("Creator").Preload("Assignee").Preload("CurrentNode").
+			Preload("Data").Preload("Data.Field", func(db *gorm.DB) *gorm.DB {
+				return db.Unscoped().Select("id", "name")
+			}).Preload("ApprovalRecords", func(db *gorm.DB) *gorm.DB {
+				return db.Where("action NOT IN ?", []string{model.ApprovalActionCC, model.ApprovalActionUrge}).Order("created_at ASC")
+			}).Preload("ApprovalRecords.Approver").Preload("ApprovalRecords.DelegateTo").
+			Where("tickets.id > ?", lastID).Order("tickets.id ASC").Limit(exportBatchSize).
+			Find(&tickets).Error; err != nil {
+			return count, err
+		}
+		if len(tickets) == 0 {
+			break
+		}
+		for i := range tickets {
+			if err := writer.Write(s.buildRow(&tickets[i], fields, users)); err != nil {
+				return count, err
+			}
+			count++
+		}
+		lastID = tickets[len(tickets)-1].ID
+	}
+
+	if err := writer.Close(); err != nil {
+		return count, err
+	}
+	return count, nil
+}
+
+// exportFields 获取工单类型表单模板的字段（未指定类型时不导出表单列）
+func (s *TicketExportService) exportFields(typeID uint) ([]model.FormField, error) {
+	if typeID == 0 {
+		return nil, nil
+	}
+	var ticketType model.TicketType
+	if err := global.GetDB().First(&ticketType, typeID).Error; err != nil {
+		return nil, err
+	}
+	if ticketType.TemplateID == nil {
+		return nil, nil
+	}
+	var fields []model.FormField
+	if err := global.GetDB().Where("template_id = ? AND field_type <> ?", *ticketType.TemplateID, model.FormFieldTypeAttachment).
+		Order("sort_order ASC, id ASC").Find(&fields).Error; err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// buildRow 生成一行导出数据
+func (s *TicketExportService) buildRow(t *model.Ticket, fields []model.FormField, users *userNameCache) []string {
+	assignee := ""
+	if t.Assignee != nil {
+		assignee = t.Assignee.Username
+	}
+	currentNode := ""
+	if t.CurrentNode != nil {
+		currentNode = t.CurrentNode.Name
+	}
+	status := ticketStatusLabels[t.Status]
+	if status == "" {
+		status = t.Status
+	}
+
+	row := []string{
+		strconv.FormatUint(uint64(t.ID), 10),
+		t.Number,
+		t.Title,
+		t.Type.Name,
+		status,
+		ticketPriorityLabels[t.Priority],
+		t.Creator.Username,
+		assignee,
+		currentNode,
+		formatExportTime(&t.CreatedAt),
+		formatExportTime(approvalFinishedAt(t)),
+		formatExportTime(t.CompletedAt),
+	}
+
+	// 按字段标识匹配，模板字段修改前创建的工单数据引用的是旧版本字段
+	named := make(map[string]string, len(t.Data))
+	for _, d := range t.Data {
+		if d.Field != nil {
+			named[d.Field.Name] = d.Value
+		}
+	}
+	// 按显示条件隐藏的字段不导出（兼容校验上线前保存的历史数据）
+	hidden := hiddenFormFields(fields, named)
+	for i := range fields {
+		if hidden[fields[i].Name] {
+			row = append(row, "")
+			continue
+		}
+		row = append(row, displayFormValue(&fields[i], named[fields[i].Name], users))
+	}
+
+	return append(row, approvalTrailSummary(t.ApprovalRecords))
+}
+
+// approvalFinishedAt 审批流程结束时间（通过后进入处理/完成，或被拒绝时的最后一条审批记录）
+func approvalFinishedAt(t *model.Ticket) *time.Time {
+	switch t.Status {
+	case model.TicketStatusProcessing, model.TicketStatusCompleted, model.TicketStatusApproved, model.TicketStatusRejected:
+	default:
+		return nil
+	}
+	for i := len(t.ApprovalRecords) - 1; i >= 0; i-- {
+		r := t.ApprovalRecords[i]
+		if r.Action == model.ApprovalActionApprove || r.Action == model.ApprovalActionReject {
+			return &r.CreatedAt
+		}
+	}
+	return nil
+}
+
+// approvalTrailSummary 审批记录摘要：时间 审批人 操作（意见），多条以分号分隔
+func approvalTrailSummary(records []model.ApprovalRecord) string {
+	parts := make([]string, 0, len(records))
+	for _, r := range records {
+		action := approvalActionLabels[r.Action]
+		if action == "" {
+			action = r.Action
+		}
+		item := fmt.Sprintf("%s %s %s", r.CreatedAt.Format("2006-01-02 15:04"), r.Approver.Username, action)
+		if r.DelegateTo != nil {
+			item += "→" + r.DelegateTo.Username
+		}
+		if r.Comment != "" {
+			item += "（" + r.Comment + "）"
+		}
+		parts = append(parts, item)
+	}
+	return strings.Join(parts, "; ")
+}
+
+func formatExportTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+// CreateJob 创建后台导出任务
+func (s *TicketExportService) CreateJob(f *TicketExportFilter, format string) (*model.TicketExportJob, error) {
+	filter, err := json.Marshal(f)
+	if err != nil {
+		return nil, err
+	}
+	job := &model.TicketExportJob{
+		CreatorID: f.UserID,
+		Format:    format,
+		Filter:    string(filter),
+		Status:    model.TicketExportStatusPending,
+		FileName:  s.FileName(format),
+	}
+	if err := global.GetDB().Create(job).Error; err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// ListJobs 获取用户的导出任务
+func (s *TicketExportService) ListJobs(userID uint, page, pageSize int) ([]model.TicketExportJob, int64, error) {
+	var jobs []model.TicketExportJob
+	var total int64
+	db := global.GetDB().Model(&model.TicketExportJob{}).Where("creator_id = ?", userID)
+	db.Count(&total)
+	offset := (page - 1) * pageSize
+	if err := db.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&jobs).Error; err != nil {
+		return nil, 0, err
+	}
+	return jobs, total, nil
+}
+
+// GetJob 获取导出任务（仅创建人可查看）
+func (s *TicketExportService) GetJob(id, userID uint) (*model.TicketExportJob, error) {
+	var job model.TicketExportJob
+	if err := global.GetDB().Where("id = ? AND creator_id = ?", id, userID).First(&job).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// GetJobDownloadURL 获取导出结果下载链接
+func (s *TicketExportService) GetJobDownloadURL(ctx context.Context, id, userID uint) (string, error) {
+	job, err := s.GetJob(id, userID)
+	if err != nil {
+		return "", err
+	}
+	if job.Status != model.TicketExportStatusCompleted || job.StoragePath == "" {
+		return "", errors.New("导出任务尚未完成")
+	}
+	provider, err := s.attachmentSvc.getStorageProvider()
+	if err != nil {
+		return "", err
+	}
+	return provider.GetSignedURL(ctx, job.StoragePath, exportURLExpiry)
+}
+
+// RunPendingJobs 执行等待中的导出任务
+func (s *TicketExportService) RunPendingJobs() {
+	s.requeueStaleJobs()
+	for {
+		var job model.TicketExportJob
+		if err := global.GetDB().Where("status = ?", model.TicketExportStatusPending).
+			Order("id ASC").First(&job).Error; err != nil {
+			return
+		}
+		// 抢占任务，避免多实例重复执行
+		res := global.GetDB().Model(&model.TicketExportJob{}).
+			Where("id = ? AND status = ?", job.ID, model.TicketExportStatusPending).
+			Updates(map[string]interface{}{"status": model.TicketExportStatusRunning, "heartbeat_at": time.Now()})
+		if res.Error != nil {
+			logger.Error("Failed to claim export job", zap.Uint("job_id", job.ID), zap.Error(res.Error))
+			return
+		}
+		if res.RowsAffected == 0 {
+			continue
+		}
+		s.runJob(&job)
+	}
+}
+
+// requeueStaleJobs 心跳超时的执行中任务（执行实例已退出）重新置为等待，其他实例正在执行的任务不受影响
+func (s *TicketExportService) requeueStaleJobs() {
+	res := global.GetDB().Model(&model.TicketExportJob{}).
+		Where("status = ? AND (heartbeat_at IS NULL OR heartbeat_at < ?)",
+			model.TicketExportStatusRunning, time.Now().Add(-exportHeartbeatTimeout)).
+		Update("status", model.TicketExportStatusPending)
+	if res.Error != nil {
+		logger.Error("Failed to requeue stale export jobs", zap.Error(res.Error))
+		return
+	}
+	if res.RowsAffected > 0 {
+		logger.Info("Requeued interrupted export jobs", zap.Int64("count", res.RowsAffected))
+	}
+}
+
+// heartbeat 执行期间定期更新任务心跳，返回停止函数
+func (s *TicketExportService) heartbeat(jobID uint) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(exportHeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				global.GetDB().Model(&model.TicketExportJob{}).
+					Where("id = ? AND status = ?", jobID, model.TicketExportStatusRunning).
+					Update("heartbeat_at", time.Now())
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// runJob 执行单个导出任务：写入临时文件后上传到存储服务
+func (s *TicketExportService) runJob(job *model.TicketExportJob) {
+	stop := s.heartbeat(job.ID)
+	updates, err := s.exportToStorage(job)
+	stop()
+	now := time.Now()
+	if updates == nil {
+		updates = map[string]interface{}{}
+	}
+	updates["finished_at"] = &now
+	if err != nil {
+		logger.Error("Ticket export job failed", zap.Uint("job_id", job.ID), zap.Error(err))
+		updates["status"] = model.TicketExportStatusFailed
+		updates["error"] = err.Error()
+	} else {
+		updates["status"] = model.TicketExportStatusCompleted
+	}
+	global.GetDB().Model(&model.TicketExportJob{}).Where("id = ?", job.ID).Updates(updates)
+}
+
+func (s *TicketExportService) exportToStorage(job *model.TicketExportJob) (map[string]interface{}, error) {
+	var f TicketExportFilter
+	if err := json.Unmarshal([]byte(job.Filter), &f); err != nil {
+		return nil, fmt.Errorf("过滤条件解析失败: %w", err)
+	}
+
+	provider, err := s.attachmentSvc.getStorageProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.CreateTemp("", "ticket-export-*."+job.Format)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	count, err := s.Export(&f, job.Format, tmp)
+	if err != nil {
+		return nil, err
+	}
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	storagePath := fmt.Sprintf("exports/%d/%s.%s", job.CreatorID, uuid.New().String(), job.Format)
+	if _, err := provider.Upload(context.Background(), tmp, storagePath, size); err != nil {
+		return nil, fmt.Errorf("文件上传失败: %w", err)
+	}
+	return map[string]interface{}{
+		"row_count":    count,
+		"storage_path": storagePath,
+	}, nil
+}
+
+// TicketExportJobRunner 工单后台导出任务
+type TicketExportJobRunner struct{}
+
+// Name 返回任务名称
+func (j *TicketExportJobRunner) Name() string {
+	return "ticket_export"
+}
+
+// Run 执行等待中的导出任务
+func (j *TicketExportJobRunner) Run() {
+	NewTicketExportService().RunPendingJobs()
+}
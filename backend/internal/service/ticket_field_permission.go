@@ -0,0 +1,266 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"backend/internal/global"
+	"backend/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// ParseFieldPermissions 解析节点字段权限配置
+func ParseFieldPermissions(s string) (map[string]string, error) {
+	perms := make(map[string]string)
+	if strings.TrimSpace(s) == "" {
+		return perms, nil
+	}
+	if err := json.Unmarshal([]byte(s), &perms); err != nil {
+		return nil, errors.New("字段权限必须是 JSON 对象")
+	}
+	for name, perm := range perms {
+		switch perm {
+		case model.FieldPermissionHidden, model.FieldPermissionReadOnly, model.FieldPermissionEditable, model.FieldPermissionRequired:
+		default:
+			return nil, fmt.Errorf("字段 %s 的权限无效: %s", name, perm)
+		}
+	}
+	return perms, nil
+}
+
+// fieldPermission 字段在节点上的权限，未配置时只读
+func fieldPermission(perms map[string]string, name string) string {
+	if perm, ok := perms[name]; ok {
+		return perm
+	}
+	return model.FieldPermissionReadOnly
+}
+
+// NodeFieldPermissions 当前节点的字段权限
+type NodeFieldPermissions struct {
+	NodeID      *uint             `json:"node_id"`
+	Permissions map[string]string `json:"permissions"` // 字段标识 -> 权限
+}
+
+// GetNodeFieldPermissions 获取工单当前节点对表单各字段的权限（不在审批中时全部只读）
+func (s *TicketService) GetNodeFieldPermissions(ticketID uint) (*NodeFieldPermissions, error) {
+	var ticket model.Ticket
+	if err := global.GetDB().First(&ticket, ticketID).Error; err != nil {
+		return nil, errors.New("工单不存在")
+	}
+	fields, err := loadTicketFormFields(global.GetDB(), &ticket)
+	if err != nil {
+		return nil, err
+	}
+	perms := map[string]string{}
+	result := &NodeFieldPermissions{Permissions: make(map[string]string, len(fields))}
+	if ticket.CurrentNodeID != nil {
+		var node model.FlowNode
+		if err := global.GetDB().First(&node, *ticket.CurrentNodeID).Error; err == nil {
+			result.NodeID = &node.ID
+			perms, _ = ParseFieldPermissions(node.FieldPermissions)
+		}
+	}
+	for _, field := range fields {
+		result.Permissions[field.Name] = fieldPermission(perms, field.Name)
+	}
+	return result, nil
+}
+
+// nodeHiddenFields 工单当前节点上配置为隐藏的字段
+func nodeHiddenFields(ticket *model.Ticket) map[string]bool {
+	if ticket.CurrentNodeID == nil {
+		return nil
+	}
+	var node model.FlowNode
+	if err := global.GetDB().Select("id", "field_permissions").First(&node, *ticket.CurrentNodeID).Error; err != nil {
+		return nil
+	}
+	perms, err := ParseFieldPermissions(node.FieldPermissions)
+	if err != nil {
+		return nil
+	}
+	var hidden map[string]bool
+	for name, perm := range perms {
+		if perm == model.FieldPermissionHidden {
+			if hidden == nil {
+				hidden = make(map[string]bool)
+			}
+			hidden[name] = true
+		}
+	}
+	return hidden
+}
+
+// hiddenFieldsFor 对当前节点审批人（非创建人、非管理员）隐藏的字段
+func (s *TicketService) hiddenFieldsFor(ticket *model.Ticket, userID uint, isAdmin bool) map[string]bool {
+	if isAdmin || ticket.CreatorID == userID {
+		return nil
+	}
+	hidden := nodeHiddenFields(ticket)
+	if len(hidden) == 0 {
+		return nil
+	}
+	if canApprove, _ := s.CanUserApprove(ticket.ID, userID); !canApprove {
+		return nil
+	}
+	return hidden
+}
+
+// ApplyNodeFieldVisibility 当前节点审批人（非创建人、非管理员）查看工单时移除节点上隐藏的字段
+func (s *TicketService) ApplyNodeFieldVisibility(ticket *model.Ticket, userID uint, isAdmin bool) {
+	hidden := s.hiddenFieldsFor(ticket, userID, isAdmin)
+	if len(hidden) == 0 {
+		return
+	}
+	visible := ticket.Data[:0]
+	for _, d := range ticket.Data {
+		if d.Field != nil && hidden[d.Field.Name] {
+			continue
+		}
+		visible = append(visible, d)
+	}
+	ticket.Data = visible
+}
+
+// applyApproverFormData 在审批事务中校验并保存审批人在当前节点修改的表单字段，记录修改明细。
+// 审批通过时，节点上必填的字段必须有值
+func (s *TicketService) applyApproverFormData(tx *gorm.DB, ticket *model.Ticket, node *model.FlowNode, approverID uint, approved bool, formData map[string]interface{}) error {
+	perms, err := ParseFieldPermissions(node.FieldPermissions)
+	if err != nil {
+		return err
+	}
+	hasRequired := false
+	for _, perm := range perms {
+		hasRequired = hasRequired || perm == model.FieldPermissionRequired
+	}
+	if len(formData) == 0 && !(approved && hasRequired) {
+		return nil
+	}
+	fields, err := loadTicketFormFields(tx, ticket)
+	if err != nil {
+		return err
+	}
+	stored, err := storedFormValues(ticket.ID)
+	if err != nil {
+		return err
+	}
+	values := formDataStrings(formData)
+	merged := make(map[string]string, len(stored)+len(values))
+	for name, value := range stored {
+		merged[name] = value
+	}
+	for name, value := range values {
+		merged[name] = value
+	}
+	if err := computeFormValues(fields, merged); err != nil {
+		return err
+	}
+	hidden := hiddenFormFields(fields, merged)
+
+	var fieldErrors []FieldError
+	fieldMap := make(map[string]*model.FormField, len(fields))
+	for i := range fields {
+		field := &fields[i]
+		fieldMap[field.Name] = field
+		perm := fieldPermission(perms, field.Name)
+		if _, ok := values[field.Name]; ok && perm != model.FieldPermissionEditable && perm != model.FieldPermissionRequired {
+			fieldErrors = append(fieldErrors, FieldError{Field: field.Name, Label: field.Label, Message: "当前节点不可编辑"})
+		}
+		if approved && perm == model.FieldPermissionRequired && !hidden[field.Name] && unquoteFormValue(merged[field.Name]) == "" {
+			fieldErrors = append(fieldErrors, FieldError{Field: field.Name, Label: field.Label, Message: "必填"})
+		}
+	}
+	// 提交的字段使用计算后的值，公式字段随之更新
+	for _, field := range fields {
+		if _, ok := values[field.Name]; ok || field.FieldType == model.FormFieldTypeFormula {
+			values[field.Name] = merged[field.Name]
+		}
+	}
+	if err := validateFormValues(fields, values, hidden, formValidateUpdate); err != nil {
+		var fe *FormValidationError
+		if !errors.As(err, &fe) {
+			return err
+		}
+		fieldErrors = append(fieldErrors, fe.Errors...)
+	}
+	if len(fieldErrors) > 0 {
+		return &FormValidationError{Errors: fieldErrors}
+	}
+
+	var changes []model.TicketDataChange
+	changed := make(map[uint]string)
+	for name, value := range values {
+		field := fieldMap[name]
+		if unquoteFormValue(stored[name]) == unquoteFormValue(value) {
+			continue
+		}
+		changed[field.ID] = value
+		changes = append(changes, model.TicketDataChange{
+			TicketID:    ticket.ID,
+			NodeID:      &node.ID,
+			FieldID:     field.ID,
+			FieldName:   field.Name,
+			FieldLabel:  field.Label,
+			OldValue:    stored[name],
+			NewValue:    value,
+			ChangedByID: approverID,
+		})
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	// 按字段替换原有数据（保留已删除字段的历史值），整体保存
+	var existing []model.TicketData
+	if err := tx.Where("ticket_id = ?", ticket.ID).Find(&existing).Error; err != nil {
+		return err
+	}
+	data := make([]model.TicketData, 0, len(existing)+len(changed))
+	for _, d := range existing {
+		if _, ok := changed[d.FieldID]; ok {
+			continue
+		}
+		data = append(data, model.TicketData{FieldID: d.FieldID, Value: d.Value})
+	}
+	for fieldID, value := range changed {
+		if unquoteFormValue(value) != "" {
+			data = append(data, model.TicketData{FieldID: fieldID, Value: value})
+		}
+	}
+	if err := saveTicketData(tx, ticket.ID, data); err != nil {
+		return err
+	}
+	if err := tx.Create(&changes).Error; err != nil {
+		return err
+	}
+	// 刷新表单数据，供后续分支条件和审批人计算使用
+	return tx.Preload("Field").Where("ticket_id = ?", ticket.ID).Find(&ticket.Data).Error
+}
+
+// GetDataChanges 工单表单数据修改记录，不返回当前节点对该用户隐藏的字段
+func (s *TicketService) GetDataChanges(ticketID, userID uint, isAdmin bool) ([]model.TicketDataChange, error) {
+	var ticket model.Ticket
+	if err := global.GetDB().First(&ticket, ticketID).Error; err != nil {
+		return nil, errors.New("工单不存在")
+	}
+	var changes []model.TicketDataChange
+	if err := global.GetDB().Preload("ChangedBy").Where("ticket_id = ?", ticketID).
+		Order("created_at ASC").Find(&changes).Error; err != nil {
+		return nil, err
+	}
+	hidden := s.hiddenFieldsFor(&ticket, userID, isAdmin)
+	if len(hidden) == 0 {
+		return changes, nil
+	}
+	visible := changes[:0]
+	for _, c := range changes {
+		if !hidden[c.FieldName] {
+			visible = append(visible, c)
+		}
+	}
+	return visible, nil
+}
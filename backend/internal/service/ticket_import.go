@@ -0,0 +1,449 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"backend/internal/global"
+	"backend/internal/model"
+	"backend/pkg/tabular"
+)
+
+const (
+	// importMaxRows 单次导入的最大数据行数
+	importMaxRows = 1000
+)
+
+// 导入列映射的目标字段
+const (
+	ImportTargetTitle       = "title"
+	ImportTargetDescription = "description"
+	ImportTargetPriority    = "priority"
+	ImportTargetCreator     = "creator"
+	ImportTargetAssignee    = "assignee"
+)
+
+// importHeaderAliases 未指定映射时按表头自动识别的工单字段
+var importHeaderAliases = map[string]string{
+	"title":       ImportTargetTitle,
+	"标题":          ImportTargetTitle,
+	"description": ImportTargetDescription,
+	"描述":          ImportTargetDescription,
+	"priority":    ImportTargetPriority,
+	"优先级":         ImportTargetPriority,
+	"creator":     ImportTargetCreator,
+	"创建人":         ImportTargetCreator,
+	"assignee":    ImportTargetAssignee,
+	"处理人":         ImportTargetAssignee,
+}
+
+// TicketImportOptions 导入选项
+type TicketImportOptions struct {
+	TypeID  uint
+	Mapping map[string]string // 表头 -> 目标（title/description/priority/creator/assignee 或 form.<字段标识>）
+	DryRun  bool              // 只校验不创建
+	Submit  bool              // 创建后提交进入审批流程，否则保留为草稿
+	UserID  uint
+	IsAdmin bool
+}
+
+// TicketImportRow 单行导入结果
+type TicketImportRow struct {
+	Row      int      `json:"row"` // 表格中的行号（表头为第 1 行）
+	Title    string   `json:"title"`
+	TicketID uint     `json:"ticket_id,omitempty"`
+	Errors   []string `json:"errors,omitempty"`
+}
+
+// TicketImportResult 导入结果
+type TicketImportResult struct {
+	DryRun    bool              `json:"dry_run"`
+	Total     int               `json:"total"`
+	Valid     int               `json:"valid"`
+	Invalid   int               `json:"invalid"`
+	Created   int               `json:"created"`
+	Submitted int               `json:"submitted"`
+	Rows      []TicketImportRow `json:"rows"`
+}
+
+// importRow 校验通过的待导入数据
+type importRow struct {
+	result   *TicketImportRow
+	ticket   model.Ticket
+	formData map[string]interface{}
+}
+
+// TicketImportService 工单导入服务
+type TicketImportService struct {
+	ticketSvc *TicketService
+}
+
+// NewTicketImportService 创建工单导入服务
+func NewTicketImportService() *TicketImportService {
+	return &TicketImportService{ticketSvc: NewTicketService()}
+}
+
+// Import 从 CSV/XLSX 导入工单
+//
+// 先校验全部数据行，存在校验错误时不创建任何工单；校验通过后逐行创建，
+// 按选项提交审批，提交失败的工单保留为草稿并在对应行返回错误。
+func (s *TicketImportService) Import(fileName string, file io.Reader, opts *TicketImportOptions) (*TicketImportResult, error) {
+	format, err := tabular.FormatFromFileName(fileName)
+	if err != nil {
+		return nil, errors.New("只支持 CSV 或 XLSX 文件")
+	}
+	rows, err := tabular.ReadAll(format, file)
+	if err != nil {
+		return nil, fmt.Errorf("文件解析失败: %w", err)
+	}
+	if len(rows) < 2 {
+		return nil, errors.New("文件中没有数据行")
+	}
+	if len(rows)-1 > importMaxRows {
+		return nil, fmt.Errorf("单次最多导入 %d 行", importMaxRows)
+	}
+
+	var ticketType model.TicketType
+	if err := global.GetDB().First(&ticketType, opts.TypeID).Error; err != nil {
+		return nil, errors.New("工单类型不存在")
+	}
+	if !ticketType.Enabled {
+		return nil, errors.New("工单类型已停用")
+	}
+	// 与创建工单使用相同的字段定义和校验规则，校验通过的行创建时不会再被拒绝
+	formFields, err := loadTypeFormFields(global.GetDB(), opts.TypeID)
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]*model.FormField, len(formFields))
+	for i := range formFields {
+		fields[formFields[i].Name] = &formFields[i]
+	}
+
+	columns, err := s.resolveColumns(rows[0], opts.Mapping, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	// 预分配容量，保证 valid 中引用的行结果地址不变
+	result := &TicketImportResult{DryRun: opts.DryRun, Rows: make([]TicketImportRow, 0, len(rows)-1)}
+	users := newUserLookup()
+	var valid []importRow
+	for i, row := range rows[1:] {
+		if isBlankRow(row) {
+			continue
+		}
+		item := s.parseRow(i+2, row, columns, formFields, fields, users, opts)
+		result.Rows = append(result.Rows, *item.result)
+		item.result = &result.Rows[len(result.Rows)-1]
+		if len(item.result.Errors) > 0 {
+			result.Invalid++
+			continue
+		}
+		result.Valid++
+		valid = append(valid, item)
+	}
+	result.Total = len(result.Rows)
+
+	if opts.DryRun || result.Invalid > 0 || len(valid) == 0 {
+		return result, nil
+	}
+
+	for _, item := range valid {
+		ticket := item.ticket
+		if err := s.ticketSvc.CreateWithFormData(&ticket, item.formData); err != nil {
+			item.result.Errors = append(item.result.Errors, "创建失败: "+err.Error())
+			continue
+		}
+		item.result.TicketID = ticket.ID
+		result.Created++
+		if opts.Submit {
+			if err := s.ticketSvc.Submit(ticket.ID); err != nil {
+				item.result.Errors = append(item.result.Errors, "提交失败（已保存为草稿）: "+err.Error())
+				continue
+			}
+			result.Submitted++
+		}
+	}
+	return result, nil
+}
+
+// resolveColumns 将表头映射为导入目标，未映射的列忽略
+func (s *TicketImportService) resolveColumns(header []string, mapping map[string]string, fields map[string]*model.FormField) ([]string, error) {
+	// 表单字段可按标识或显示名称自动识别
+	labels := make(map[string]string, len(fields))
+	for name, f := range fields {
+		labels[strings.ToLower(f.Label)] = name
+		labels[strings.ToLower(name)] = name
+	}
+
+	columns := make([]string, len(header))
+	hasTitle := false
+	for i, h := range header {
+		h = strings.TrimSpace(h)
+		target := ""
+		if len(mapping) > 0 {
+			target = mapping[h]
+		} else if alias, ok := importHeaderAliases[strings.ToLower(h)]; ok {
+			target = alias
+		} else if name, ok := labels[strings.ToLower(h)]; ok {
+			target = filterFormPrefix + name
+		}
+		if target == "" {
+			continue
+		}
+		if strings.HasPrefix(target, filterFormPrefix) {
+			if _, ok := fields[strings.TrimPrefix(target, filterFormPrefix)]; !ok {
+				return nil, fmt.Errorf("列 %s 映射的表单字段 %s 不存在", h, target)
+			}
+		} else if !isImportTicketTarget(target) {
+			return nil, fmt.Errorf("列 %s 的映射目标 %s 不支持", h, target)
+		}
+		if target == ImportTargetTitle {
+			hasTitle = true
+		}
+		columns[i] = target
+	}
+	if !hasTitle {
+		return nil, errors.New("缺少标题列")
+	}
+	return columns, nil
+}
+
+func isImportTicketTarget(target string) bool {
+	switch target {
+	case ImportTargetTitle, ImportTargetDescription, ImportTargetPriority, ImportTargetCreator, ImportTargetAssignee:
+		return true
+	}
+	return false
+}
+
+// parseRow 校验并转换一行数据，表单数据按创建工单的规则校验（公式计算、显示条件、校验规则）
+func (s *TicketImportService) parseRow(rowNum int, row []string, columns []string, formFields []model.FormField, fields map[string]*model.FormField, users *userLookup, opts *TicketImportOptions) importRow {
+	result := &TicketImportRow{Row: rowNum}
+	item := importRow{
+		result: result,
+		ticket: model.Ticket{
+			TypeID:    opts.TypeID,
+			Priority:  model.TicketPriorityMedium,
+			CreatorID: opts.UserID,
+			Status:    model.TicketStatusDraft,
+		},
+		formData: make(map[string]interface{}),
+	}
+	addErr := func(format string, args ...interface{}) {
+		result.Errors = append(result.Errors, fmt.Sprintf(format, args...))
+	}
+	failed := make(map[string]bool) // 单元格转换失败的字段，不再重复报告校验错误
+
+	for i, target := range columns {
+		if target == "" {
+			continue
+		}
+		value := ""
+		if i < len(row) {
+			value = strings.TrimSpace(row[i])
+		}
+
+		switch target {
+		case ImportTargetTitle:
+			item.ticket.Title = value
+			result.Title = value
+		case ImportTargetDescription:
+			item.ticket.Description = value
+		case ImportTargetPriority:
+			if value == "" {
+				continue
+			}
+			p, ok := parseImportPriority(value)
+			if !ok {
+				addErr("优先级无效: %s", value)
+				continue
+			}
+			item.ticket.Priority = p
+		case ImportTargetCreator:
+			if value == "" {
+				continue
+			}
+			id, ok := users.Resolve(value)
+			if !ok {
+				addErr("创建人不存在: %s", value)
+				continue
+			}
+			if id != opts.UserID && !opts.IsAdmin {
+				addErr("只有管理员可以指定其他创建人")
+				continue
+			}
+			item.ticket.CreatorID = id
+		case ImportTargetAssignee:
+			if value == "" {
+				continue
+			}
+			id, ok := users.Resolve(value)
+			if !ok {
+				addErr("处理人不存在: %s", value)
+				continue
+			}
+			item.ticket.AssigneeID = &id
+		default:
+			field := fields[strings.TrimPrefix(target, filterFormPrefix)]
+			v, err := convertImportValue(field, value, users)
+			if err != nil {
+				addErr("%s: %s", field.Label, err.Error())
+				failed[field.Name] = true
+				continue
+			}
+			if v != nil {
+				item.formData[field.Name] = v
+			}
+		}
+	}
+
+	if item.ticket.Title == "" {
+		addErr("标题不能为空")
+	} else if len([]rune(item.ticket.Title)) > 200 {
+		addErr("标题不能超过 200 个字符")
+	}
+
+	values := formDataStrings(item.formData)
+	if err := computeFormValues(formFields, values); err != nil {
+		addErr("%s", err.Error())
+		return item
+	}
+	hidden := hiddenFormFields(formFields, values)
+	if err := validateFormValues(formFields, values, hidden, formValidateCreate); err != nil {
+		var fe *FormValidationError
+		if !errors.As(err, &fe) {
+			addErr("%s", err.Error())
+			return item
+		}
+		for _, e := range fe.Errors {
+			if failed[e.Field] {
+				continue
+			}
+			addErr("%s: %s", e.Label, e.Message)
+		}
+	}
+	return item
+}
+
+// convertImportValue 按字段类型校验并转换单元格值，空值返回 nil
+func convertImportValue(field *model.FormField, value string, users *userLookup) (interface{}, error) {
+	if value == "" {
+		return nil, nil
+	}
+	switch field.FieldType {
+	case model.FormFieldTypeNumber, model.FormFieldTypeMoney:
+		n, err := strconv.ParseFloat(strings.ReplaceAll(value, ",", ""), 64)
+		if err != nil {
+			return nil, fmt.Errorf("不是有效的数字: %s", value)
+		}
+		return strconv.FormatFloat(n, 'f', -1, 64), nil
+	case model.FormFieldTypeDate:
+		t, ok := parseFormTime(value)
+		if !ok {
+			return nil, fmt.Errorf("不是有效的日期: %s", value)
+		}
+		return t.Format("2006-01-02"), nil
+	case model.FormFieldTypeDatetime:
+		t, ok := parseFormTime(value)
+		if !ok {
+			return nil, fmt.Errorf("不是有效的时间: %s", value)
+		}
+		return t.Format("2006-01-02T15:04"), nil
+	case model.FormFieldTypeUser:
+		id, ok := users.Resolve(value)
+		if !ok {
+			return nil, fmt.Errorf("用户不存在: %s", value)
+		}
+		return strconv.FormatUint(uint64(id), 10), nil
+	case model.FormFieldTypeSelect:
+		opt, ok := matchFieldOption(field, value)
+		if !ok {
+			return nil, fmt.Errorf("选项无效: %s", value)
+		}
+		return opt, nil
+	case model.FormFieldTypeMultiSelect:
+		var selected []interface{}
+		for _, part := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == '，' || r == '、' }) {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			opt, ok := matchFieldOption(field, part)
+			if !ok {
+				return nil, fmt.Errorf("选项无效: %s", part)
+			}
+			selected = append(selected, opt)
+		}
+		return selected, nil
+	case model.FormFieldTypeAttachment:
+		return nil, errors.New("附件字段不支持导入")
+	default:
+		return value, nil
+	}
+}
+
+// matchFieldOption 按选项值或名称匹配，返回选项值
+func matchFieldOption(field *model.FormField, value string) (string, bool) {
+	for _, opt := range parseFieldOptions(field.Options) {
+		if opt.Value == value || opt.Label == value {
+			return opt.Value, true
+		}
+	}
+	return "", false
+}
+
+// parseImportPriority 解析优先级：数字 1-4 或 低/中/高/紧急
+func parseImportPriority(value string) (int, bool) {
+	if p, err := strconv.Atoi(value); err == nil {
+		return p, p >= model.TicketPriorityLow && p <= model.TicketPriorityUrgent
+	}
+	for p, label := range ticketPriorityLabels {
+		if label == value {
+			return p, true
+		}
+	}
+	return 0, false
+}
+
+func isBlankRow(row []string) bool {
+	for _, v := range row {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// userLookup 按用户名、邮箱或ID查找用户（带缓存）
+type userLookup struct {
+	ids map[string]uint
+}
+
+func newUserLookup() *userLookup {
+	return &userLookup{ids: make(map[string]uint)}
+}
+
+// Resolve 解析用户，返回用户ID
+func (l *userLookup) Resolve(value string) (uint, bool) {
+	if id, ok := l.ids[value]; ok {
+		return id, id > 0
+	}
+	var user model.User
+	db := global.GetDB().Select("id")
+	if id, err := strconv.ParseUint(value, 10, 64); err == nil {
+		db = db.Where("id = ?", id)
+	} else {
+		db = db.Where("username = ? OR email = ?", value, value)
+	}
+	if err := db.First(&user).Error; err != nil {
+		l.ids[value] = 0
+		return 0, false
+	}
+	l.ids[value] = user.ID
+	return user.ID, true
+}
@@ -0,0 +1,230 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/model"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// ticketNumberMaxSkips 分配编号时跳过已被占用编号的最大次数
+	ticketNumberMaxSkips = 100
+	// ticketNumberRetries 编号唯一索引冲突时整个创建事务的最大执行次数
+	ticketNumberRetries = 3
+)
+
+// ticketNumberToken 编号格式中的占位符：{yyyy} {yy} {mm} {dd} {seq} {seq:N}
+var ticketNumberToken = regexp.MustCompile(`\{([a-z]+)(?::(\d+))?\}`)
+
+// ValidateTicketNumberFormat 校验工单编号格式与重置周期、生成时机
+func ValidateTicketNumberFormat(t *model.TicketType) error {
+	if t.NumberReset == "" {
+		t.NumberReset = model.TicketNumberResetNever
+	}
+	switch t.NumberReset {
+	case model.TicketNumberResetNever, model.TicketNumberResetYearly, model.TicketNumberResetMonthly, model.TicketNumberResetDaily:
+	default:
+		return fmt.Errorf("不支持的编号重置周期: %s", t.NumberReset)
+	}
+	if t.NumberOn == "" {
+		t.NumberOn = model.TicketNumberOnCreate
+	}
+	switch t.NumberOn {
+	case model.TicketNumberOnCreate, model.TicketNumberOnSubmit:
+	default:
+		return fmt.Errorf("不支持的编号生成时机: %s", t.NumberOn)
+	}
+	if t.NumberFormat == "" {
+		return nil
+	}
+
+	hasSeq := false
+	for _, m := range ticketNumberToken.FindAllStringSubmatch(t.NumberFormat, -1) {
+		switch m[1] {
+		case "seq":
+			hasSeq = true
+			if m[2] != "" {
+				if width, _ := strconv.Atoi(m[2]); width < 1 || width > 12 {
+					return errors.New("序号位数必须在 1-12 之间")
+				}
+			}
+		case "yyyy", "yy", "mm", "dd":
+			if m[2] != "" {
+				return fmt.Errorf("编号占位符 {%s} 不支持指定位数", m[1])
+			}
+		default:
+			return fmt.Errorf("不支持的编号占位符: {%s}", m[1])
+		}
+	}
+	if !hasSeq {
+		return errors.New("编号格式必须包含序号占位符 {seq}")
+	}
+	if len(formatTicketNumber(t.NumberFormat, time.Now(), 1<<40)) > 64 {
+		return errors.New("编号格式过长")
+	}
+	return nil
+}
+
+// ticketNumberPeriod 计算序号所属的重置周期
+func ticketNumberPeriod(reset string, now time.Time) string {
+	switch reset {
+	case model.TicketNumberResetYearly:
+		return now.Format("2006")
+	case model.TicketNumberResetMonthly:
+		return now.Format("200601")
+	case model.TicketNumberResetDaily:
+		return now.Format("20060102")
+	default:
+		return ""
+	}
+}
+
+// formatTicketNumber 按格式生成编号
+func formatTicketNumber(format string, now time.Time, seq int64) string {
+	return ticketNumberToken.ReplaceAllStringFunc(format, func(token string) string {
+		m := ticketNumberToken.FindStringSubmatch(token)
+		switch m[1] {
+		case "yyyy":
+			return now.Format("2006")
+		case "yy":
+			return now.Format("06")
+		case "mm":
+			return now.Format("01")
+		case "dd":
+			return now.Format("02")
+		case "seq":
+			if m[2] != "" {
+				return fmt.Sprintf("%0"+m[2]+"d", seq)
+			}
+			return strconv.FormatInt(seq, 10)
+		}
+		return token
+	})
+}
+
+// nextTicketNumber 在事务内分配下一个编号。序号行加行锁，与工单写入在同一事务提交，
+// 事务回滚时序号一并回滚，保证编号连续且并发安全；类型未配置编号格式时返回空字符串
+func nextTicketNumber(tx *gorm.DB, typeID uint) (string, error) {
+	var ticketType model.TicketType
+	if err := tx.Select("id", "number_format", "number_reset").First(&ticketType, typeID).Error; err != nil {
+		return "", err
+	}
+	if ticketType.NumberFormat == "" {
+		return "", nil
+	}
+
+	now := time.Now()
+	period := ticketNumberPeriod(ticketType.NumberReset, now)
+
+	// 首次使用时创建序号行，并发创建由唯一索引去重
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.TicketNumberSequence{TypeID: typeID, Period: period}).Error; err != nil {
+		return "", err
+	}
+	var seq model.TicketNumberSequence
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("type_id = ? AND period = ?", typeID, period).First(&seq).Error; err != nil {
+		return "", err
+	}
+	// 不同类型或周期的格式可能生成相同编号，跳过已被占用的编号（含已删除工单）
+	for i := 0; i < ticketNumberMaxSkips; i++ {
+		seq.Value++
+		number := formatTicketNumber(ticketType.NumberFormat, now, seq.Value)
+		var count int64
+		if err := tx.Unscoped().Model(&model.Ticket{}).Where("number = ?", number).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count > 0 {
+			continue
+		}
+		if err := tx.Model(&seq).Update("value", seq.Value).Error; err != nil {
+			return "", err
+		}
+		return number, nil
+	}
+	return "", errors.New("无法分配工单编号，请检查编号格式是否与其他类型重复")
+}
+
+// isTicketNumberConflict 是否为工单编号唯一索引冲突
+func isTicketNumberConflict(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 && strings.Contains(mysqlErr.Message, "idx_ticket_number")
+}
+
+// withTicketNumberRetry 执行分配编号的事务，并发分配到相同编号导致唯一索引冲突时恢复工单状态后重试
+func withTicketNumberRetry(ticket *model.Ticket, fn func() error) error {
+	id, number := ticket.ID, ticket.Number
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= ticketNumberRetries || !isTicketNumberConflict(err) {
+			return err
+		}
+		ticket.ID, ticket.Number = id, number
+	}
+}
+
+// assignTicketNumber 为尚无编号的工单分配编号（生成时机与类型配置一致时）
+func assignTicketNumber(tx *gorm.DB, ticket *model.Ticket, on string) error {
+	if ticket.Number != "" || ticket.TypeID == 0 {
+		return nil
+	}
+	var ticketType model.TicketType
+	if err := tx.Select("id", "number_on").First(&ticketType, ticket.TypeID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	numberOn := ticketType.NumberOn
+	if numberOn == "" {
+		numberOn = model.TicketNumberOnCreate
+	}
+	if numberOn != on {
+		return nil
+	}
+	if ticket.ID > 0 {
+		// 锁定工单行并重新读取，并发提交时只有一方分配编号
+		var current model.Ticket
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "number").
+			First(&current, ticket.ID).Error; err != nil {
+			return err
+		}
+		if current.Number != "" {
+			ticket.Number = current.Number
+			return nil
+		}
+	}
+	number, err := nextTicketNumber(tx, ticket.TypeID)
+	if err != nil || number == "" {
+		return err
+	}
+	if ticket.ID > 0 {
+		res := tx.Model(&model.Ticket{}).Where("id = ? AND number = ''", ticket.ID).Update("number", number)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			// 返回错误回滚事务，已分配的序号随之回滚
+			return errors.New("工单编号已被分配，请重试")
+		}
+	}
+	ticket.Number = number
+	return nil
+}
+
+// ticketDisplayNumber 工单展示编号：有编号时使用编号，否则使用 #ID
+func ticketDisplayNumber(ticket *model.Ticket) string {
+	if ticket.Number != "" {
+		return ticket.Number
+	}
+	return "#" + strconv.FormatUint(uint64(ticket.ID), 10)
+}
@@ -0,0 +1,299 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/global"
+	"backend/internal/model"
+	"backend/pkg/logger"
+	"backend/pkg/scheduler"
+
+	"go.uber.org/zap"
+)
+
+// TicketScheduleService 周期工单服务
+type TicketScheduleService struct {
+	ticketSvc   *TicketService
+	templateSvc *TemplateService
+}
+
+// NewTicketScheduleService 创建周期工单服务
+func NewTicketScheduleService() *TicketScheduleService {
+	return &TicketScheduleService{ticketSvc: NewTicketService(), templateSvc: NewTemplateService()}
+}
+
+// validate 校验 cron 表达式、模板及发起人配置，并计算下次执行时间
+func (s *TicketScheduleService) validate(sch *model.TicketSchedule) error {
+	cron, err := scheduler.ParseCron(sch.Cron)
+	if err != nil {
+		return fmt.Errorf("cron 表达式无效: %v", err)
+	}
+	next := cron.Next(time.Now())
+	if next.IsZero() {
+		return errors.New("cron 表达式没有可执行的时间")
+	}
+	sch.NextRunAt = &next
+
+	var count int64
+	global.GetDB().Model(&model.TicketTemplate{}).Where("id = ?", sch.TemplateID).Count(&count)
+	if count == 0 {
+		return errors.New("工单模板不存在")
+	}
+
+	switch sch.CreatorType {
+	case model.ScheduleCreatorUser:
+		if sch.CreatorID == nil || *sch.CreatorID == 0 {
+			return errors.New("请指定发起人")
+		}
+		global.GetDB().Model(&model.User{}).Where("id = ?", *sch.CreatorID).Count(&count)
+		if count == 0 {
+			return errors.New("发起人不存在")
+		}
+		sch.RoleID = nil
+	case model.ScheduleCreatorRole:
+		if sch.RoleID == nil || *sch.RoleID == 0 {
+			return errors.New("请指定发起角色")
+		}
+		global.GetDB().Model(&model.Role{}).Where("id = ?", *sch.RoleID).Count(&count)
+		if count == 0 {
+			return errors.New("发起角色不存在")
+		}
+		sch.CreatorID = nil
+	default:
+		return fmt.Errorf("不支持的发起人类型: %s", sch.CreatorType)
+	}
+	return nil
+}
+
+// Create 创建周期工单
+func (s *TicketScheduleService) Create(sch *model.TicketSchedule) error {
+	if err := s.validate(sch); err != nil {
+		return err
+	}
+	return global.GetDB().Create(sch).Error
+}
+
+// Update 更新周期工单（重新计算下次执行时间）
+func (s *TicketScheduleService) Update(id uint, sch *model.TicketSchedule) error {
+	var existing model.TicketSchedule
+	if err := global.GetDB().First(&existing, id).Error; err != nil {
+		return errors.New("周期工单不存在")
+	}
+	if err := s.validate(sch); err != nil {
+		return err
+	}
+	return global.GetDB().Model(&existing).
+		Select("Name", "TemplateID", "Cron", "CreatorType", "CreatorID", "RoleID", "Title", "Priority",
+			"AutoSubmit", "SkipIfOpen", "Enabled", "NextRunAt").
+		Updates(sch).Error
+}
+
+// Delete 删除周期工单
+func (s *TicketScheduleService) Delete(id uint) error {
+	return global.GetDB().Delete(&model.TicketSchedule{}, id).Error
+}
+
+// GetByID 获取周期工单详情
+func (s *TicketScheduleService) GetByID(id uint) (*model.TicketSchedule, error) {
+	var sch model.TicketSchedule
+	if err := global.GetDB().Preload("Template").First(&sch, id).Error; err != nil {
+		return nil, err
+	}
+	return &sch, nil
+}
+
+// List 周期工单列表
+func (s *TicketScheduleService) List(page, pageSize int, keyword string) ([]model.TicketSchedule, int64, error) {
+	var schedules []model.TicketSchedule
+	var total int64
+	db := global.GetDB().Model(&model.TicketSchedule{})
+	if keyword != "" {
+		db = db.Where("name LIKE ?", "%"+keyword+"%")
+	}
+	db.Count(&total)
+	offset := (page - 1) * pageSize
+	if err := db.Preload("Template").Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&schedules).Error; err != nil {
+		return nil, 0, err
+	}
+	return schedules, total, nil
+}
+
+// ListRuns 周期工单执行记录
+func (s *TicketScheduleService) ListRuns(scheduleID uint, page, pageSize int) ([]model.TicketScheduleRun, int64, error) {
+	var runs []model.TicketScheduleRun
+	var total int64
+	db := global.GetDB().Model(&model.TicketScheduleRun{}).Where("schedule_id = ?", scheduleID)
+	db.Count(&total)
+	offset := (page - 1) * pageSize
+	if err := db.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&runs).Error; err != nil {
+		return nil, 0, err
+	}
+	return runs, total, nil
+}
+
+// RunNow 立即执行一次（不影响下次定时执行时间）
+func (s *TicketScheduleService) RunNow(id uint) (*model.TicketScheduleRun, error) {
+	var sch model.TicketSchedule
+	if err := global.GetDB().First(&sch, id).Error; err != nil {
+		return nil, errors.New("周期工单不存在")
+	}
+	return s.execute(&sch, time.Now(), true), nil
+}
+
+// RunDueSchedules 执行所有到期的周期工单
+func (s *TicketScheduleService) RunDueSchedules() {
+	now := time.Now()
+	var schedules []model.TicketSchedule
+	if err := global.GetDB().Where("enabled = ? AND next_run_at <= ?", true, now).Find(&schedules).Error; err != nil {
+		logger.Error("Failed to load due ticket schedules", zap.Error(err))
+		return
+	}
+	for i := range schedules {
+		sch := &schedules[i]
+		scheduledAt := *sch.NextRunAt
+
+		updates := map[string]interface{}{"last_run_at": now}
+		if cron, err := scheduler.ParseCron(sch.Cron); err == nil {
+			updates["next_run_at"] = cron.Next(now)
+		} else {
+			// 表达式失效时停用，避免每分钟重复执行
+			updates["enabled"] = false
+		}
+		// 以 next_run_at 作为乐观锁，多实例部署时只有一个实例能领取本次执行
+		result := global.GetDB().Model(&model.TicketSchedule{}).
+			Where("id = ? AND next_run_at = ?", sch.ID, scheduledAt).Updates(updates)
+		if result.Error != nil || result.RowsAffected == 0 {
+			continue
+		}
+		s.execute(sch, scheduledAt, false)
+	}
+}
+
+// creatorIDs 本次需要为哪些用户创建工单
+func (s *TicketScheduleService) creatorIDs(sch *model.TicketSchedule) []uint {
+	if sch.CreatorType == model.ScheduleCreatorUser {
+		if sch.CreatorID == nil {
+			return nil
+		}
+		return []uint{*sch.CreatorID}
+	}
+	if sch.RoleID == nil {
+		return nil
+	}
+	var ids []uint
+	global.GetDB().Model(&model.User{}).
+		Joins("JOIN user_roles ON users.id = user_roles.user_id").
+		Where("user_roles.role_id = ? AND users.status = ?", *sch.RoleID, 1).
+		Order("users.id").Pluck("users.id", &ids)
+	return ids
+}
+
+// hasOpenTicket 判断该周期工单为发起人创建的上一个工单是否仍未完结
+func (s *TicketScheduleService) hasOpenTicket(scheduleID, creatorID uint) bool {
+	var count int64
+	global.GetDB().Model(&model.Ticket{}).
+		Where("schedule_id = ? AND creator_id = ? AND status IN ?", scheduleID, creatorID, []string{
+			model.TicketStatusDraft, model.TicketStatusPending, model.TicketStatusApproving, model.TicketStatusProcessing,
+		}).Count(&count)
+	return count > 0
+}
+
+// execute 执行一次周期工单并记录执行结果
+func (s *TicketScheduleService) execute(sch *model.TicketSchedule, scheduledAt time.Time, manual bool) *model.TicketScheduleRun {
+	run := &model.TicketScheduleRun{ScheduleID: sch.ID, ScheduledAt: scheduledAt, Manual: manual}
+	defer func() {
+		if err := global.GetDB().Create(run).Error; err != nil {
+			logger.Warn("Failed to record ticket schedule run", zap.Uint("schedule_id", sch.ID), zap.Error(err))
+		}
+	}()
+
+	var tmpl model.TicketTemplate
+	if err := global.GetDB().First(&tmpl, sch.TemplateID).Error; err != nil {
+		run.Status = model.TicketScheduleRunFailed
+		run.Message = "工单模板不存在"
+		return run
+	}
+
+	creators := s.creatorIDs(sch)
+	if len(creators) == 0 {
+		run.Status = model.TicketScheduleRunFailed
+		run.Message = "没有可用的发起人"
+		return run
+	}
+
+	var created []string
+	var messages []string
+	skipped := 0
+	for _, creatorID := range creators {
+		if sch.SkipIfOpen && s.hasOpenTicket(sch.ID, creatorID) {
+			skipped++
+			messages = append(messages, fmt.Sprintf("用户 %d 的上一个工单未完结，已跳过", creatorID))
+			continue
+		}
+		// 预设值中的占位符按发起人替换
+		resolved, err := s.templateSvc.Resolve(&tmpl, creatorID)
+		if err != nil {
+			messages = append(messages, fmt.Sprintf("用户 %d 创建失败: %v", creatorID, err))
+			continue
+		}
+		title := sch.Title
+		if title == "" {
+			title = resolved.Title
+		}
+		if title == "" {
+			title = tmpl.Name
+		}
+		scheduleID := sch.ID
+		ticket := model.Ticket{
+			Title:       title,
+			Description: resolved.Description,
+			TypeID:      tmpl.TypeID,
+			Priority:    sch.Priority,
+			CreatorID:   creatorID,
+			Status:      model.TicketStatusDraft,
+			ScheduleID:  &scheduleID,
+		}
+		if err := s.ticketSvc.CreateWithFormData(&ticket, resolved.FormData); err != nil {
+			messages = append(messages, fmt.Sprintf("用户 %d 创建失败: %v", creatorID, err))
+			continue
+		}
+		created = append(created, strconv.FormatUint(uint64(ticket.ID), 10))
+		if sch.AutoSubmit {
+			if err := s.ticketSvc.Submit(ticket.ID); err != nil {
+				messages = append(messages, fmt.Sprintf("工单 %d 自动提交失败: %v", ticket.ID, err))
+			}
+		}
+	}
+
+	run.TicketIDs = strings.Join(created, ",")
+	run.Message = strings.Join(messages, "\n")
+	failed := len(creators) - skipped - len(created)
+	switch {
+	case len(created) == 0 && failed == 0:
+		run.Status = model.TicketScheduleRunSkipped
+	case len(created) == 0:
+		run.Status = model.TicketScheduleRunFailed
+	case failed > 0:
+		run.Status = model.TicketScheduleRunPartial
+	default:
+		run.Status = model.TicketScheduleRunSuccess
+	}
+	return run
+}
+
+// TicketScheduleJob 周期工单调度任务
+type TicketScheduleJob struct{}
+
+// Name 返回任务名称
+func (j *TicketScheduleJob) Name() string {
+	return "ticket_schedule"
+}
+
+// Run 执行到期的周期工单
+func (j *TicketScheduleJob) Run() {
+	NewTicketScheduleService().RunDueSchedules()
+}
@@ -0,0 +1,327 @@
+package service
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+
+	"backend/internal/global"
+	"backend/internal/model"
+	"backend/pkg/logger"
+	"backend/pkg/search"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 索引字段名及权重
+const (
+	searchFieldNumber      = "number"
+	searchFieldTitle       = "title"
+	searchFieldDescription = "description"
+	searchFieldForm        = "form"
+	searchFieldComment     = "comment"
+	searchFieldAttachment  = "attachment"
+
+	// searchRebuildBatch 全量重建时每批加载的工单数
+	searchRebuildBatch = 200
+)
+
+// searchRebuildState 全量重建期间单独更新或移除过的工单，替换索引前同步到新索引，避免更新丢失
+type searchRebuildState struct {
+	mu      sync.Mutex
+	running bool
+	touched map[uint]struct{}
+}
+
+var searchRebuilding = &searchRebuildState{}
+
+// touch 重建期间记录工单
+func (r *searchRebuildState) touch(ticketID uint) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.running {
+		r.touched[ticketID] = struct{}{}
+	}
+}
+
+// take 取出并清空已记录的工单
+func (r *searchRebuildState) take() []uint {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.takeLocked()
+}
+
+func (r *searchRebuildState) takeLocked() []uint {
+	ids := make([]uint, 0, len(r.touched))
+	for id := range r.touched {
+		ids = append(ids, id)
+	}
+	r.touched = make(map[uint]struct{})
+	return ids
+}
+
+// TicketSearchService 工单全文检索服务
+type TicketSearchService struct {
+	ticketSvc *TicketService
+}
+
+// NewTicketSearchService 创建工单全文检索服务
+func NewTicketSearchService() *TicketSearchService {
+	return &TicketSearchService{ticketSvc: &TicketService{}}
+}
+
+// TicketSearchResult 工单检索结果
+type TicketSearchResult struct {
+	Ticket     model.Ticket        `json:"ticket"`
+	Score      float64             `json:"score"`
+	Highlights map[string][]string `json:"highlights,omitempty"`
+}
+
+// preloadForIndex 预加载建索引所需的表单数据、评论和附件
+func preloadForIndex(db *gorm.DB) *gorm.DB {
+	return db.Preload("Data").Preload("Data.Field").Preload("Comments").Preload("Attachments")
+}
+
+// buildDocument 将工单及其表单数据、评论、附件转换为索引文档
+func (s *TicketSearchService) buildDocument(ticket *model.Ticket) search.Document {
+	var formLines []string
+	for _, data := range ticket.Data {
+		if data.Value == "" {
+			continue
+		}
+		label := ""
+		value := data.Value
+		if data.Field != nil {
+			label = data.Field.Label
+			if data.Field.FieldType == model.FormFieldTypeUser {
+				value = resolveUserNames(data.Value)
+			}
+		}
+		if label != "" {
+			formLines = append(formLines, label+": "+value)
+		} else {
+			formLines = append(formLines, value)
+		}
+	}
+
+	var comments []string
+	for _, c := range ticket.Comments {
+		comments = append(comments, c.Content)
+	}
+
+	var files []string
+	for _, a := range ticket.Attachments {
+		files = append(files, a.FileName)
+	}
+
+	return search.Document{
+		ID: ticket.ID,
+		Fields: []search.Field{
+			{Name: searchFieldNumber, Text: ticket.Number, Boost: 5},
+			{Name: searchFieldTitle, Text: ticket.Title, Boost: 3},
+			{Name: searchFieldDescription, Text: ticket.Description, Boost: 1},
+			{Name: searchFieldForm, Text: strings.Join(formLines, "\n"), Boost: 1.5},
+			{Name: searchFieldComment, Text: strings.Join(comments, "\n"), Boost: 1},
+			{Name: searchFieldAttachment, Text: strings.Join(files, "\n"), Boost: 1.2},
+		},
+	}
+}
+
+// resolveUserNames 将用户字段中的用户ID（单个或逗号分隔）解析为用户名，便于按姓名检索
+func resolveUserNames(value string) string {
+	var ids []uint
+	for _, part := range strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == '[' || r == ']' || r == ' ' || r == '"'
+	}) {
+		if id, err := strconv.ParseUint(part, 10, 64); err == nil {
+			ids = append(ids, uint(id))
+		}
+	}
+	if len(ids) == 0 {
+		return value
+	}
+	var names []string
+	global.GetDB().Model(&model.User{}).Where("id IN ?", ids).Pluck("username", &names)
+	if len(names) == 0 {
+		return value
+	}
+	return strings.Join(names, ", ")
+}
+
+// IndexTicket 重建单个工单的索引
+func (s *TicketSearchService) IndexTicket(ticketID uint) error {
+	// 先记录再获取索引：全量重建替换索引前会把记录的工单同步到新索引
+	searchRebuilding.touch(ticketID)
+	idx := global.GetSearchIndex()
+	if idx == nil {
+		return nil
+	}
+	return s.indexInto(idx, ticketID)
+}
+
+// indexInto 从数据库加载工单写入指定索引，工单已删除时从索引中移除
+func (s *TicketSearchService) indexInto(idx search.Index, ticketID uint) error {
+	var ticket model.Ticket
+	if err := global.GetDB().Scopes(preloadForIndex).First(&ticket, ticketID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return idx.Delete(ticketID)
+		}
+		return err
+	}
+	return idx.Index(s.buildDocument(&ticket))
+}
+
+// RemoveTicket 从索引中移除工单
+func (s *TicketSearchService) RemoveTicket(ticketID uint) error {
+	searchRebuilding.touch(ticketID)
+	idx := global.GetSearchIndex()
+	if idx == nil {
+		return nil
+	}
+	return idx.Delete(ticketID)
+}
+
+// Rebuild 全量重建索引，构建完成后替换当前索引。重建期间单独更新的工单在替换前同步到新索引
+func (s *TicketSearchService) Rebuild() (int, error) {
+	r := searchRebuilding
+	r.mu.Lock()
+	if r.running {
+		r.mu.Unlock()
+		return 0, errors.New("索引正在重建")
+	}
+	r.running = true
+	r.touched = make(map[uint]struct{})
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		r.running = false
+		r.touched = nil
+		r.mu.Unlock()
+	}()
+
+	idx, err := global.NewSearchIndex()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	var lastID uint
+	for {
+		var tickets []model.Ticket
+		if err := global.GetDB().Scopes(preloadForIndex).
+			Where("id > ?", lastID).Order("id ASC").Limit(searchRebuildBatch).Find(&tickets).Error; err != nil {
+			return count, err
+		}
+		if len(tickets) == 0 {
+			break
+		}
+		for i := range tickets {
+			if err := idx.Index(s.buildDocument(&tickets[i])); err != nil {
+				return count, err
+			}
+			count++
+		}
+		lastID = tickets[len(tickets)-1].ID
+	}
+
+	for _, id := range r.take() {
+		if err := s.indexInto(idx, id); err != nil {
+			return count, err
+		}
+	}
+	// 加锁同步最后一批并替换索引，此后的更新直接写入新索引
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, id := range r.takeLocked() {
+		if err := s.indexInto(idx, id); err != nil {
+			return count, err
+		}
+	}
+	global.SetSearchIndex(idx)
+	return count, nil
+}
+
+// Search 全文检索工单，非管理员只返回自己有权查看的工单
+func (s *TicketSearchService) Search(userID uint, isAdmin bool, keyword string, page, pageSize int) ([]TicketSearchResult, int64, error) {
+	idx := global.GetSearchIndex()
+	if idx == nil {
+		return nil, 0, errors.New("全文检索未初始化")
+	}
+
+	// 非管理员在检索时按权限过滤，保证总数和分页只包含可见工单
+	query := search.Query{Text: keyword, Offset: (page - 1) * pageSize, Limit: pageSize}
+	if !isAdmin {
+		var visibleIDs []uint
+		if err := global.GetDB().Model(&model.Ticket{}).Scopes(s.ticketSvc.ScopeVisibleTo(userID)).
+			Pluck("tickets.id", &visibleIDs).Error; err != nil {
+			return nil, 0, err
+		}
+		visible := make(map[uint]bool, len(visibleIDs))
+		for _, id := range visibleIDs {
+			visible[id] = true
+		}
+		query.Filter = func(id uint) bool { return visible[id] }
+	}
+	res, err := idx.Search(query)
+	if err != nil {
+		return nil, 0, err
+	}
+	total := int64(res.Total)
+	pageHits := res.Hits
+	if len(pageHits) == 0 {
+		return []TicketSearchResult{}, total, nil
+	}
+
+	pageIDs := make([]uint, len(pageHits))
+	for i, h := range pageHits {
+		pageIDs[i] = h.ID
+	}
+	var tickets []model.Ticket
+	if err := global.GetDB().Preload("Type").Preload("Creator").Preload("Assignee").Preload("CurrentNode").
+		Where("id IN ?", pageIDs).Find(&tickets).Error; err != nil {
+		return nil, 0, err
+	}
+	ticketMap := make(map[uint]model.Ticket, len(tickets))
+	for _, t := range tickets {
+		ticketMap[t.ID] = t
+	}
+
+	results := make([]TicketSearchResult, 0, len(pageHits))
+	for _, h := range pageHits {
+		if t, ok := ticketMap[h.ID]; ok {
+			// 当前节点对该用户隐藏了字段时不返回表单内容的高亮片段
+			if len(s.ticketSvc.hiddenFieldsFor(&t, userID, isAdmin)) > 0 {
+				delete(h.Highlights, searchFieldForm)
+			}
+			results = append(results, TicketSearchResult{Ticket: t, Score: h.Score, Highlights: h.Highlights})
+		}
+	}
+	return results, total, nil
+}
+
+// reindexTicket 工单相关数据变更后刷新索引（失败只记录日志，由定时重建兜底）
+func reindexTicket(ticketID uint) {
+	if err := NewTicketSearchService().IndexTicket(ticketID); err != nil {
+		logger.Warn("Failed to index ticket", zap.Uint("ticket_id", ticketID), zap.Error(err))
+	}
+}
+
+// TicketIndexJob 工单全文索引重建任务
+type TicketIndexJob struct{}
+
+// Name 返回任务名称
+func (j *TicketIndexJob) Name() string {
+	return "ticket_search_rebuild"
+}
+
+// Run 全量重建索引
+func (j *TicketIndexJob) Run() {
+	count, err := NewTicketSearchService().Rebuild()
+	if err != nil {
+		logger.Error("Ticket search index rebuild failed", zap.Error(err))
+		return
+	}
+	logger.Info("Ticket search index rebuilt", zap.Int("count", count))
+}
@@ -12,10 +12,16 @@ func NewTicketTypeService() *TicketTypeService {
 }
 
 func (s *TicketTypeService) Create(t *model.TicketType) error {
+	if err := ValidateTicketNumberFormat(t); err != nil {
+		return err
+	}
 	return global.GetDB().Create(t).Error
 }
 
 func (s *TicketTypeService) Update(id uint, t *model.TicketType) error {
+	if err := ValidateTicketNumberFormat(t); err != nil {
+		return err
+	}
 	return global.GetDB().Model(&model.TicketType{}).Where("id = ?", id).Updates(t).Error
 }
 
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"backend/internal/global"
+	"backend/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// TicketViewService 工单保存视图服务
+type TicketViewService struct {
+	ticketSvc *TicketService
+}
+
+// NewTicketViewService 创建工单保存视图服务
+func NewTicketViewService() *TicketViewService {
+	return &TicketViewService{ticketSvc: &TicketService{}}
+}
+
+// validate 校验视图的过滤条件、排序和共享设置
+func (s *TicketViewService) validate(view *model.TicketView) error {
+	if view.Visibility == "" {
+		view.Visibility = model.TicketViewVisibilityPrivate
+	}
+	switch view.Visibility {
+	case model.TicketViewVisibilityPrivate:
+		view.RoleID = nil
+	case model.TicketViewVisibilityRole:
+		if view.RoleID == nil || *view.RoleID == 0 {
+			return errors.New("共享视图必须指定角色")
+		}
+		var count int64
+		global.GetDB().Model(&model.Role{}).Where("id = ?", *view.RoleID).Count(&count)
+		if count == 0 {
+			return errors.New("共享角色不存在")
+		}
+	default:
+		return fmt.Errorf("不支持的可见范围: %s", view.Visibility)
+	}
+	if _, err := ticketSortClause(view.SortBy, view.SortOrder); err != nil {
+		return err
+	}
+	if _, err := ParseTicketFilter(view.Filter); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Create 创建保存视图
+func (s *TicketViewService) Create(view *model.TicketView) error {
+	if err := s.validate(view); err != nil {
+		return err
+	}
+	return global.GetDB().Create(view).Error
+}
+
+// Update 更新保存视图，仅所有者或管理员可操作
+func (s *TicketViewService) Update(id, userID uint, isAdmin bool, view *model.TicketView) error {
+	existing, err := s.getOwned(id, userID, isAdmin)
+	if err != nil {
+		return err
+	}
+	if err := s.validate(view); err != nil {
+		return err
+	}
+	return global.GetDB().Model(existing).Select("Name", "Description", "Visibility", "RoleID", "Filter", "SortBy", "SortOrder").
+		Updates(view).Error
+}
+
+// Delete 删除保存视图，仅所有者或管理员可操作
+func (s *TicketViewService) Delete(id, userID uint, isAdmin bool) error {
+	view, err := s.getOwned(id, userID, isAdmin)
+	if err != nil {
+		return err
+	}
+	return global.GetDB().Delete(view).Error
+}
+
+func (s *TicketViewService) getOwned(id, userID uint, isAdmin bool) (*model.TicketView, error) {
+	var view model.TicketView
+	if err := global.GetDB().First(&view, id).Error; err != nil {
+		return nil, err
+	}
+	if !isAdmin && view.OwnerID != userID {
+		return nil, errors.New("只能修改自己创建的视图")
+	}
+	return &view, nil
+}
+
+// GetVisible 获取用户可使用的视图（自己的、共享给所属角色的；管理员可使用全部）
+func (s *TicketViewService) GetVisible(id, userID uint, isAdmin bool) (*model.TicketView, error) {
+	var view model.TicketView
+	db := global.GetDB().Preload("Owner")
+	if !isAdmin {
+		db = s.scopeVisible(db, userID)
+	}
+	if err := db.First(&view, id).Error; err != nil {
+		return nil, err
+	}
+	return &view, nil
+}
+
+// List 列出用户可使用的视图
+func (s *TicketViewService) List(userID uint) ([]model.TicketView, error) {
+	var views []model.TicketView
+	db := s.scopeVisible(global.GetDB().Model(&model.TicketView{}), userID)
+	if err := db.Preload("Owner").Order("created_at DESC").Find(&views).Error; err != nil {
+		return nil, err
+	}
+	return views, nil
+}
+
+// scopeVisible 过滤用户可使用的视图：自己创建的，或共享给用户所属角色的
+func (s *TicketViewService) scopeVisible(db *gorm.DB, userID uint) *gorm.DB {
+	roleQuery := global.GetDB().Table("user_roles").Select("role_id").Where("user_id = ?", userID)
+	return db.Where("(owner_id = ? OR (visibility = ? AND role_id IN (?)))",
+		userID, model.TicketViewVisibilityRole, roleQuery)
+}
+
+// Run 执行查询：可选的视图条件与临时条件取交集，临时排序优先于视图排序
+func (s *TicketViewService) Run(userID uint, isAdmin bool, viewID uint, rawFilter, sortBy, sortOrder string, page, pageSize int) ([]model.Ticket, int64, error) {
+	var filters []TicketFilter
+	if viewID > 0 {
+		view, err := s.GetVisible(viewID, userID, isAdmin)
+		if err != nil {
+			return nil, 0, errors.New("视图不存在")
+		}
+		viewFilter, err := ParseTicketFilter(view.Filter)
+		if err != nil {
+			return nil, 0, err
+		}
+		if viewFilter != nil {
+			filters = append(filters, *viewFilter)
+		}
+		if sortBy == "" {
+			sortBy, sortOrder = view.SortBy, view.SortOrder
+		}
+	}
+	extra, err := ParseTicketFilter(rawFilter)
+	if err != nil {
+		return nil, 0, err
+	}
+	if extra != nil {
+		filters = append(filters, *extra)
+	}
+
+	q := &TicketQuery{SortBy: sortBy, SortOrder: sortOrder, Page: page, PageSize: pageSize}
+	switch len(filters) {
+	case 0:
+	case 1:
+		q.Filter = &filters[0]
+	default:
+		q.Filter = &TicketFilter{And: filters}
+	}
+	return s.ticketSvc.Query(userID, isAdmin, q)
+}
@@ -37,6 +37,11 @@ func (s *UserService) Create(user *model.User) error {
 		}
 	}
 
+	// 检查钉钉用户ID是否已被使用
+	if err := checkDingTalkUserID(user.DingTalkUserID, 0); err != nil {
+		return err
+	}
+
 	// 加密密码
 	hashedPassword, err := utils.HashPassword(user.Password)
 	if err != nil {
@@ -48,8 +53,8 @@ func (s *UserService) Create(user *model.User) error {
 	return global.GetDB().Create(user).Error
 }
 
-// Update 更新用户
-func (s *UserService) Update(userID uint, user *model.User) error {
+// Update 更新用户，optionalFields 为本次请求提供了的可选字段（部门、钉钉/企业微信用户ID），未提供的保持不变
+func (s *UserService) Update(userID uint, user *model.User, optionalFields ...string) error {
 	var existingUser model.User
 	if err := global.GetDB().Where("id = ?", userID).First(&existingUser).Error; err != nil {
 		return err
@@ -77,6 +82,13 @@ func (s *UserService) Update(userID uint, user *model.User) error {
 		}
 	}
 
+	// 检查钉钉用户ID是否已被其他用户使用
+	if user.DingTalkUserID != existingUser.DingTalkUserID {
+		if err := checkDingTalkUserID(user.DingTalkUserID, userID); err != nil {
+			return err
+		}
+	}
+
 	// 如果提供了新密码，则加密
 	if user.Password != "" {
 		hashedPassword, err := utils.HashPassword(user.Password)
@@ -89,10 +101,26 @@ func (s *UserService) Update(userID uint, user *model.User) error {
 	}
 
 	// 使用 Select 明确指定要更新的字段，包括零值字段
+	fields := append([]string{"username", "email", "phone", "status"}, optionalFields...)
 	if user.Password != "" {
-		return global.GetDB().Model(&existingUser).Select("username", "email", "phone", "status", "password").Updates(user).Error
+		fields = append(fields, "password")
+	}
+	return global.GetDB().Model(&existingUser).Select(fields).Updates(user).Error
+}
+
+// checkDingTalkUserID 检查钉钉用户ID是否已被其他用户使用，钉钉回调按此ID识别操作人，必须唯一
+func checkDingTalkUserID(dingTalkUserID string, excludeID uint) error {
+	if dingTalkUserID == "" {
+		return nil
+	}
+	var count int64
+	if err := global.GetDB().Model(&model.User{}).Where("ding_talk_user_id = ? AND id != ?", dingTalkUserID, excludeID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.New("钉钉用户ID已被其他用户使用")
 	}
-	return global.GetDB().Model(&existingUser).Select("username", "email", "phone", "status").Updates(user).Error
+	return nil
 }
 
 // Delete 删除用户
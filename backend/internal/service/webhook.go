@@ -0,0 +1,560 @@
+package service
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/internal/global"
+	"backend/internal/model"
+	"backend/pkg/logger"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	webhookMaxAttempts    = 8                // 最多投递次数（含首次）
+	webhookBaseBackoff    = 30 * time.Second // 首次重试间隔，之后每次翻倍
+	webhookMaxBackoff     = 6 * time.Hour    // 最大重试间隔
+	webhookTimeout        = 10 * time.Second // 单次请求超时
+	webhookBatchSize      = 100              // 每轮最多投递的记录数
+	webhookSendingTimeout = 5 * time.Minute  // 投递中状态超过该时长视为中断，重新投递
+	webhookResponseLimit  = 2048             // 保存的响应内容长度
+	webhookWorkers        = 8                // 并发投递的订阅数
+)
+
+// webhookEvents 可订阅的 Webhook 事件
+var webhookEvents = map[string]bool{
+	model.TicketEventCreated:     true,
+	model.TicketEventSubmitted:   true,
+	model.TicketEventNodeEntered: true,
+	model.TicketEventApproved:    true,
+	model.TicketEventRejected:    true,
+	model.TicketEventReturned:    true,
+	model.TicketEventWithdrawn:   true,
+	model.TicketEventReassigned:  true,
+	model.TicketEventCompleted:   true,
+	model.TicketEventCancelled:   true,
+	model.WebhookEventCommented:  true,
+}
+
+// WebhookPayload Webhook 推送内容
+type WebhookPayload struct {
+	ID         string                 `json:"id"` // 事件ID，重新投递时不变，可用于去重
+	Event      string                 `json:"event"`
+	OccurredAt time.Time              `json:"occurred_at"`
+	ActorID    *uint                  `json:"actor_id,omitempty"`
+	NodeID     *uint                  `json:"node_id,omitempty"`
+	Ticket     *WebhookTicket         `json:"ticket,omitempty"`
+	Data       map[string]interface{} `json:"data,omitempty"` // 事件附加数据，如评论内容
+}
+
+// WebhookTicket Webhook 推送的工单信息
+type WebhookTicket struct {
+	ID            uint                   `json:"id"`
+	Number        string                 `json:"number"`
+	Title         string                 `json:"title"`
+	Description   string                 `json:"description"`
+	TypeID        uint                   `json:"type_id"`
+	TypeName      string                 `json:"type_name"`
+	Status        string                 `json:"status"`
+	Priority      int                    `json:"priority"`
+	CreatorID     uint                   `json:"creator_id"`
+	Creator       string                 `json:"creator"`
+	AssigneeID    *uint                  `json:"assignee_id"`
+	CurrentNodeID *uint                  `json:"current_node_id"`
+	CurrentNode   string                 `json:"current_node"`
+	CreatedAt     time.Time              `json:"created_at"`
+	CompletedAt   *time.Time             `json:"completed_at"`
+	FormData      map[string]interface{} `json:"form_data"` // 字段标识 -> 值
+}
+
+// WebhookService Webhook 订阅与投递服务
+type WebhookService struct{}
+
+// NewWebhookService 创建 Webhook 服务
+func NewWebhookService() *WebhookService {
+	return &WebhookService{}
+}
+
+// validate 校验订阅配置并规范化事件和类型列表，未设置密钥时自动生成
+func (s *WebhookService) validate(hook *model.Webhook) error {
+	u, err := url.Parse(hook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("Webhook 地址无效")
+	}
+	events := splitCSV(hook.Events)
+	for _, e := range events {
+		if !webhookEvents[e] {
+			return fmt.Errorf("不支持的事件: %s", e)
+		}
+	}
+	hook.Events = strings.Join(events, ",")
+	typeIDs := splitCSV(hook.TypeIDs)
+	for _, id := range typeIDs {
+		if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+			return fmt.Errorf("工单类型ID无效: %s", id)
+		}
+	}
+	hook.TypeIDs = strings.Join(typeIDs, ",")
+	if hook.Secret == "" {
+		secret, err := newWebhookSecret()
+		if err != nil {
+			return err
+		}
+		hook.Secret = secret
+	}
+	return nil
+}
+
+// newWebhookSecret 生成随机签名密钥
+func newWebhookSecret() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// splitCSV 拆分逗号分隔的列表，去除空项
+func splitCSV(s string) []string {
+	var result []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// List Webhook 列表
+func (s *WebhookService) List() ([]model.Webhook, error) {
+	var hooks []model.Webhook
+	if err := global.GetDB().Order("id ASC").Find(&hooks).Error; err != nil {
+		return nil, err
+	}
+	return hooks, nil
+}
+
+// Get Webhook 详情
+func (s *WebhookService) Get(id uint) (*model.Webhook, error) {
+	var hook model.Webhook
+	if err := global.GetDB().First(&hook, id).Error; err != nil {
+		return nil, errors.New("Webhook 不存在")
+	}
+	return &hook, nil
+}
+
+// Create 创建 Webhook
+func (s *WebhookService) Create(hook *model.Webhook) error {
+	if err := s.validate(hook); err != nil {
+		return err
+	}
+	return global.GetDB().Create(hook).Error
+}
+
+// Update 更新 Webhook，密钥为空时保留原密钥
+func (s *WebhookService) Update(id uint, hook *model.Webhook) error {
+	existing, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+	if hook.Secret == "" {
+		hook.Secret = existing.Secret
+	}
+	if err := s.validate(hook); err != nil {
+		return err
+	}
+	return global.GetDB().Model(existing).
+		Select("Name", "URL", "Secret", "Events", "TypeIDs", "Enabled").
+		Updates(hook).Error
+}
+
+// RotateSecret 重新生成签名密钥，返回包含新密钥的订阅
+func (s *WebhookService) RotateSecret(id uint) (*model.Webhook, error) {
+	hook, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := global.GetDB().Model(hook).Update("secret", secret).Error; err != nil {
+		return nil, err
+	}
+	hook.Secret = secret
+	return hook, nil
+}
+
+// Delete 删除 Webhook（投递记录保留）
+func (s *WebhookService) Delete(id uint) error {
+	return global.GetDB().Delete(&model.Webhook{}, id).Error
+}
+
+// ListDeliveries Webhook 投递记录
+func (s *WebhookService) ListDeliveries(webhookID uint, status string, page, pageSize int) ([]model.WebhookDelivery, int64, error) {
+	var deliveries []model.WebhookDelivery
+	var total int64
+	db := global.GetDB().Model(&model.WebhookDelivery{}).Where("webhook_id = ?", webhookID)
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+	db.Count(&total)
+	if err := db.Omit("payload").Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).
+		Find(&deliveries).Error; err != nil {
+		return nil, 0, err
+	}
+	return deliveries, total, nil
+}
+
+// GetDelivery 投递记录详情（含推送内容）
+func (s *WebhookService) GetDelivery(id uint) (*model.WebhookDelivery, error) {
+	var d model.WebhookDelivery
+	if err := global.GetDB().Preload("Webhook").First(&d, id).Error; err != nil {
+		return nil, errors.New("投递记录不存在")
+	}
+	return &d, nil
+}
+
+// Redeliver 重新投递：复制原推送内容生成新的投递记录，立即进入队列
+func (s *WebhookService) Redeliver(id uint) (*model.WebhookDelivery, error) {
+	original, err := s.GetDelivery(id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	d := &model.WebhookDelivery{
+		WebhookID:     original.WebhookID,
+		EventID:       original.EventID,
+		Event:         original.Event,
+		TicketID:      original.TicketID,
+		Payload:       original.Payload,
+		Status:        model.WebhookDeliveryPending,
+		NextAttemptAt: &now,
+		RedeliveryOf:  &original.ID,
+	}
+	if err := global.GetDB().Create(d).Error; err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Ping 发送测试事件
+func (s *WebhookService) Ping(id uint) (*model.WebhookDelivery, error) {
+	hook, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	payload := WebhookPayload{
+		ID:         uuid.New().String(),
+		Event:      model.WebhookEventPing,
+		OccurredAt: time.Now(),
+		Data:       map[string]interface{}{"webhook_id": hook.ID, "name": hook.Name},
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	d := &model.WebhookDelivery{
+		WebhookID:     hook.ID,
+		EventID:       payload.ID,
+		Event:         payload.Event,
+		Payload:       string(body),
+		Status:        model.WebhookDeliveryPending,
+		NextAttemptAt: &payload.OccurredAt,
+	}
+	if err := global.GetDB().Create(d).Error; err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// webhookMatches 判断订阅是否匹配事件和工单类型
+func webhookMatches(hook *model.Webhook, event string, typeID uint) bool {
+	if events := splitCSV(hook.Events); len(events) > 0 && !containsString(events, event) {
+		return false
+	}
+	if typeIDs := splitCSV(hook.TypeIDs); len(typeIDs) > 0 && !containsString(typeIDs, strconv.FormatUint(uint64(typeID), 10)) {
+		return false
+	}
+	return true
+}
+
+// enqueueWebhookEvent 为匹配的订阅生成投递记录（失败只记录日志，不影响业务流程）
+func enqueueWebhookEvent(ticketID uint, event string, nodeID *uint, actorID uint, data map[string]interface{}) {
+	if err := enqueueWebhookDeliveries(ticketID, event, nodeID, actorID, data); err != nil {
+		logger.Warn("Failed to enqueue webhook event", zap.Uint("ticket_id", ticketID),
+			zap.String("event", event), zap.Error(err))
+	}
+}
+
+func enqueueWebhookDeliveries(ticketID uint, event string, nodeID *uint, actorID uint, data map[string]interface{}) error {
+	var hooks []model.Webhook
+	if err := global.GetDB().Where("enabled = ?", true).Find(&hooks).Error; err != nil || len(hooks) == 0 {
+		return err
+	}
+	var ticket model.Ticket
+	if err := global.GetDB().Preload("Type").Preload("Creator").Preload("CurrentNode").
+		First(&ticket, ticketID).Error; err != nil {
+		return err
+	}
+	var matched []model.Webhook
+	for _, hook := range hooks {
+		if webhookMatches(&hook, event, ticket.TypeID) {
+			matched = append(matched, hook)
+		}
+	}
+	if len(matched) == 0 {
+		return nil
+	}
+
+	payload, err := buildWebhookPayload(&ticket, event, nodeID, actorID, data)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	deliveries := make([]model.WebhookDelivery, 0, len(matched))
+	for _, hook := range matched {
+		deliveries = append(deliveries, model.WebhookDelivery{
+			WebhookID:     hook.ID,
+			EventID:       payload.ID,
+			Event:         event,
+			TicketID:      ticket.ID,
+			Payload:       string(body),
+			Status:        model.WebhookDeliveryPending,
+			NextAttemptAt: &payload.OccurredAt,
+		})
+	}
+	return global.GetDB().Create(&deliveries).Error
+}
+
+// buildWebhookPayload 组装推送内容，表单数据按字段标识输出
+func buildWebhookPayload(ticket *model.Ticket, event string, nodeID *uint, actorID uint, data map[string]interface{}) (*WebhookPayload, error) {
+	stored, err := storedFormValues(ticket.ID)
+	if err != nil {
+		return nil, err
+	}
+	formData := make(map[string]interface{}, len(stored))
+	for name, raw := range stored {
+		var v interface{}
+		if json.Unmarshal([]byte(raw), &v) == nil {
+			formData[name] = v
+		} else {
+			formData[name] = raw
+		}
+	}
+	t := &WebhookTicket{
+		ID:            ticket.ID,
+		Number:        ticket.Number,
+		Title:         ticket.Title,
+		Description:   ticket.Description,
+		TypeID:        ticket.TypeID,
+		TypeName:      ticket.Type.Name,
+		Status:        ticket.Status,
+		Priority:      ticket.Priority,
+		CreatorID:     ticket.CreatorID,
+		Creator:       ticket.Creator.Username,
+		AssigneeID:    ticket.AssigneeID,
+		CurrentNodeID: ticket.CurrentNodeID,
+		CreatedAt:     ticket.CreatedAt,
+		CompletedAt:   ticket.CompletedAt,
+		FormData:      formData,
+	}
+	if ticket.CurrentNode != nil {
+		t.CurrentNode = ticket.CurrentNode.Name
+	}
+	payload := &WebhookPayload{
+		ID:         uuid.New().String(),
+		Event:      event,
+		OccurredAt: time.Now(),
+		NodeID:     nodeID,
+		Ticket:     t,
+		Data:       data,
+	}
+	if actorID > 0 {
+		payload.ActorID = &actorID
+	}
+	return payload, nil
+}
+
+// WebhookSignature 计算签名：HMAC-SHA256(secret, 时间戳 + "." + 请求体)，十六进制编码
+func WebhookSignature(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBackoff 第 attempts 次失败后的重试间隔
+func webhookBackoff(attempts int) time.Duration {
+	delay := webhookBaseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= webhookMaxBackoff {
+			return webhookMaxBackoff
+		}
+	}
+	return delay
+}
+
+// RunPendingDeliveries 投递到期的记录：按订阅分组并发投递，同一订阅内按顺序投递，
+// 某条投递失败时本轮跳过该订阅的其余记录，避免不可用的地址拖慢其他订阅
+func (s *WebhookService) RunPendingDeliveries() {
+	now := time.Now()
+	// 投递中断（如进程退出）的记录重新进入队列
+	global.GetDB().Model(&model.WebhookDelivery{}).
+		Where("status = ? AND updated_at < ?", model.WebhookDeliverySending, now.Add(-webhookSendingTimeout)).
+		Update("status", model.WebhookDeliveryPending)
+
+	var deliveries []model.WebhookDelivery
+	if err := global.GetDB().Where("status = ? AND next_attempt_at <= ?", model.WebhookDeliveryPending, now).
+		Order("id ASC").Limit(webhookBatchSize).Find(&deliveries).Error; err != nil {
+		logger.Error("Failed to load webhook deliveries", zap.Error(err))
+		return
+	}
+	var order []uint
+	groups := make(map[uint][]*model.WebhookDelivery)
+	for i := range deliveries {
+		id := deliveries[i].WebhookID
+		if _, ok := groups[id]; !ok {
+			order = append(order, id)
+		}
+		groups[id] = append(groups[id], &deliveries[i])
+	}
+
+	jobs := make(chan []*model.WebhookDelivery)
+	var wg sync.WaitGroup
+	for i := 0; i < webhookWorkers && i < len(order); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for group := range jobs {
+				s.deliverGroup(group)
+			}
+		}()
+	}
+	for _, id := range order {
+		jobs <- groups[id]
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// deliverGroup 按顺序投递同一订阅的记录，失败后停止，剩余记录留待下一轮
+func (s *WebhookService) deliverGroup(group []*model.WebhookDelivery) {
+	for _, d := range group {
+		// 抢占记录，避免多实例重复投递
+		res := global.GetDB().Model(&model.WebhookDelivery{}).
+			Where("id = ? AND status = ?", d.ID, model.WebhookDeliveryPending).
+			Update("status", model.WebhookDeliverySending)
+		if res.Error != nil || res.RowsAffected == 0 {
+			continue
+		}
+		if !s.deliver(d) {
+			return
+		}
+	}
+}
+
+// deliver 执行一次投递并记录结果，失败时按指数退避安排重试，返回是否投递成功
+func (s *WebhookService) deliver(d *model.WebhookDelivery) bool {
+	attempts := d.Attempts + 1
+	updates := map[string]interface{}{"attempts": attempts}
+
+	var hook model.Webhook
+	if err := global.GetDB().First(&hook, d.WebhookID).Error; err != nil || !hook.Enabled {
+		updates["status"] = model.WebhookDeliveryFailed
+		updates["error"] = "Webhook 已删除或已停用"
+		updates["next_attempt_at"] = nil
+		global.GetDB().Model(d).Updates(updates)
+		return false
+	}
+
+	code, respBody, duration, err := sendWebhook(&hook, d)
+	ok := err == nil && code >= 200 && code < 300
+	updates["response_code"] = code
+	updates["response_body"] = respBody
+	updates["duration_ms"] = duration.Milliseconds()
+	if ok {
+		now := time.Now()
+		updates["status"] = model.WebhookDeliverySuccess
+		updates["error"] = ""
+		updates["delivered_at"] = &now
+		updates["next_attempt_at"] = nil
+	} else {
+		if err == nil {
+			err = fmt.Errorf("响应状态码 %d", code)
+		}
+		updates["error"] = err.Error()
+		if attempts >= webhookMaxAttempts {
+			updates["status"] = model.WebhookDeliveryFailed
+			updates["next_attempt_at"] = nil
+		} else {
+			next := time.Now().Add(webhookBackoff(attempts))
+			updates["status"] = model.WebhookDeliveryPending
+			updates["next_attempt_at"] = &next
+		}
+		logger.Warn("Webhook delivery failed", zap.Uint("delivery_id", d.ID), zap.Uint("webhook_id", hook.ID),
+			zap.Int("attempts", attempts), zap.Error(err))
+	}
+	global.GetDB().Model(d).Updates(updates)
+	return ok
+}
+
+// sendWebhook 发送请求，返回状态码、截断的响应内容和耗时
+func sendWebhook(hook *model.Webhook, d *model.WebhookDelivery) (int, string, time.Duration, error) {
+	body := []byte(d.Payload)
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Zeus-Webhook")
+	req.Header.Set("X-Webhook-Event", d.Event)
+	req.Header.Set("X-Webhook-Event-ID", d.EventID)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatUint(uint64(d.ID), 10))
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+WebhookSignature(hook.Secret, timestamp, body))
+
+	start := time.Now()
+	resp, err := (&http.Client{Timeout: webhookTimeout}).Do(req)
+	duration := time.Since(start)
+	if err != nil {
+		return 0, "", duration, err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseLimit))
+	return resp.StatusCode, string(respBody), duration, nil
+}
+
+// WebhookDeliveryJob Webhook 投递定时任务
+type WebhookDeliveryJob struct{}
+
+// Name 返回任务名称
+func (j *WebhookDeliveryJob) Name() string {
+	return "webhook_delivery"
+}
+
+// Run 投递到期的 Webhook 记录
+func (j *WebhookDeliveryJob) Run() {
+	NewWebhookService().RunPendingDeliveries()
+}
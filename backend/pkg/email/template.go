@@ -0,0 +1,19 @@
+package email
+
+import (
+	"bytes"
+	"html/template"
+)
+
+// RenderHTML 使用 html/template 渲染 HTML 邮件正文（自动转义数据中的 HTML）
+func RenderHTML(tmpl string, data interface{}) (string, error) {
+	t, err := template.New("email").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
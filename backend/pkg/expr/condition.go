@@ -0,0 +1,326 @@
+// Package expr 表单显示条件与流程分支条件的求值
+package expr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Condition 条件表达式，单个比较或 all/any/not 组合：
+//
+//	{"field": "amount", "operator": ">", "value": 1000}
+//	{"all": [{"field": "type", "value": "A"}, {"field": "urgent", "operator": "!=", "value": ""}]}
+//	{"any": [...]}  {"not": {...}}
+//
+// 顶层为数组时等同于 all
+type Condition struct {
+	Field    string       `json:"field,omitempty"`
+	Operator string       `json:"operator,omitempty"`
+	Value    interface{}  `json:"value,omitempty"`
+	All      []*Condition `json:"all,omitempty"`
+	Any      []*Condition `json:"any,omitempty"`
+	Not      *Condition   `json:"not,omitempty"`
+}
+
+// Lookup 按字段标识获取原始值，未填写返回空字符串
+type Lookup func(field string) string
+
+// operators 支持的比较运算符，值为规范名称
+var operators = map[string]string{
+	"":          "eq",
+	"eq":        "eq",
+	"=":         "eq",
+	"==":        "eq",
+	"ne":        "ne",
+	"!=":        "ne",
+	"gt":        "gt",
+	">":         "gt",
+	"gte":       "gte",
+	">=":        "gte",
+	"lt":        "lt",
+	"<":         "lt",
+	"lte":       "lte",
+	"<=":        "lte",
+	"contains":  "contains",
+	"in":        "in",
+	"not_in":    "not_in",
+	"notIn":     "not_in",
+	"empty":     "empty",
+	"not_empty": "not_empty",
+}
+
+// timeLayouts 日期/时间值支持的格式
+var timeLayouts = []string{
+	"2006-01-02",
+	"2006-01-02T15:04",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02 15:04:05",
+	time.RFC3339,
+}
+
+// ParseCondition 解析并校验条件 JSON，空字符串返回 nil（表示无条件）
+func ParseCondition(s string) (*Condition, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	cond := &Condition{}
+	if strings.HasPrefix(s, "[") {
+		if err := json.Unmarshal([]byte(s), &cond.All); err != nil {
+			return nil, errors.New("条件必须是 JSON 对象或数组")
+		}
+	} else if err := json.Unmarshal([]byte(s), cond); err != nil {
+		return nil, errors.New("条件必须是 JSON 对象或数组")
+	}
+	if err := cond.validate(); err != nil {
+		return nil, err
+	}
+	return cond, nil
+}
+
+func (c *Condition) validate() error {
+	if c == nil {
+		return errors.New("条件不能为空")
+	}
+	groups := 0
+	for _, set := range [][]*Condition{c.All, c.Any} {
+		if set != nil {
+			groups++
+		}
+		for _, sub := range set {
+			if err := sub.validate(); err != nil {
+				return err
+			}
+		}
+	}
+	if c.Not != nil {
+		groups++
+		if err := c.Not.validate(); err != nil {
+			return err
+		}
+	}
+	if c.Field != "" {
+		groups++
+		if _, ok := operators[c.Operator]; !ok {
+			return fmt.Errorf("不支持的运算符: %s", c.Operator)
+		}
+	}
+	if groups != 1 {
+		return errors.New("条件需且仅需指定 field、all、any、not 之一")
+	}
+	return nil
+}
+
+// Fields 条件引用的字段标识
+func (c *Condition) Fields() []string {
+	if c == nil {
+		return nil
+	}
+	var fields []string
+	if c.Field != "" {
+		fields = append(fields, c.Field)
+	}
+	for _, sub := range append(append([]*Condition{}, c.All...), c.Any...) {
+		fields = append(fields, sub.Fields()...)
+	}
+	return append(fields, c.Not.Fields()...)
+}
+
+// Eval 求值，nil 条件恒为真
+func (c *Condition) Eval(lookup Lookup) bool {
+	if c == nil {
+		return true
+	}
+	switch {
+	case c.All != nil:
+		for _, sub := range c.All {
+			if !sub.Eval(lookup) {
+				return false
+			}
+		}
+		return true
+	case c.Any != nil:
+		for _, sub := range c.Any {
+			if sub.Eval(lookup) {
+				return true
+			}
+		}
+		return false
+	case c.Not != nil:
+		return !c.Not.Eval(lookup)
+	}
+	return compare(lookup(c.Field), operators[c.Operator], c.Value)
+}
+
+// Match 解析并求值条件，条件无效时按 invalid 返回
+func Match(condition string, lookup Lookup, invalid bool) bool {
+	cond, err := ParseCondition(condition)
+	if err != nil {
+		return invalid
+	}
+	return cond.Eval(lookup)
+}
+
+func compare(raw, op string, target interface{}) bool {
+	values := SplitValues(raw)
+	switch op {
+	case "empty":
+		return len(values) == 0
+	case "not_empty":
+		return len(values) > 0
+	case "in", "not_in":
+		found := false
+		for _, t := range targetList(target) {
+			for _, v := range values {
+				if v == t {
+					found = true
+				}
+			}
+		}
+		return found == (op == "in")
+	case "contains":
+		t := stringify(target)
+		if len(values) > 1 || strings.HasPrefix(strings.TrimSpace(raw), "[") {
+			for _, v := range values {
+				if v == t {
+					return true
+				}
+			}
+			return false
+		}
+		return strings.Contains(strings.Join(values, ","), t)
+	}
+
+	// 目标为数组时 == / != 等同于 in / not_in
+	if list, ok := target.([]interface{}); ok && (op == "eq" || op == "ne") {
+		return compare(raw, map[string]string{"eq": "in", "ne": "not_in"}[op], list)
+	}
+	t := stringify(target)
+	value := strings.Join(values, ",")
+	// == / != 按字符串比较（"1" 与 "1.0" 不相等），数字/时间比较仅用于大小比较
+	switch op {
+	case "eq":
+		return value == t
+	case "ne":
+		return value != t
+	}
+	cmp, ok := Compare(value, t)
+	if !ok {
+		return false
+	}
+	switch op {
+	case "gt":
+		return cmp > 0
+	case "gte":
+		return cmp >= 0
+	case "lt":
+		return cmp < 0
+	case "lte":
+		return cmp <= 0
+	}
+	return false
+}
+
+// Compare 按数字或时间比较两个值，返回 -1/0/1；无法按类型比较时 ok 为 false
+func Compare(a, b string) (int, bool) {
+	a, b = strings.TrimSpace(a), strings.TrimSpace(b)
+	if x, err := strconv.ParseFloat(a, 64); err == nil {
+		y, err := strconv.ParseFloat(b, 64)
+		if err != nil {
+			return 0, false
+		}
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	}
+	if x, ok := ParseTime(a); ok {
+		y, ok := ParseTime(b)
+		if !ok {
+			return 0, false
+		}
+		return x.Compare(y), true
+	}
+	return 0, false
+}
+
+// ParseTime 解析日期/时间值
+func ParseTime(s string) (time.Time, bool) {
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// SplitValues 将原始值拆分为单个值：JSON 数组按元素拆分，其余去掉 JSON 引号后作为单个值
+func SplitValues(raw string) []string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" || raw == "null" {
+		return nil
+	}
+	if strings.HasPrefix(raw, "[") {
+		var items []interface{}
+		if err := json.Unmarshal([]byte(raw), &items); err == nil {
+			result := make([]string, 0, len(items))
+			for _, item := range items {
+				if s := strings.TrimSpace(stringify(item)); s != "" {
+					result = append(result, s)
+				}
+			}
+			return result
+		}
+	}
+	if len(raw) >= 2 && raw[0] == '"' && raw[len(raw)-1] == '"' {
+		var s string
+		if err := json.Unmarshal([]byte(raw), &s); err == nil {
+			raw = strings.TrimSpace(s)
+		}
+	}
+	if raw == "" {
+		return nil
+	}
+	return []string{raw}
+}
+
+func targetList(target interface{}) []string {
+	switch v := target.(type) {
+	case []interface{}:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			result = append(result, stringify(item))
+		}
+		return result
+	case string:
+		var result []string
+		for _, part := range strings.Split(v, ",") {
+			result = append(result, strings.TrimSpace(part))
+		}
+		return result
+	}
+	return []string{stringify(target)}
+}
+
+func stringify(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(t)
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
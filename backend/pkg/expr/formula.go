@@ -0,0 +1,426 @@
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Env 公式变量取值：标量字段返回单个值，表格列（如 items.subtotal）返回列表，未知变量 ok 为 false
+type Env func(name string) (values []float64, isList bool, ok bool)
+
+// Formula 已编译的算术公式，支持 + - * / %、括号、数字、字段变量及函数：
+// sum/avg/min/max/count（参数可为表格列或多个标量）、round(x[, n])、abs、ceil、floor
+type Formula struct {
+	src  string
+	root node
+}
+
+// CompileFormula 编译公式
+func CompileFormula(src string) (*Formula, error) {
+	p := &parser{src: src}
+	p.next()
+	root, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, fmt.Errorf("公式在 %q 附近有多余内容", p.tok.text)
+	}
+	return &Formula{src: src, root: root}, nil
+}
+
+// String 返回公式原文
+func (f *Formula) String() string {
+	return f.src
+}
+
+// Vars 公式引用的变量名
+func (f *Formula) Vars() []string {
+	var vars []string
+	seen := make(map[string]bool)
+	walk(f.root, func(n node) {
+		if v, ok := n.(varNode); ok && !seen[string(v)] {
+			seen[string(v)] = true
+			vars = append(vars, string(v))
+		}
+	})
+	return vars
+}
+
+// Eval 计算公式结果
+func (f *Formula) Eval(env Env) (float64, error) {
+	v, err := f.root.eval(env)
+	if err != nil {
+		return 0, err
+	}
+	if v.isList {
+		return 0, errors.New("公式结果不能是列表，请使用 sum 等聚合函数")
+	}
+	if math.IsNaN(v.num) || math.IsInf(v.num, 0) {
+		return 0, errors.New("公式结果无效")
+	}
+	return v.num, nil
+}
+
+// functions 支持的函数
+var functions = map[string]bool{
+	"sum": true, "avg": true, "min": true, "max": true, "count": true,
+	"round": true, "abs": true, "ceil": true, "floor": true,
+}
+
+// value 求值中间结果：标量或列表
+type value struct {
+	num    float64
+	list   []float64
+	isList bool
+}
+
+type node interface {
+	eval(env Env) (value, error)
+}
+
+type numNode float64
+
+type varNode string
+
+type negNode struct{ x node }
+
+type binNode struct {
+	op   byte
+	l, r node
+}
+
+type callNode struct {
+	name string
+	args []node
+}
+
+func walk(n node, fn func(node)) {
+	fn(n)
+	switch t := n.(type) {
+	case negNode:
+		walk(t.x, fn)
+	case binNode:
+		walk(t.l, fn)
+		walk(t.r, fn)
+	case callNode:
+		for _, a := range t.args {
+			walk(a, fn)
+		}
+	}
+}
+
+func (n numNode) eval(Env) (value, error) {
+	return value{num: float64(n)}, nil
+}
+
+func (n varNode) eval(env Env) (value, error) {
+	values, isList, ok := env(string(n))
+	if !ok {
+		return value{}, fmt.Errorf("未知变量: %s", string(n))
+	}
+	if isList {
+		return value{list: values, isList: true}, nil
+	}
+	if len(values) == 0 {
+		return value{}, nil
+	}
+	return value{num: values[0]}, nil
+}
+
+func (n negNode) eval(env Env) (value, error) {
+	v, err := scalar(n.x, env)
+	return value{num: -v}, err
+}
+
+func (n binNode) eval(env Env) (value, error) {
+	l, err := scalar(n.l, env)
+	if err != nil {
+		return value{}, err
+	}
+	r, err := scalar(n.r, env)
+	if err != nil {
+		return value{}, err
+	}
+	switch n.op {
+	case '+':
+		return value{num: l + r}, nil
+	case '-':
+		return value{num: l - r}, nil
+	case '*':
+		return value{num: l * r}, nil
+	case '/':
+		if r == 0 {
+			return value{}, errors.New("除数为 0")
+		}
+		return value{num: l / r}, nil
+	case '%':
+		if r == 0 {
+			return value{}, errors.New("除数为 0")
+		}
+		return value{num: math.Mod(l, r)}, nil
+	}
+	return value{}, fmt.Errorf("不支持的运算符: %c", n.op)
+}
+
+func (n callNode) eval(env Env) (value, error) {
+	switch n.name {
+	case "sum", "avg", "min", "max", "count":
+		var nums []float64
+		for _, a := range n.args {
+			v, err := a.eval(env)
+			if err != nil {
+				return value{}, err
+			}
+			if v.isList {
+				nums = append(nums, v.list...)
+			} else {
+				nums = append(nums, v.num)
+			}
+		}
+		return value{num: aggregate(n.name, nums)}, nil
+	case "round":
+		if len(n.args) < 1 || len(n.args) > 2 {
+			return value{}, errors.New("round 需要 1 或 2 个参数")
+		}
+		x, err := scalar(n.args[0], env)
+		if err != nil {
+			return value{}, err
+		}
+		digits := 0.0
+		if len(n.args) == 2 {
+			if digits, err = scalar(n.args[1], env); err != nil {
+				return value{}, err
+			}
+		}
+		return value{num: Round(x, int(digits))}, nil
+	case "abs", "ceil", "floor":
+		if len(n.args) != 1 {
+			return value{}, fmt.Errorf("%s 需要 1 个参数", n.name)
+		}
+		x, err := scalar(n.args[0], env)
+		if err != nil {
+			return value{}, err
+		}
+		fn := map[string]func(float64) float64{"abs": math.Abs, "ceil": math.Ceil, "floor": math.Floor}[n.name]
+		return value{num: fn(x)}, nil
+	}
+	return value{}, fmt.Errorf("不支持的函数: %s", n.name)
+}
+
+func aggregate(name string, nums []float64) float64 {
+	if name == "count" {
+		return float64(len(nums))
+	}
+	if len(nums) == 0 {
+		return 0
+	}
+	result := nums[0]
+	sum := 0.0
+	for _, n := range nums {
+		sum += n
+		if name == "min" && n < result || name == "max" && n > result {
+			result = n
+		}
+	}
+	switch name {
+	case "sum":
+		return sum
+	case "avg":
+		return sum / float64(len(nums))
+	}
+	return result
+}
+
+func scalar(n node, env Env) (float64, error) {
+	v, err := n.eval(env)
+	if err != nil {
+		return 0, err
+	}
+	if v.isList {
+		return 0, errors.New("列表变量需使用 sum 等聚合函数")
+	}
+	return v.num, nil
+}
+
+// Round 按小数位数四舍五入
+func Round(x float64, digits int) float64 {
+	p := math.Pow(10, float64(digits))
+	return math.Round(x*p) / p
+}
+
+// ==================== 词法与语法分析 ====================
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokNum
+	tokIdent
+	tokOp
+)
+
+type token struct {
+	kind tokKind
+	text string
+}
+
+type parser struct {
+	src string
+	pos int
+	tok token
+	err error
+}
+
+func (p *parser) next() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+	if p.pos >= len(p.src) {
+		p.tok = token{kind: tokEOF}
+		return
+	}
+	start := p.pos
+	r := rune(p.src[p.pos])
+	switch {
+	case r >= '0' && r <= '9' || r == '.':
+		for p.pos < len(p.src) && (p.src[p.pos] >= '0' && p.src[p.pos] <= '9' || p.src[p.pos] == '.') {
+			p.pos++
+		}
+		p.tok = token{kind: tokNum, text: p.src[start:p.pos]}
+	case strings.ContainsRune("+-*/%(),", r):
+		p.pos++
+		p.tok = token{kind: tokOp, text: string(r)}
+	default:
+		for _, c := range p.src[p.pos:] {
+			if !(c == '_' || c == '.' || unicode.IsLetter(c) || unicode.IsDigit(c)) {
+				break
+			}
+			p.pos += len(string(c))
+		}
+		if p.pos == start {
+			p.err = fmt.Errorf("公式包含无效字符: %q", string([]rune(p.src[start:])[0]))
+			p.tok = token{kind: tokEOF}
+			return
+		}
+		p.tok = token{kind: tokIdent, text: p.src[start:p.pos]}
+	}
+}
+
+func (p *parser) isOp(ops string) bool {
+	return p.tok.kind == tokOp && strings.Contains(ops, p.tok.text)
+}
+
+func (p *parser) parseExpr() (node, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("+-") {
+		op := p.tok.text[0]
+		p.next()
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = binNode{op: op, l: left, r: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseTerm() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("*/%") {
+		op := p.tok.text[0]
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = binNode{op: op, l: left, r: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.isOp("-") {
+		p.next()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return negNode{x: x}, nil
+	}
+	if p.isOp("+") {
+		p.next()
+		return p.parseUnary()
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	tok := p.tok
+	switch tok.kind {
+	case tokNum:
+		n, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("无效的数字: %s", tok.text)
+		}
+		p.next()
+		return numNode(n), nil
+	case tokIdent:
+		p.next()
+		if !p.isOp("(") {
+			return varNode(tok.text), nil
+		}
+		p.next()
+		call := callNode{name: strings.ToLower(tok.text)}
+		if !functions[call.name] {
+			return nil, fmt.Errorf("不支持的函数: %s", tok.text)
+		}
+		for !p.isOp(")") {
+			arg, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+			if p.isOp(",") {
+				p.next()
+			} else if !p.isOp(")") {
+				return nil, fmt.Errorf("函数 %s 缺少右括号", tok.text)
+			}
+		}
+		p.next()
+		return call, nil
+	case tokOp:
+		if tok.text == "(" {
+			p.next()
+			inner, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if !p.isOp(")") {
+				return nil, errors.New("缺少右括号")
+			}
+			p.next()
+			return inner, nil
+		}
+	}
+	if tok.kind == tokEOF {
+		return nil, errors.New("公式不完整")
+	}
+	return nil, fmt.Errorf("公式在 %q 附近有语法错误", tok.text)
+}
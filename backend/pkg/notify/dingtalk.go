@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 )
 
@@ -22,7 +23,7 @@ func NewDingTalkNotifier(cfg *DingTalkConfig) *DingTalkNotifier {
 	return &DingTalkNotifier{config: cfg}
 }
 
-// Send 发送钉钉消息
+// Send 发送钉钉消息，to 为钉钉用户ID（多个以逗号分隔）时在群内 @ 对应用户，为空时发送到群
 func (n *DingTalkNotifier) Send(to string, title string, content string) error {
 	if !n.config.Enabled {
 		return nil
@@ -38,13 +39,27 @@ func (n *DingTalkNotifier) Send(to string, title string, content string) error {
 	}
 
 	// 构建消息体
+	text := fmt.Sprintf("### %s\n\n%s", title, content)
+	var atUserIDs, mentions []string
+	for _, id := range strings.Split(to, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			atUserIDs = append(atUserIDs, id)
+			mentions = append(mentions, "@"+id)
+		}
+	}
+	if len(mentions) > 0 {
+		text += "\n\n" + strings.Join(mentions, " ")
+	}
 	msg := map[string]interface{}{
 		"msgtype": "markdown",
 		"markdown": map[string]string{
 			"title": title,
-			"text":  fmt.Sprintf("### %s\n\n%s", title, content),
+			"text":  text,
 		},
 	}
+	if len(atUserIDs) > 0 {
+		msg["at"] = map[string]interface{}{"atUserIds": atUserIDs}
+	}
 
 	body, _ := json.Marshal(msg)
 	resp, err := http.Post(webhook, "application/json", bytes.NewReader(body))
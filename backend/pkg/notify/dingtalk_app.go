@@ -0,0 +1,259 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultDingTalkBaseURL    = "https://oapi.dingtalk.com" // 钉钉开放平台接口地址
+	DefaultDingTalkAPIBaseURL = "https://api.dingtalk.com"  // 钉钉新版接口地址（互动卡片）
+)
+
+// errDingTalkTokenInvalid access_token 无效或已过期
+var errDingTalkTokenInvalid = errors.New("钉钉 access_token 无效或已过期")
+
+// DingTalkAppNotifier 钉钉企业内部应用通知（工作通知、互动卡片，按用户发送）
+type DingTalkAppNotifier struct {
+	config      *DingTalkConfig
+	baseURL     string
+	apiBaseURL  string
+	client      *http.Client
+	accessToken string
+	tokenExpiry time.Time
+	mu          sync.Mutex
+}
+
+// NewDingTalkAppNotifier 创建钉钉企业内部应用通知
+func NewDingTalkAppNotifier(cfg *DingTalkConfig) *DingTalkAppNotifier {
+	baseURL := strings.TrimRight(cfg.BaseURL, "/")
+	apiBaseURL := strings.TrimRight(cfg.APIBaseURL, "/")
+	if apiBaseURL == "" {
+		// 仅配置 base_url 时（如指向本地模拟服务）新版接口使用同一地址
+		apiBaseURL = baseURL
+	}
+	if baseURL == "" {
+		baseURL = DefaultDingTalkBaseURL
+	}
+	if apiBaseURL == "" {
+		apiBaseURL = DefaultDingTalkAPIBaseURL
+	}
+	return &DingTalkAppNotifier{
+		config:     cfg,
+		baseURL:    baseURL,
+		apiBaseURL: apiBaseURL,
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Send 发送 Markdown 工作通知，to 为钉钉用户ID（多个以逗号分隔）
+func (n *DingTalkAppNotifier) Send(to string, title string, content string) error {
+	return n.sendWorkNotification(to, map[string]interface{}{
+		"msgtype": "markdown",
+		"markdown": map[string]string{
+			"title": title,
+			"text":  fmt.Sprintf("### %s\n\n%s", title, content),
+		},
+	})
+}
+
+// SendActionCard 发送带按钮的卡片：配置了互动卡片模板且按钮带回调参数时发送互动卡片（点击按钮回调到事件回调地址），
+// 否则发送按钮竖直排列、点击后在钉钉内打开链接的卡片工作通知
+func (n *DingTalkAppNotifier) SendActionCard(to string, title string, content string, buttons []ActionButton) error {
+	if n.config.CardTemplateID != "" && hasButtonValues(buttons) {
+		return n.sendInteractiveCard(to, title, content, buttons)
+	}
+	btns := make([]map[string]string, 0, len(buttons))
+	for _, b := range buttons {
+		btns = append(btns, map[string]string{"title": b.Title, "action_url": b.URL})
+	}
+	return n.sendWorkNotification(to, map[string]interface{}{
+		"msgtype": "action_card",
+		"action_card": map[string]interface{}{
+			"title":           title,
+			"markdown":        fmt.Sprintf("### %s\n\n%s", title, content),
+			"btn_orientation": "1",
+			"btn_json_list":   btns,
+		},
+	})
+}
+
+// hasButtonValues 所有按钮都带回调参数
+func hasButtonValues(buttons []ActionButton) bool {
+	for _, b := range buttons {
+		if b.Value == "" {
+			return false
+		}
+	}
+	return len(buttons) > 0
+}
+
+// sendInteractiveCard 以机器人单聊发送互动卡片。卡片模板使用 title、markdown、buttons 变量，
+// buttons 为 [{title, url, value}] 的 JSON，按钮回调参数应配置为 {"token": value}
+func (n *DingTalkAppNotifier) sendInteractiveCard(to string, title string, content string, buttons []ActionButton) error {
+	if !n.config.Enabled {
+		return nil
+	}
+	if strings.TrimSpace(to) == "" {
+		return fmt.Errorf("钉钉互动卡片缺少接收用户")
+	}
+	btns, _ := json.Marshal(buttons)
+	trackID := make([]byte, 16)
+	if _, err := rand.Read(trackID); err != nil {
+		return err
+	}
+	robotCode := n.config.RobotCode
+	if robotCode == "" {
+		robotCode = n.config.AppKey
+	}
+	body, _ := json.Marshal(map[string]interface{}{
+		"cardTemplateId":     n.config.CardTemplateID,
+		"outTrackId":         hex.EncodeToString(trackID),
+		"robotCode":          robotCode,
+		"conversationType":   0,
+		"receiverUserIdList": strings.Split(to, ","),
+		"callbackRouteKey":   n.config.CallbackRouteKey,
+		"cardData": map[string]interface{}{
+			"cardParamMap": map[string]string{
+				"title":    title,
+				"markdown": fmt.Sprintf("### %s\n\n%s", title, content),
+				"buttons":  string(btns),
+			},
+		},
+	})
+	return n.withAccessToken(func(token string) error {
+		req, err := http.NewRequest(http.MethodPost, n.apiBaseURL+"/v1.0/im/interactiveCards/send", bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("x-acs-dingtalk-access-token", token)
+		resp, err := n.client.Do(req)
+		if err != nil {
+			return fmt.Errorf("发送钉钉互动卡片失败: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode == http.StatusOK {
+			return nil
+		}
+		var result struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		}
+		json.NewDecoder(resp.Body).Decode(&result)
+		if resp.StatusCode == http.StatusUnauthorized || result.Code == "InvalidAuthentication" {
+			return errDingTalkTokenInvalid
+		}
+		return fmt.Errorf("钉钉返回错误: %d %s %s", resp.StatusCode, result.Code, result.Message)
+	})
+}
+
+// sendWorkNotification 调用工作通知接口
+func (n *DingTalkAppNotifier) sendWorkNotification(to string, msg map[string]interface{}) error {
+	if !n.config.Enabled {
+		return nil
+	}
+	if strings.TrimSpace(to) == "" {
+		return fmt.Errorf("钉钉工作通知缺少接收用户")
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"agent_id":    n.config.AgentID,
+		"userid_list": to,
+		"msg":         msg,
+	})
+	return n.withAccessToken(func(token string) error {
+		endpoint := fmt.Sprintf("%s/topapi/message/corpconversation/asyncsend_v2?access_token=%s", n.baseURL, url.QueryEscape(token))
+		resp, err := n.client.Post(endpoint, "application/json", bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("发送钉钉工作通知失败: %w", err)
+		}
+		defer resp.Body.Close()
+
+		var result struct {
+			ErrCode int    `json:"errcode"`
+			ErrMsg  string `json:"errmsg"`
+			TaskID  int64  `json:"task_id"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			return fmt.Errorf("钉钉返回内容解析失败: %w", err)
+		}
+		if result.ErrCode == 40014 || result.ErrCode == 42001 {
+			return errDingTalkTokenInvalid
+		}
+		if result.ErrCode != 0 {
+			return fmt.Errorf("钉钉返回错误: %d %s", result.ErrCode, result.ErrMsg)
+		}
+		return nil
+	})
+}
+
+// withAccessToken 使用缓存的 access_token 调用接口，令牌无效或过期时清除缓存、重新获取后重试一次
+func (n *DingTalkAppNotifier) withAccessToken(call func(token string) error) error {
+	for attempt := 0; ; attempt++ {
+		token, err := n.getAccessToken()
+		if err != nil {
+			return err
+		}
+		err = call(token)
+		if !errors.Is(err, errDingTalkTokenInvalid) {
+			return err
+		}
+		n.resetAccessToken()
+		if attempt > 0 {
+			return err
+		}
+	}
+}
+
+// getAccessToken 获取 access_token（有效期内复用）
+func (n *DingTalkAppNotifier) getAccessToken() (string, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.accessToken != "" && time.Now().Before(n.tokenExpiry) {
+		return n.accessToken, nil
+	}
+
+	endpoint := fmt.Sprintf("%s/gettoken?appkey=%s&appsecret=%s", n.baseURL,
+		url.QueryEscape(n.config.AppKey), url.QueryEscape(n.config.AppSecret))
+	resp, err := n.client.Get(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("获取钉钉 access_token 失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		ErrCode     int    `json:"errcode"`
+		ErrMsg      string `json:"errmsg"`
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	if result.ErrCode != 0 {
+		return "", fmt.Errorf("获取钉钉 access_token 失败: %s", result.ErrMsg)
+	}
+
+	n.accessToken = result.AccessToken
+	n.tokenExpiry = time.Now().Add(time.Duration(result.ExpiresIn-60) * time.Second)
+	return n.accessToken, nil
+}
+
+// resetAccessToken 清除缓存的 access_token
+func (n *DingTalkAppNotifier) resetAccessToken() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.accessToken = ""
+}
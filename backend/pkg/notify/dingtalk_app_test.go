@@ -0,0 +1,147 @@
+package notify_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"backend/pkg/notify"
+	"backend/pkg/notify/dingtalkmock"
+)
+
+func newAppNotifier(t *testing.T) (*dingtalkmock.Mock, *notify.DingTalkAppNotifier) {
+	t.Helper()
+	mock, server := dingtalkmock.NewServer("key", "secret")
+	t.Cleanup(server.Close)
+	return mock, notify.NewDingTalkAppNotifier(&notify.DingTalkConfig{
+		Enabled:   true,
+		Mode:      notify.DingTalkModeApp,
+		AppKey:    "key",
+		AppSecret: "secret",
+		AgentID:   100,
+		BaseURL:   server.URL,
+	})
+}
+
+func TestDingTalkAppNotifierSendsWorkNotification(t *testing.T) {
+	mock, n := newAppNotifier(t)
+	if err := n.Send("u1,u2", "待审批", "请处理工单 IT-0001"); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	messages := mock.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("messages = %d, want 1", len(messages))
+	}
+	m := messages[0]
+	if m.AgentID != 100 || m.UserIDList != "u1,u2" {
+		t.Errorf("agent_id = %d, userid_list = %q", m.AgentID, m.UserIDList)
+	}
+	var msg struct {
+		MsgType  string `json:"msgtype"`
+		Markdown struct {
+			Title string `json:"title"`
+			Text  string `json:"text"`
+		} `json:"markdown"`
+	}
+	if err := json.Unmarshal(m.Msg, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.MsgType != "markdown" || msg.Markdown.Title != "待审批" || msg.Markdown.Text != "### 待审批\n\n请处理工单 IT-0001" {
+		t.Errorf("msg = %+v", msg)
+	}
+}
+
+func TestDingTalkAppNotifierReusesAccessToken(t *testing.T) {
+	mock, n := newAppNotifier(t)
+	for i := 0; i < 3; i++ {
+		if err := n.Send("u1", "标题", "内容"); err != nil {
+			t.Fatalf("Send #%d: %v", i, err)
+		}
+	}
+	if got := mock.TokenRequests(); got != 1 {
+		t.Errorf("token requests = %d, want 1", got)
+	}
+	if got := len(mock.Messages()); got != 3 {
+		t.Errorf("messages = %d, want 3", got)
+	}
+}
+
+func TestDingTalkAppNotifierRefreshesInvalidToken(t *testing.T) {
+	tests := []struct {
+		name       string
+		invalidate func(*dingtalkmock.Mock)
+	}{
+		{"revoked (40014)", (*dingtalkmock.Mock).RevokeTokens},
+		{"expired (42001)", (*dingtalkmock.Mock).ExpireTokens},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock, n := newAppNotifier(t)
+			if err := n.Send("u1", "标题", "内容"); err != nil {
+				t.Fatalf("Send: %v", err)
+			}
+			tt.invalidate(mock)
+			if err := n.Send("u1", "标题", "内容"); err != nil {
+				t.Fatalf("Send after token invalidated: %v", err)
+			}
+			if got := mock.TokenRequests(); got != 2 {
+				t.Errorf("token requests = %d, want 2", got)
+			}
+			if got := len(mock.Messages()); got != 2 {
+				t.Errorf("messages = %d, want 2", got)
+			}
+		})
+	}
+}
+
+func TestDingTalkAppNotifierSendsInteractiveCard(t *testing.T) {
+	mock, server := dingtalkmock.NewServer("key", "secret")
+	defer server.Close()
+	n := notify.NewDingTalkAppNotifier(&notify.DingTalkConfig{
+		Enabled:          true,
+		Mode:             notify.DingTalkModeApp,
+		AppKey:           "key",
+		AppSecret:        "secret",
+		BaseURL:          server.URL,
+		CardTemplateID:   "approval.schema",
+		CallbackRouteKey: "ticket-approval",
+	})
+	buttons := []notify.ActionButton{
+		{Title: "通过", URL: "https://example.com/a", Value: "approve-token"},
+		{Title: "拒绝", URL: "https://example.com/r", Value: "reject-token"},
+	}
+	if err := n.SendActionCard("u1", "待审批", "内容", buttons); err != nil {
+		t.Fatalf("SendActionCard: %v", err)
+	}
+
+	cards := mock.Cards()
+	if len(cards) != 1 || len(mock.Messages()) != 0 {
+		t.Fatalf("cards = %d, messages = %d, want 1 card", len(cards), len(mock.Messages()))
+	}
+	card := cards[0]
+	if card.CardTemplateID != "approval.schema" || card.RobotCode != "key" || card.CallbackRouteKey != "ticket-approval" ||
+		len(card.ReceiverUserIDList) != 1 || card.ReceiverUserIDList[0] != "u1" {
+		t.Errorf("card = %+v", card)
+	}
+	var data struct {
+		CardParamMap map[string]string `json:"cardParamMap"`
+	}
+	if err := json.Unmarshal(card.CardData, &data); err != nil {
+		t.Fatal(err)
+	}
+	var got []notify.ActionButton
+	if err := json.Unmarshal([]byte(data.CardParamMap["buttons"]), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Value != "approve-token" || got[1].Value != "reject-token" {
+		t.Errorf("buttons = %+v", got)
+	}
+
+	// 按钮没有回调参数时退回为打开链接的卡片工作通知
+	if err := n.SendActionCard("u1", "待审批", "内容", []notify.ActionButton{{Title: "查看", URL: "https://example.com"}}); err != nil {
+		t.Fatalf("SendActionCard: %v", err)
+	}
+	if len(mock.Cards()) != 1 || len(mock.Messages()) != 1 {
+		t.Errorf("cards = %d, messages = %d, want 1 each", len(mock.Cards()), len(mock.Messages()))
+	}
+}
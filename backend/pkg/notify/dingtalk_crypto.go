@@ -0,0 +1,127 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DingTalkCrypto 钉钉事件回调加解密：签名为 token、时间戳、随机串、密文排序拼接后的 SHA1，
+// 内容为 AES-256-CBC 加密的「16 字节随机串 + 4 字节长度 + 消息 + AppKey」
+type DingTalkCrypto struct {
+	token  string
+	key    []byte
+	appKey string
+}
+
+// NewDingTalkCrypto 创建回调加解密器，aesKey 为 43 位 Base64 编码的密钥
+func NewDingTalkCrypto(token, aesKey, appKey string) (*DingTalkCrypto, error) {
+	if token == "" || aesKey == "" {
+		return nil, errors.New("未配置钉钉回调 Token 或 AES Key")
+	}
+	key, err := base64.StdEncoding.DecodeString(aesKey + "=")
+	if err != nil || len(key) != 32 {
+		return nil, errors.New("钉钉回调 AES Key 无效")
+	}
+	return &DingTalkCrypto{token: token, key: key, appKey: appKey}, nil
+}
+
+// Signature 计算回调签名
+func (c *DingTalkCrypto) Signature(timestamp, nonce, encrypt string) string {
+	parts := []string{c.token, timestamp, nonce, encrypt}
+	sort.Strings(parts)
+	sum := sha1.Sum([]byte(strings.Join(parts, "")))
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifySignature 校验回调签名
+func (c *DingTalkCrypto) VerifySignature(signature, timestamp, nonce, encrypt string) bool {
+	expected := c.Signature(timestamp, nonce, encrypt)
+	return subtle.ConstantTimeCompare([]byte(signature), []byte(expected)) == 1
+}
+
+// Decrypt 解密回调内容并校验 AppKey
+func (c *DingTalkCrypto) Decrypt(encrypt string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(encrypt)
+	if err != nil {
+		return nil, fmt.Errorf("回调内容解码失败: %w", err)
+	}
+	if len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return nil, errors.New("回调内容长度无效")
+	}
+	block, err := aes.NewCipher(c.key)
+	if err != nil {
+		return nil, err
+	}
+	plain := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, c.key[:aes.BlockSize]).CryptBlocks(plain, data)
+
+	pad := int(plain[len(plain)-1])
+	if pad < 1 || pad > 32 || pad > len(plain) {
+		return nil, errors.New("回调内容填充无效")
+	}
+	plain = plain[:len(plain)-pad]
+	if len(plain) < 20 {
+		return nil, errors.New("回调内容长度无效")
+	}
+	size := int(binary.BigEndian.Uint32(plain[16:20]))
+	if size < 0 || 20+size > len(plain) {
+		return nil, errors.New("回调内容长度无效")
+	}
+	msg, appKey := plain[20:20+size], string(plain[20+size:])
+	if c.appKey != "" && appKey != c.appKey {
+		return nil, errors.New("回调 AppKey 不匹配")
+	}
+	return msg, nil
+}
+
+// Encrypt 加密消息（用于回调响应）
+func (c *DingTalkCrypto) Encrypt(msg []byte) (string, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	buf.Write(random)
+	size := make([]byte, 4)
+	binary.BigEndian.PutUint32(size, uint32(len(msg)))
+	buf.Write(size)
+	buf.Write(msg)
+	buf.WriteString(c.appKey)
+
+	pad := 32 - buf.Len()%32
+	buf.Write(bytes.Repeat([]byte{byte(pad)}, pad))
+
+	block, err := aes.NewCipher(c.key)
+	if err != nil {
+		return "", err
+	}
+	data := buf.Bytes()
+	out := make([]byte, len(data))
+	cipher.NewCBCEncrypter(block, c.key[:aes.BlockSize]).CryptBlocks(out, data)
+	return base64.StdEncoding.EncodeToString(out), nil
+}
+
+// EncryptResponse 生成回调响应：{msg_signature, timeStamp, nonce, encrypt}
+func (c *DingTalkCrypto) EncryptResponse(msg []byte, timestamp, nonce string) (map[string]string, error) {
+	encrypt, err := c.Encrypt(msg)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{
+		"msg_signature": c.Signature(timestamp, nonce, encrypt),
+		"timeStamp":     timestamp,
+		"nonce":         nonce,
+		"encrypt":       encrypt,
+	}, nil
+}
@@ -0,0 +1,252 @@
+// Package dingtalkmock 钉钉开放平台接口的本地模拟，用于联调企业内部应用工作通知和事件回调
+package dingtalkmock
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+
+	"backend/pkg/notify"
+)
+
+// Message 收到的工作通知
+type Message struct {
+	AgentID    int64           `json:"agent_id"`
+	UserIDList string          `json:"userid_list"`
+	Msg        json.RawMessage `json:"msg"`
+	TaskID     int64           `json:"task_id"`
+	ReceivedAt time.Time       `json:"received_at"`
+}
+
+// Card 收到的互动卡片
+type Card struct {
+	CardTemplateID     string          `json:"cardTemplateId"`
+	OutTrackID         string          `json:"outTrackId"`
+	RobotCode          string          `json:"robotCode"`
+	ReceiverUserIDList []string        `json:"receiverUserIdList"`
+	CallbackRouteKey   string          `json:"callbackRouteKey"`
+	CardData           json.RawMessage `json:"cardData"`
+	ReceivedAt         time.Time       `json:"received_at"`
+}
+
+// Mock 模拟钉钉开放平台：GET /gettoken、POST /topapi/message/corpconversation/asyncsend_v2、
+// POST /v1.0/im/interactiveCards/send，以及查看收到消息的 GET /_mock/messages、GET /_mock/cards
+type Mock struct {
+	AppKey    string
+	AppSecret string
+	TokenTTL  time.Duration // access_token 有效期，默认 2 小时
+
+	mu            sync.Mutex
+	tokens        map[string]time.Time
+	tokenRequests int
+	messages      []Message
+	cards         []Card
+	mux           *http.ServeMux
+}
+
+// New 创建模拟服务
+func New(appKey, appSecret string) *Mock {
+	m := &Mock{
+		AppKey:    appKey,
+		AppSecret: appSecret,
+		TokenTTL:  2 * time.Hour,
+		tokens:    make(map[string]time.Time),
+		mux:       http.NewServeMux(),
+	}
+	m.mux.HandleFunc("/gettoken", m.getToken)
+	m.mux.HandleFunc("/topapi/message/corpconversation/asyncsend_v2", m.asyncSend)
+	m.mux.HandleFunc("/v1.0/im/interactiveCards/send", m.sendInteractiveCard)
+	m.mux.HandleFunc("/_mock/messages", m.listMessages)
+	m.mux.HandleFunc("/_mock/cards", m.listCards)
+	return m
+}
+
+// NewServer 创建并启动模拟服务（监听本地随机端口），返回的 URL 可作为钉钉配置的 base_url
+func NewServer(appKey, appSecret string) (*Mock, *httptest.Server) {
+	m := New(appKey, appSecret)
+	return m, httptest.NewServer(m)
+}
+
+// ServeHTTP 实现 http.Handler
+func (m *Mock) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mux.ServeHTTP(w, r)
+}
+
+// Messages 收到的工作通知
+func (m *Mock) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.messages...)
+}
+
+// Cards 收到的互动卡片
+func (m *Mock) Cards() []Card {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Card(nil), m.cards...)
+}
+
+// TokenRequests 获取 access_token 的次数
+func (m *Mock) TokenRequests() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.tokenRequests
+}
+
+// ExpireTokens 使已发放的 access_token 全部过期（接口返回 42001）
+func (m *Mock) ExpireTokens() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for token := range m.tokens {
+		m.tokens[token] = time.Now().Add(-time.Second)
+	}
+}
+
+// RevokeTokens 作废已发放的 access_token（接口返回 40014）
+func (m *Mock) RevokeTokens() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tokens = make(map[string]time.Time)
+}
+
+// checkToken 校验 access_token，返回钉钉错误码（0 表示有效）
+func (m *Mock) checkToken(token string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	expiry, ok := m.tokens[token]
+	if !ok {
+		return 40014
+	}
+	if time.Now().After(expiry) {
+		return 42001
+	}
+	return 0
+}
+
+func (m *Mock) getToken(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("appkey") != m.AppKey || q.Get("appsecret") != m.AppSecret {
+		writeJSON(w, map[string]interface{}{"errcode": 40089, "errmsg": "不合法的corpid或corpsecret"})
+		return
+	}
+	token := randomHex(16)
+	m.mu.Lock()
+	m.tokenRequests++
+	m.tokens[token] = time.Now().Add(m.TokenTTL)
+	m.mu.Unlock()
+	writeJSON(w, map[string]interface{}{
+		"errcode":      0,
+		"errmsg":       "ok",
+		"access_token": token,
+		"expires_in":   int(m.TokenTTL.Seconds()),
+	})
+}
+
+func (m *Mock) asyncSend(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	switch m.checkToken(r.URL.Query().Get("access_token")) {
+	case 40014:
+		writeJSON(w, map[string]interface{}{"errcode": 40014, "errmsg": "不合法的access_token"})
+		return
+	case 42001:
+		writeJSON(w, map[string]interface{}{"errcode": 42001, "errmsg": "access_token超时"})
+		return
+	}
+
+	var msg Message
+	if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+		writeJSON(w, map[string]interface{}{"errcode": 40035, "errmsg": "不合法的参数"})
+		return
+	}
+	if msg.UserIDList == "" || len(msg.Msg) == 0 {
+		writeJSON(w, map[string]interface{}{"errcode": 40035, "errmsg": "缺少参数 userid_list 或 msg"})
+		return
+	}
+	m.mu.Lock()
+	msg.TaskID = int64(len(m.messages) + 1)
+	msg.ReceivedAt = time.Now()
+	m.messages = append(m.messages, msg)
+	m.mu.Unlock()
+	writeJSON(w, map[string]interface{}{"errcode": 0, "errmsg": "ok", "task_id": msg.TaskID})
+}
+
+func (m *Mock) sendInteractiveCard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if m.checkToken(r.Header.Get("x-acs-dingtalk-access-token")) != 0 {
+		writeAPIError(w, http.StatusUnauthorized, "InvalidAuthentication", "不合法的access_token")
+		return
+	}
+
+	var card Card
+	if err := json.NewDecoder(r.Body).Decode(&card); err != nil || card.CardTemplateID == "" || len(card.ReceiverUserIDList) == 0 {
+		writeAPIError(w, http.StatusBadRequest, "InvalidParameter", "缺少参数 cardTemplateId 或 receiverUserIdList")
+		return
+	}
+	m.mu.Lock()
+	card.ReceivedAt = time.Now()
+	m.cards = append(m.cards, card)
+	m.mu.Unlock()
+	writeJSON(w, map[string]interface{}{"result": map[string]string{"processQueryKey": randomHex(8)}})
+}
+
+func (m *Mock) listMessages(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, m.Messages())
+}
+
+func (m *Mock) listCards(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, m.Cards())
+}
+
+// NewCallbackRequest 构造一条按钉钉规则签名并加密的事件回调请求，用于模拟钉钉推送事件
+func NewCallbackRequest(crypto *notify.DingTalkCrypto, callbackURL string, event interface{}) (*http.Request, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	encrypt, err := crypto.Encrypt(data)
+	if err != nil {
+		return nil, err
+	}
+	timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	nonce := randomHex(8)
+	body, _ := json.Marshal(map[string]string{"encrypt": encrypt})
+	url := fmt.Sprintf("%s?msg_signature=%s&timestamp=%s&nonce=%s",
+		callbackURL, crypto.Signature(timestamp, nonce, encrypt), timestamp, nonce)
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeAPIError 新版接口的错误响应：HTTP 状态码 + {code, message}
+func writeAPIError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"code": code, "message": message})
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
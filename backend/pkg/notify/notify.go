@@ -11,11 +11,42 @@ type Config struct {
 	WeChat   WeChatConfig   `json:"wechat"`
 }
 
+// ActionButton 消息卡片按钮
+type ActionButton struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+	Value string `json:"value,omitempty"` // 互动卡片按钮回调参数（如审批令牌），点击后随回调原样返回
+}
+
+// ActionCardSender 支持发送带按钮的消息卡片
+type ActionCardSender interface {
+	SendActionCard(to string, title string, content string, buttons []ActionButton) error
+}
+
+// DingTalk 发送方式
+const (
+	DingTalkModeRobot = "robot" // 群机器人
+	DingTalkModeApp   = "app"   // 企业内部应用工作通知
+)
+
 // DingTalkConfig 钉钉配置
 type DingTalkConfig struct {
 	Enabled bool   `json:"enabled"`
+	Mode    string `json:"mode"`    // 发送方式：robot（默认）、app
 	Webhook string `json:"webhook"` // 机器人 Webhook 地址
 	Secret  string `json:"secret"`  // 签名密钥（可选）
+
+	// 企业内部应用（mode=app）
+	AppKey           string `json:"app_key"`
+	AppSecret        string `json:"app_secret"`
+	AgentID          int64  `json:"agent_id"`
+	BaseURL          string `json:"base_url"`           // 开放平台接口地址，默认 https://oapi.dingtalk.com（本地联调可指向模拟服务）
+	APIBaseURL       string `json:"api_base_url"`       // 新版接口地址，默认 https://api.dingtalk.com，仅配置 base_url 时与其相同
+	CallbackToken    string `json:"callback_token"`     // 事件回调签名 Token
+	CallbackAESKey   string `json:"callback_aes_key"`   // 事件回调加密 AES Key（43 位）
+	CardTemplateID   string `json:"card_template_id"`   // 互动卡片模板ID，配置后审批通知以互动卡片发送
+	RobotCode        string `json:"robot_code"`         // 发送互动卡片的机器人编码，默认与 AppKey 相同
+	CallbackRouteKey string `json:"callback_route_key"` // 互动卡片回调路由，需注册为 /api/v1/dingtalk/callback
 }
 
 // WeChatConfig 企业微信配置
@@ -23,7 +23,7 @@ func NewWeChatNotifier(cfg *WeChatConfig) *WeChatNotifier {
 	return &WeChatNotifier{config: cfg}
 }
 
-// Send 发送企业微信消息
+// Send 发送企业微信应用消息（Markdown 格式）
 func (n *WeChatNotifier) Send(to string, title string, content string) error {
 	if !n.config.Enabled {
 		return nil
@@ -37,10 +37,10 @@ func (n *WeChatNotifier) Send(to string, title string, content string) error {
 	// 构建消息体
 	msg := map[string]interface{}{
 		"touser":  to,
-		"msgtype": "text",
+		"msgtype": "markdown",
 		"agentid": n.config.AgentID,
-		"text": map[string]string{
-			"content": fmt.Sprintf("%s\n\n%s", title, content),
+		"markdown": map[string]string{
+			"content": fmt.Sprintf("### %s\n\n%s", title, content),
 		},
 	}
 
@@ -0,0 +1,210 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// BM25 参数
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// memoryDoc 内存索引中的文档
+type memoryDoc struct {
+	fields  []Field
+	lengths []int           // 每个字段的词数
+	terms   map[string]bool // 文档包含的词（删除时使用）
+}
+
+// MemoryIndex 内嵌内存倒排索引（BM25 评分），进程重启后需重建
+type MemoryIndex struct {
+	mu          sync.RWMutex
+	docs        map[uint]*memoryDoc
+	postings    map[string]map[uint]map[int]int // 词 -> 文档ID -> 字段下标 -> 词频
+	fieldLenSum map[string]int                  // 字段名 -> 词数总和
+	fieldCount  map[string]int                  // 字段名 -> 字段出现次数
+}
+
+// NewMemoryIndex 创建内存索引
+func NewMemoryIndex() *MemoryIndex {
+	idx := &MemoryIndex{}
+	idx.reset()
+	return idx
+}
+
+func (m *MemoryIndex) reset() {
+	m.docs = make(map[uint]*memoryDoc)
+	m.postings = make(map[string]map[uint]map[int]int)
+	m.fieldLenSum = make(map[string]int)
+	m.fieldCount = make(map[string]int)
+}
+
+// Index 添加或替换文档
+func (m *MemoryIndex) Index(doc Document) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.remove(doc.ID)
+
+	md := &memoryDoc{
+		fields:  doc.Fields,
+		lengths: make([]int, len(doc.Fields)),
+		terms:   make(map[string]bool),
+	}
+	for i, f := range doc.Fields {
+		tokens := Tokenize(f.Text)
+		md.lengths[i] = len(tokens)
+		m.fieldLenSum[f.Name] += len(tokens)
+		m.fieldCount[f.Name]++
+		for _, t := range tokens {
+			docs, ok := m.postings[t]
+			if !ok {
+				docs = make(map[uint]map[int]int)
+				m.postings[t] = docs
+			}
+			fields, ok := docs[doc.ID]
+			if !ok {
+				fields = make(map[int]int)
+				docs[doc.ID] = fields
+			}
+			fields[i]++
+			md.terms[t] = true
+		}
+	}
+	m.docs[doc.ID] = md
+	return nil
+}
+
+// Delete 删除文档
+func (m *MemoryIndex) Delete(id uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remove(id)
+	return nil
+}
+
+func (m *MemoryIndex) remove(id uint) {
+	md, ok := m.docs[id]
+	if !ok {
+		return
+	}
+	for t := range md.terms {
+		if docs, ok := m.postings[t]; ok {
+			delete(docs, id)
+			if len(docs) == 0 {
+				delete(m.postings, t)
+			}
+		}
+	}
+	for i, f := range md.fields {
+		m.fieldLenSum[f.Name] -= md.lengths[i]
+		m.fieldCount[f.Name]--
+	}
+	delete(m.docs, id)
+}
+
+// Reset 清空索引
+func (m *MemoryIndex) Reset() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reset()
+	return nil
+}
+
+// Count 文档数量
+func (m *MemoryIndex) Count() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.docs)
+}
+
+// Search 检索：文档需包含全部查询词，按 BM25 得分降序
+func (m *MemoryIndex) Search(q Query) (*Result, error) {
+	terms := uniqueTokens(q.Text)
+	if len(terms) == 0 {
+		return &Result{}, nil
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	// 以文档数最少的词为起点求交集
+	sort.Slice(terms, func(i, j int) bool {
+		return len(m.postings[terms[i]]) < len(m.postings[terms[j]])
+	})
+	candidates := m.postings[terms[0]]
+	if len(candidates) == 0 {
+		return &Result{}, nil
+	}
+
+	total := float64(len(m.docs))
+	var hits []Hit
+	for id := range candidates {
+		if q.Filter != nil && !q.Filter(id) {
+			continue
+		}
+		score := 0.0
+		matched := true
+		for _, t := range terms {
+			fields, ok := m.postings[t][id]
+			if !ok {
+				matched = false
+				break
+			}
+			df := float64(len(m.postings[t]))
+			idf := math.Log(1 + (total-df+0.5)/(df+0.5))
+			md := m.docs[id]
+			for fi, tf := range fields {
+				f := md.fields[fi]
+				boost := f.Boost
+				if boost <= 0 {
+					boost = 1
+				}
+				avg := 1.0
+				if c := m.fieldCount[f.Name]; c > 0 {
+					avg = float64(m.fieldLenSum[f.Name]) / float64(c)
+				}
+				norm := 1 - bm25B + bm25B*float64(md.lengths[fi])/math.Max(avg, 1)
+				score += boost * idf * float64(tf) * (bm25K1 + 1) / (float64(tf) + bm25K1*norm)
+			}
+		}
+		if matched {
+			hits = append(hits, Hit{ID: id, Score: score})
+		}
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score == hits[j].Score {
+			return hits[i].ID > hits[j].ID
+		}
+		return hits[i].Score > hits[j].Score
+	})
+	result := &Result{Total: len(hits)}
+	if q.Offset > 0 {
+		if q.Offset >= len(hits) {
+			return result, nil
+		}
+		hits = hits[q.Offset:]
+	}
+	if q.Limit > 0 && len(hits) > q.Limit {
+		hits = hits[:q.Limit]
+	}
+
+	// 生成高亮片段
+	for i := range hits {
+		md := m.docs[hits[i].ID]
+		for _, f := range md.fields {
+			if snippet := Highlight(f.Text, terms, q.SnippetRunes); snippet != "" {
+				if hits[i].Highlights == nil {
+					hits[i].Highlights = make(map[string][]string)
+				}
+				hits[i].Highlights[f.Name] = append(hits[i].Highlights[f.Name], snippet)
+			}
+		}
+	}
+	result.Hits = hits
+	return result, nil
+}
@@ -0,0 +1,67 @@
+package search
+
+import "fmt"
+
+// Field 文档字段
+type Field struct {
+	Name  string  // 字段名（用于高亮结果归类）
+	Text  string  // 字段文本
+	Boost float64 // 字段权重，<= 0 时按 1 处理
+}
+
+// Document 待索引文档
+type Document struct {
+	ID     uint
+	Fields []Field
+}
+
+// Query 检索请求
+type Query struct {
+	Text         string             // 检索关键字
+	Offset       int                // 跳过的结果数（分页）
+	Limit        int                // 最多返回的结果数，<= 0 时不限制
+	SnippetRunes int                // 高亮片段长度（字符数），<= 0 时使用默认值
+	Filter       func(id uint) bool // 文档过滤（如按权限），返回 false 的文档不计入结果和总数
+}
+
+// Result 检索结果
+type Result struct {
+	Total int   // 命中总数（过滤后、分页前）
+	Hits  []Hit // 当前页命中结果
+}
+
+// Hit 检索命中结果
+type Hit struct {
+	ID         uint                `json:"id"`
+	Score      float64             `json:"score"`
+	Highlights map[string][]string `json:"highlights,omitempty"` // 字段名 -> 高亮片段（命中词以 <em> 包裹）
+}
+
+// Index 全文索引接口（可插拔后端）
+type Index interface {
+	// Index 添加或替换文档
+	Index(doc Document) error
+	// Delete 删除文档
+	Delete(id uint) error
+	// Search 检索，结果按相关度降序
+	Search(q Query) (*Result, error)
+	// Reset 清空索引
+	Reset() error
+	// Count 文档数量
+	Count() int
+}
+
+// Config 索引配置
+type Config struct {
+	Engine string `json:"engine" yaml:"engine"` // memory（内嵌索引，无需外部服务）
+}
+
+// NewIndex 根据配置创建索引
+func NewIndex(cfg *Config) (Index, error) {
+	switch cfg.Engine {
+	case "", "memory":
+		return NewMemoryIndex(), nil
+	default:
+		return nil, fmt.Errorf("unsupported search engine: %s", cfg.Engine)
+	}
+}
@@ -0,0 +1,171 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Tokenize 分词：拉丁字母和数字按单词切分并转为小写，中日韩文字按二元组（bigram）切分
+func Tokenize(text string) []string {
+	var tokens []string
+	var word []rune
+	var cjk []rune
+
+	flushWord := func() {
+		if len(word) > 0 {
+			tokens = append(tokens, string(word))
+			word = word[:0]
+		}
+	}
+	flushCJK := func() {
+		switch len(cjk) {
+		case 0:
+		case 1:
+			tokens = append(tokens, string(cjk))
+		default:
+			for i := 0; i < len(cjk)-1; i++ {
+				tokens = append(tokens, string(cjk[i:i+2]))
+			}
+		}
+		cjk = cjk[:0]
+	}
+
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			flushWord()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK()
+			word = append(word, unicode.ToLower(r))
+		default:
+			flushWord()
+			flushCJK()
+		}
+	}
+	flushWord()
+	flushCJK()
+	return tokens
+}
+
+// uniqueTokens 去重后的分词结果（保持首次出现顺序）
+func uniqueTokens(text string) []string {
+	seen := make(map[string]bool)
+	var result []string
+	for _, t := range Tokenize(text) {
+		if !seen[t] {
+			seen[t] = true
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
+
+// Highlight 在文本中标记命中词，返回命中位置附近的片段；未命中返回空字符串
+func Highlight(text string, terms []string, snippetRunes int) string {
+	if text == "" || len(terms) == 0 {
+		return ""
+	}
+	if snippetRunes <= 0 {
+		snippetRunes = 120
+	}
+
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	// 标记所有命中区间
+	marked := make([]bool, len(runes))
+	first := -1
+	for _, term := range terms {
+		tr := []rune(term)
+		if len(tr) == 0 {
+			continue
+		}
+		for i := 0; i+len(tr) <= len(lower); i++ {
+			if runesEqual(lower[i:i+len(tr)], tr) {
+				for j := i; j < i+len(tr); j++ {
+					marked[j] = true
+				}
+				if first == -1 || i < first {
+					first = i
+				}
+			}
+		}
+	}
+	if first == -1 {
+		return ""
+	}
+
+	// 以第一个命中位置为中心截取片段
+	start := first - snippetRunes/3
+	if start < 0 {
+		start = 0
+	}
+	end := start + snippetRunes
+	if end > len(runes) {
+		end = len(runes)
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	inMark := false
+	for i := start; i < end; i++ {
+		if marked[i] && !inMark {
+			b.WriteString("<em>")
+			inMark = true
+		} else if !marked[i] && inMark {
+			b.WriteString("</em>")
+			inMark = false
+		}
+		b.WriteString(escapeRune(runes[i]))
+	}
+	if inMark {
+		b.WriteString("</em>")
+	}
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+func runesEqual(a, b []rune) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// escapeRune 转义 HTML 特殊字符，避免高亮片段被当作 HTML 注入
+func escapeRune(r rune) string {
+	switch r {
+	case '<':
+		return "&lt;"
+	case '>':
+		return "&gt;"
+	case '&':
+		return "&amp;"
+	case '"':
+		return "&#34;"
+	case '\'':
+		return "&#39;"
+	case '\n', '\r', '\t':
+		return " "
+	default:
+		return string(r)
+	}
+}
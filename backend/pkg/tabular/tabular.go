@@ -0,0 +1,180 @@
+package tabular
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// 支持的表格格式
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// defaultSheet XLSX 默认工作表名称
+const defaultSheet = "Sheet1"
+
+// Writer 逐行写入表格数据
+type Writer interface {
+	// Write 写入一行
+	Write(row []string) error
+	// Close 完成写入并输出到底层 io.Writer
+	Close() error
+}
+
+// NewWriter 按格式创建表格写入器
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return NewCSVWriter(w), nil
+	case FormatXLSX:
+		return NewXLSXWriter(w)
+	default:
+		return nil, fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+// ContentType 返回格式对应的 MIME 类型
+func ContentType(format string) string {
+	if format == FormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// CSVWriter CSV 写入器（写入 UTF-8 BOM，便于 Excel 正确识别中文；以公式字符开头的单元格加 ' 前缀，防止 CSV 注入）
+type CSVWriter struct {
+	w       io.Writer
+	csv     *csv.Writer
+	started bool
+}
+
+// NewCSVWriter 创建 CSV 写入器
+func NewCSVWriter(w io.Writer) *CSVWriter {
+	return &CSVWriter{w: w, csv: csv.NewWriter(w)}
+}
+
+// Write 写入一行
+func (c *CSVWriter) Write(row []string) error {
+	if !c.started {
+		c.started = true
+		if _, err := c.w.Write([]byte("\xEF\xBB\xBF")); err != nil {
+			return err
+		}
+	}
+	escaped := make([]string, len(row))
+	for i, cell := range row {
+		escaped[i] = escapeFormula(cell)
+	}
+	return c.csv.Write(escaped)
+}
+
+// escapeFormula 以 = + - @ 或制表符、回车开头的内容会被电子表格当作公式执行，加 ' 前缀按文本显示；
+// 数值（如负数金额）保持原样
+func escapeFormula(cell string) string {
+	if cell == "" || !strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return cell
+	}
+	if _, err := strconv.ParseFloat(cell, 64); err == nil {
+		return cell
+	}
+	return "'" + cell
+}
+
+// Close 刷新缓冲区
+func (c *CSVWriter) Close() error {
+	c.csv.Flush()
+	return c.csv.Error()
+}
+
+// XLSXWriter XLSX 流式写入器
+type XLSXWriter struct {
+	w      io.Writer
+	file   *excelize.File
+	stream *excelize.StreamWriter
+	row    int
+}
+
+// NewXLSXWriter 创建 XLSX 写入器
+func NewXLSXWriter(w io.Writer) (*XLSXWriter, error) {
+	f := excelize.NewFile()
+	sw, err := f.NewStreamWriter(defaultSheet)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &XLSXWriter{w: w, file: f, stream: sw}, nil
+}
+
+// Write 写入一行
+func (x *XLSXWriter) Write(row []string) error {
+	x.row++
+	cells := make([]interface{}, len(row))
+	for i, v := range row {
+		cells[i] = v
+	}
+	cell, err := excelize.CoordinatesToCellName(1, x.row)
+	if err != nil {
+		return err
+	}
+	return x.stream.SetRow(cell, cells)
+}
+
+// Close 完成写入并输出文件
+func (x *XLSXWriter) Close() error {
+	defer x.file.Close()
+	if err := x.stream.Flush(); err != nil {
+		return err
+	}
+	_, err := x.file.WriteTo(x.w)
+	return err
+}
+
+// FormatFromFileName 根据文件扩展名判断表格格式
+func FormatFromFileName(name string) (string, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return FormatCSV, nil
+	case ".xlsx":
+		return FormatXLSX, nil
+	default:
+		return "", fmt.Errorf("unsupported file type: %s", filepath.Ext(name))
+	}
+}
+
+// ReadAll 读取表格全部行（XLSX 读取第一个工作表），去除 CSV 的 UTF-8 BOM
+func ReadAll(format string, r io.Reader) ([][]string, error) {
+	switch format {
+	case FormatCSV:
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		reader.LazyQuotes = true
+		rows, err := reader.ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		if len(rows) > 0 && len(rows[0]) > 0 {
+			rows[0][0] = strings.TrimPrefix(rows[0][0], "\xEF\xBB\xBF")
+		}
+		return rows, nil
+	case FormatXLSX:
+		f, err := excelize.OpenReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		sheets := f.GetSheetList()
+		if len(sheets) == 0 {
+			return nil, nil
+		}
+		return f.GetRows(sheets[0])
+	default:
+		return nil, fmt.Errorf("unsupported format: %s", format)
+	}
+}